@@ -0,0 +1,33 @@
+package main
+
+import (
+	"eventBookingSystem/configs"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configUsage = `usage: server config <command>
+
+commands:
+  print  show the effective configuration as YAML, with secrets redacted`
+
+// runConfig runs the config subcommand with the arguments after "config".
+func runConfig(config *configs.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", configUsage)
+	}
+
+	switch command := args[0]; {
+	case command == "print" && len(args) == 1:
+		out, err := yaml.Marshal(config.Redacted())
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	default:
+		return fmt.Errorf("invalid arguments %q\n%s", args, configUsage)
+	}
+}
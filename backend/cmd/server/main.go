@@ -1,97 +1,394 @@
 package main
 
 import (
+	"context"
 	"eventBookingSystem/configs"
+	"eventBookingSystem/internal/auth/oidc"
 	"eventBookingSystem/internal/auth/roles"
+	"eventBookingSystem/internal/auth/token"
 	"eventBookingSystem/internal/bookings"
 	"eventBookingSystem/internal/events"
+	"eventBookingSystem/internal/health"
+	"eventBookingSystem/internal/logging"
+	"eventBookingSystem/internal/metrics"
 	"eventBookingSystem/internal/middleware"
+	"eventBookingSystem/internal/notify"
+	"eventBookingSystem/internal/ratelimit"
 	"eventBookingSystem/internal/users"
+	"eventBookingSystem/internal/waitlist"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/rs/cors"
+	"gorm.io/gorm"
 )
 
 func main() {
 	config, err := configs.LoadConfig()
 	if err != nil {
-		log.Fatal("Failed to load config:", err)
+		fatal("Failed to load config", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if err := runConfig(config, os.Args[2:]); err != nil {
+			fatal("Config command failed", err)
+		}
 		return
 	}
 
+	logger, err := logging.New(os.Stderr, config.Logging.Format, config.Logging.Level)
+	if err != nil {
+		fatal("Failed to configure logging", err)
+	}
+	slog.SetDefault(logger)
+
+	if config.IsDevelopment() {
+		slog.Warn("Running in development mode, weak secrets are accepted")
+	}
+
 	db, err := configs.ConnectDB(config)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		fatal("Failed to connect to database", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		fatal("Failed to get database pool", err)
+	}
+	migrator, err := newMigrator(sqlDB)
+	if err != nil {
+		fatal("Failed to load migrations", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := runMigrate(migrator, os.Args[2:])
+		configs.CloseDB(db)
+		if err != nil {
+			fatal("Migration failed", err)
+		}
 		return
 	}
-	db.AutoMigrate(&users.User{}, &events.Event{}, &bookings.Booking{})
+
+	if config.Database.MigrateOnStart {
+		if err := migrator.Up(context.Background()); err != nil {
+			fatal("Failed to migrate database", err)
+		}
+	}
+	if err := roles.Seed(db); err != nil {
+		fatal("Failed to seed roles", err)
+	}
+
+	notificationLog := logger
+	if config.Notifications.LogFile != "" {
+		file, err := os.OpenFile(config.Notifications.LogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			fatal("Failed to open notification log", err)
+		}
+		defer file.Close()
+		notificationLog, _ = logging.New(file, config.Logging.Format, "info")
+	}
+	notifier := notify.NewLogNotifier(notificationLog)
+
+	rateLimitStore, err := newRateLimitStore(config.RateLimit.Store, db)
+	if err != nil {
+		fatal("Failed to configure rate limiting", err)
+	}
+	loginLockout := ratelimit.NewLockout(rateLimitStore, config.RateLimit.LoginLockout.Threshold, config.RateLimit.LoginLockout.Delay, config.RateLimit.LoginLockout.MaxDelay, config.RateLimit.LoginLockout.Window)
+
+	roleRepository := roles.NewRoleRepository(db)
+	roleService := roles.NewRoleService(roleRepository, config.Auth.PermissionCacheTTL)
+	roleHandler := roles.NewRoleHandler(roleService)
+
+	tokenManager, err := newTokenManager(config.Auth)
+	if err != nil {
+		fatal("Failed to configure tokens", err)
+	}
 
 	userRepository := users.NewUserRepository(db)
-	userService := users.NewUserService(userRepository)
-	userHandler := users.NewUserHandler(userService)
+	userService := users.NewUserService(userRepository, loginLockout)
+	sessionRepository := users.NewSessionRepository(db)
+	sessionService := users.NewSessionService(sessionRepository, userRepository, tokenManager, roleService, config.Auth.RefreshTokenTTL, config.Features.RequireAdminMFA)
+	passwordResetRepository := users.NewPasswordResetRepository(db)
+	passwordResetService := users.NewPasswordResetService(passwordResetRepository, userRepository, notifier, config.Auth.PasswordResetTTL, config.Notifications.AppURL)
+	emailVerificationRepository := users.NewEmailVerificationRepository(db)
+	emailVerificationService := users.NewEmailVerificationService(emailVerificationRepository, userRepository, notifier, config.Auth.EmailVerificationTTL, config.Auth.EmailVerificationResendInterval, config.Notifications.AppURL)
+	mfaRepository := users.NewMFARepository(db)
+	mfaService := users.NewMFAService(mfaRepository, userRepository, tokenManager, roleService, loginLockout, config.Auth.MFAIssuer, config.Auth.MFAPendingTTL, config.Features.RequireAdminMFA)
+	var oidcService users.OIDCService
+	if config.OIDC.Enabled() {
+		oidcClient := oidc.NewClient(oidc.Options{
+			IssuerURL:    config.OIDC.IssuerURL,
+			ClientID:     config.OIDC.ClientID,
+			ClientSecret: config.OIDC.ClientSecret,
+			RedirectURL:  config.OIDC.RedirectURL,
+			Scopes:       config.OIDC.Scopes,
+			HTTPClient:   &http.Client{Timeout: 10 * time.Second},
+		})
+		oidcService = users.NewOIDCService(users.NewOIDCRepository(db), userRepository, oidcClient, notifier, config.OIDC.StateTTL, config.OIDC.AutoProvision)
+	}
+	userHandler := users.NewUserHandler(userService, sessionService, passwordResetService, emailVerificationService, mfaService, oidcService)
+
+	waitlistRepository := waitlist.NewWaitlistRepository(db)
+	waitlistService := waitlist.NewWaitlistService(waitlistRepository)
+	waitlistHandler := waitlist.NewWaitlistHandler(waitlistService)
 
 	eventRepository := events.NewEventRepository(db)
-	eventService := events.NewEventService(eventRepository)
-	eventHandler := events.NewEventHandler(eventService)
+	eventSearcher := events.NewEventSearcher(db, eventRepository)
+	eventService := events.NewEventService(eventRepository, eventSearcher, waitlistService)
+	eventHandler := events.NewEventHandler(eventService, roleService)
 
 	bookingRepository := bookings.NewBookingRepository(db)
-	bookingService := bookings.NewBookingService(bookingRepository)
-	bookingHandler := bookings.NewBookingHandler(bookingService)
+	bookingService := bookings.NewBookingService(bookingRepository, waitlistService, config.Bookings.HoldTTL)
+	bookingHandler := bookings.NewBookingHandler(bookingService, roleService)
+
+	// Background workers run until workersCtx is cancelled during shutdown.
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+
+	holdSweeper := bookings.NewHoldSweeper(bookingService, config.Bookings.HoldSweepInterval)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		holdSweeper.Run(workersCtx)
+	}()
+
+	metrics.RegisterDB(sqlDB, "postgres")
+
+	healthChecker := health.NewChecker(config.Server.ReadinessTimeout)
+	healthChecker.Register("database", health.DatabaseCheck(sqlDB))
+	healthChecker.Register("migrations", health.MigrationCheck(migrator))
+	healthChecker.Register("hold_sweeper", holdSweeper.Check)
+
+	limiter := ratelimit.NewLimiter(rateLimitStore)
+	byIP := ratelimit.ByIP(config.RateLimit.TrustProxy)
+	byEmail := ratelimit.ByJSONField("email")
+
+	// Unauthenticated routes are limited per client IP, and the ones that
+	// take an email address also per address.
+	limitSetup := limiter.Middleware(ratelimit.Rule{Name: "setup", Key: byIP, Limit: config.RateLimit.Setup})
+	limitRegister := limiter.Middleware(ratelimit.Rule{Name: "register", Key: byIP, Limit: config.RateLimit.Register})
+	limitLogin := limiter.Middleware(
+		ratelimit.Rule{Name: "login", Key: byIP, Limit: config.RateLimit.Login},
+		ratelimit.Rule{Name: "login_email", Key: byEmail, Limit: config.RateLimit.LoginEmail},
+	)
+	limitPasswordReset := limiter.Middleware(
+		ratelimit.Rule{Name: "password_reset", Key: byIP, Limit: config.RateLimit.PasswordReset},
+		ratelimit.Rule{Name: "password_reset_email", Key: byEmail, Limit: config.RateLimit.PasswordReset},
+	)
+	limitPublic := limiter.Middleware(ratelimit.Rule{Name: "public", Key: byIP, Limit: config.RateLimit.Public})
+	limitUser := limiter.Middleware(ratelimit.Rule{Name: "user", Key: ratelimit.ByUser, Limit: config.RateLimit.User})
+
+	// Authenticated routes are limited per user.
+	authenticate := func(next http.Handler) http.Handler {
+		return middleware.AuthMiddleware(tokenManager, sessionService)(limitUser(next))
+	}
+	authenticateEnrollment := func(next http.Handler) http.Handler {
+		return middleware.MFAEnrollmentAuthMiddleware(tokenManager, sessionService)(limitUser(next))
+	}
+
+	// Creating bookings only requires a verified email when configured to.
+	requireVerifiedEmail := func(next http.Handler) http.Handler { return next }
+	if config.Features.RequireVerifiedEmail {
+		requireVerifiedEmail = middleware.RequireVerifiedEmail(emailVerificationService)
+	}
+
+	// protect requires an authenticated user with the given permission.
+	protect := func(permission string, handler http.HandlerFunc) http.Handler {
+		return authenticate(
+			middleware.RequirePermission(roleService, permission)(handler),
+		)
+	}
+
+	// protectBooking guards routes that take seats, which may additionally
+	// require a verified email.
+	protectBooking := func(handler http.HandlerFunc) http.Handler {
+		return authenticate(
+			middleware.RequirePermission(roleService, roles.PermissionCreateBookings)(
+				requireVerifiedEmail(handler),
+			),
+		)
+	}
 
 	mux := http.NewServeMux()
 
+	mux.HandleFunc("GET /healthz", healthChecker.Liveness)
+	mux.HandleFunc("GET /readyz", healthChecker.Readiness)
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.Handle("GET /.well-known/jwks.json", limitPublic(http.HandlerFunc(tokenManager.ServeJWKS)))
+
 	// Public routes
-	mux.HandleFunc("/api/setup", userHandler.Setup)
-	mux.HandleFunc("/api/users/register", userHandler.Register)
-	mux.HandleFunc("/api/users/login", userHandler.Login)
+	mux.Handle("POST /api/setup", limitSetup(http.HandlerFunc(userHandler.Setup)))
+	mux.Handle("POST /api/users/register", limitRegister(http.HandlerFunc(userHandler.Register)))
+	mux.Handle("POST /api/users/login", limitLogin(http.HandlerFunc(userHandler.Login)))
+	mux.Handle("POST /api/users/login/mfa", limitLogin(http.HandlerFunc(userHandler.LoginMFA)))
+	if config.OIDC.Enabled() {
+		mux.Handle("POST /api/users/oidc/start", limitPublic(http.HandlerFunc(userHandler.OIDCStart)))
+		mux.Handle("POST /api/users/oidc/callback", limitPublic(http.HandlerFunc(userHandler.OIDCCallback)))
+	}
+	mux.Handle("POST /api/users/refresh", limitPublic(http.HandlerFunc(userHandler.Refresh)))
+	mux.Handle("POST /api/users/password-reset/request", limitPasswordReset(http.HandlerFunc(userHandler.RequestPasswordReset)))
+	mux.Handle("POST /api/users/password-reset/confirm", limitPublic(http.HandlerFunc(userHandler.ResetPassword)))
+	mux.Handle("POST /api/users/verify-email", limitPublic(http.HandlerFunc(userHandler.VerifyEmail)))
+
+	// Routes for any authenticated user
+	mux.Handle("POST /api/users/logout", authenticateEnrollment(http.HandlerFunc(userHandler.Logout)))
+	mux.Handle("POST /api/users/logout-all", authenticateEnrollment(http.HandlerFunc(userHandler.LogoutAll)))
+	mux.Handle("POST /api/users/verify-email/resend", authenticate(http.HandlerFunc(userHandler.ResendVerification)))
+	mux.Handle("POST /api/users/mfa/enroll", authenticateEnrollment(http.HandlerFunc(userHandler.BeginMFAEnrollment)))
+	mux.Handle("POST /api/users/mfa/confirm", authenticateEnrollment(http.HandlerFunc(userHandler.ConfirmMFAEnrollment)))
+	mux.Handle("POST /api/users/mfa/recovery-codes", authenticate(http.HandlerFunc(userHandler.RegenerateRecoveryCodes)))
+	mux.Handle("POST /api/users/mfa/disable", authenticate(http.HandlerFunc(userHandler.DisableMFA)))
 
 	// Protected routes with specific permissions
-	mux.Handle("/api/users/profile",
-		middleware.AuthMiddleware(
-			middleware.RequirePermission(roles.PermissionReadBookings)(
-				http.HandlerFunc(userHandler.GetProfile),
-			),
-		),
-	)
+	mux.Handle("GET /api/users/profile", protect(roles.PermissionReadBookings, userHandler.GetProfile))
 
-	mux.Handle("/api/admin/users/create",
-		middleware.AuthMiddleware(
-			middleware.RequirePermission(roles.PermissionManageUsers)(
-				http.HandlerFunc(userHandler.CreateAdmin),
-			),
-		),
-	)
+	mux.Handle("POST /api/admin/users/create", protect(roles.PermissionManageUsers, userHandler.CreateAdmin))
+	mux.Handle("GET /api/admin/users/{userID}/roles", protect(roles.PermissionManageRoles, roleHandler.GetUserRoles))
+	mux.Handle("PUT /api/admin/users/{userID}/roles", protect(roles.PermissionManageRoles, roleHandler.SetUserRoles))
+	mux.Handle("GET /api/admin/roles", protect(roles.PermissionManageRoles, roleHandler.ListRoles))
+	mux.Handle("POST /api/admin/roles", protect(roles.PermissionManageRoles, roleHandler.CreateRole))
+	mux.Handle("GET /api/admin/roles/{name}", protect(roles.PermissionManageRoles, roleHandler.GetRole))
+	mux.Handle("PUT /api/admin/roles/{name}", protect(roles.PermissionManageRoles, roleHandler.UpdateRole))
+	mux.Handle("DELETE /api/admin/roles/{name}", protect(roles.PermissionManageRoles, roleHandler.DeleteRole))
+	mux.Handle("GET /api/admin/permissions", protect(roles.PermissionManageRoles, roleHandler.ListPermissions))
+	mux.Handle("POST /api/admin/events/{id}/transfer", protect(roles.PermissionManageAnyEvents, eventHandler.TransferOwnership))
 
-	mux.Handle("/api/events",
-		middleware.AuthMiddleware(
-			middleware.RequirePermission(roles.PermissionCreateEvents)(
-				http.HandlerFunc(eventHandler.HandleEvents),
-			),
-		),
-	)
+	mux.Handle("GET /api/events", protect(roles.PermissionReadEvents, eventHandler.ListEvents))
+	mux.Handle("POST /api/events", protect(roles.PermissionCreateEvents, eventHandler.CreateEvent))
+	mux.Handle("GET /api/events/search", protect(roles.PermissionReadEvents, eventHandler.SearchEvents))
+	mux.Handle("GET /api/events/{id}", protect(roles.PermissionReadEvents, eventHandler.GetEventDetails))
+	mux.Handle("PUT /api/events/{id}", protect(roles.PermissionUpdateEvents, eventHandler.UpdateEvent))
+	mux.Handle("DELETE /api/events/{id}", protect(roles.PermissionDeleteEvents, eventHandler.DeleteEvent))
 
-	mux.Handle("/api/bookings/",
-		middleware.AuthMiddleware(
-			middleware.RequirePermission(roles.PermissionCreateBookings)(
-				http.HandlerFunc(bookingHandler.HandleBookings),
-			),
-		),
-	)
+	mux.Handle("GET /api/organizer/events", protect(roles.PermissionCreateEvents, eventHandler.ListOrganizerEvents))
+	mux.Handle("GET /api/organizer/events/{id}/bookings", protect(roles.PermissionCreateEvents, bookingHandler.GetEventBookings))
+	mux.Handle("GET /api/organizer/events/{id}/attendees", protect(roles.PermissionCreateEvents, bookingHandler.GetEventAttendees))
+
+	mux.Handle("POST /api/bookings", protectBooking(bookingHandler.CreateBooking))
+	mux.Handle("POST /api/bookings/holds", protectBooking(bookingHandler.HoldSeats))
+	mux.Handle("POST /api/bookings/{id}/confirm", protect(roles.PermissionCreateBookings, bookingHandler.ConfirmHold))
+	mux.Handle("GET /api/bookings/{id}", protect(roles.PermissionReadBookings, bookingHandler.GetBookingByID))
+	mux.Handle("GET /api/bookings/users/{userID}", protect(roles.PermissionReadBookings, bookingHandler.GetBookingsByUserID))
+	mux.Handle("DELETE /api/bookings/{id}", protect(roles.PermissionCancelBookings, bookingHandler.CancelBooking))
+
+	mux.Handle("GET /api/waitlist", protect(roles.PermissionReadBookings, waitlistHandler.ListEntries))
+	mux.Handle("POST /api/waitlist/{eventID}", protectBooking(waitlistHandler.Join))
+	mux.Handle("GET /api/waitlist/{eventID}", protect(roles.PermissionReadBookings, waitlistHandler.GetPosition))
+	mux.Handle("DELETE /api/waitlist/{eventID}", protect(roles.PermissionCancelBookings, waitlistHandler.Leave))
 
 	// CORS configuration
 	corsHandler := cors.New(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:5173"}, // Allow requests from your React app
+		AllowedOrigins:   config.CORS.AllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Authorization", "Content-Type"},
-		AllowCredentials: true,
+		AllowCredentials: config.CORS.AllowCredentials,
 	})
 
 	// Wrap the ServeMux with the CORS handler
 	handler := corsHandler.Handler(mux)
-	http.Handle("/", middleware.LoggingMiddleware(handler))
 
-	fmt.Println("Server listening on port 8080")
-	http.ListenAndServe(":8080", nil)
+	server := &http.Server{
+		Addr:              config.Server.ListenAddr,
+		Handler:           middleware.LoggingMiddleware(metrics.Middleware(handler)),
+		ReadTimeout:       config.Server.ReadTimeout,
+		ReadHeaderTimeout: config.Server.ReadHeaderTimeout,
+		WriteTimeout:      config.Server.WriteTimeout,
+		IdleTimeout:       config.Server.IdleTimeout,
+		MaxHeaderBytes:    config.Server.MaxHeaderBytes,
+	}
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Server listening", "addr", config.Server.ListenAddr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		fatal("Server failed", err)
+	case <-signals.Done():
+		slog.Info("Shutting down")
+	}
+	// A second signal kills the process instead of waiting for the drain.
+	stopSignals()
+
+	// Report not ready and give load balancers time to notice before the
+	// listener closes.
+	healthChecker.SetShuttingDown()
+	time.Sleep(config.Server.ShutdownDelay)
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), config.Server.ShutdownTimeout)
+	defer cancelShutdown()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to drain requests", "timeout", config.Server.ShutdownTimeout, "error", err)
+		server.Close()
+	}
+
+	stopWorkers()
+	workers.Wait()
+
+	if err := configs.CloseDB(db); err != nil {
+		slog.Error("Failed to close database", "error", err)
+	}
+
+	slog.Info("Server stopped")
+}
+
+// newTokenManager creates the issuer and verifier of access tokens from the
+// auth config, reading key files.
+func newTokenManager(auth configs.AuthConfig) (*token.Manager, error) {
+	signingKey, err := loadTokenKey(configs.JWTKey{ID: auth.JWTKeyID, Secret: auth.JWTSecret, File: auth.JWTPrivateKeyFile})
+	if err != nil {
+		return nil, err
+	}
+
+	verificationKeys := make([]token.Key, len(auth.JWTVerificationKeys))
+	for i, key := range auth.JWTVerificationKeys {
+		if verificationKeys[i], err = loadTokenKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return token.NewManager(token.Options{
+		Issuer:    auth.JWTIssuer,
+		Audience:  auth.JWTAudience,
+		TTL:       auth.AccessTokenTTL,
+		ClockSkew: auth.JWTClockSkew,
+	}, signingKey, verificationKeys...)
+}
+
+func loadTokenKey(key configs.JWTKey) (token.Key, error) {
+	if key.File != "" {
+		return token.LoadKeyFile(key.ID, key.File)
+	}
+	return token.HMACKey(key.ID, []byte(key.Secret)), nil
+}
+
+// newRateLimitStore creates the store of the rate limiter, "memory" or
+// "postgres".
+func newRateLimitStore(kind string, db *gorm.DB) (ratelimit.Store, error) {
+	switch kind {
+	case "memory":
+		return ratelimit.NewMemoryStore(), nil
+	case "postgres":
+		return ratelimit.NewPostgresStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q, must be memory or postgres", kind)
+	}
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"eventBookingSystem/internal/migrate"
+	"eventBookingSystem/migrations"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up            apply all pending migrations
+  down [steps]  revert the last steps migrations (default 1)
+  status        list migrations and when they were applied
+  to <version>  migrate up or down to version, 0 reverts everything`
+
+func newMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	loaded, err := migrate.Load(migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator := migrate.NewMigrator(db, loaded)
+	migrator.Logf = func(format string, args ...any) {
+		slog.Info(fmt.Sprintf(format, args...))
+	}
+	return migrator, nil
+}
+
+// runMigrate runs the migrate subcommand with the arguments after "migrate".
+func runMigrate(migrator *migrate.Migrator, args []string) error {
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", migrateUsage)
+	}
+
+	switch command := args[0]; {
+	case command == "up" && len(args) == 1:
+		return migrator.Up(ctx)
+	case command == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil || parsed < 1 {
+				return fmt.Errorf("invalid steps %q, expected a positive integer", args[1])
+			}
+			steps = parsed
+		}
+		return migrator.Down(ctx, steps)
+	case command == "to" && len(args) == 2:
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return migrator.To(ctx, version)
+	case command == "status" && len(args) == 1:
+		return printMigrationStatus(ctx, migrator)
+	default:
+		return fmt.Errorf("invalid arguments %q\n%s", args, migrateUsage)
+	}
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migrate.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return w.Flush()
+}
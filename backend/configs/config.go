@@ -1,57 +1,284 @@
 package configs
 
 import (
+	"errors"
+	"eventBookingSystem/internal/ratelimit"
 	"fmt"
-	"log"
+	"io/fs"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
 )
 
+// Environments the server can run in. Only development accepts weak secrets.
+const (
+	EnvDevelopment = "development"
+	EnvStaging     = "staging"
+	EnvProduction  = "production"
+)
+
+// Config is the configuration of the server. It is read from the file named
+// by CONFIG_FILE, if any, on top of Default, and environment variables
+// override both.
 type Config struct {
-	DBHost     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	DBPort     string
-	JWTSecret  string
+	Env string `yaml:"env" toml:"env"`
+
+	Server        ServerConfig        `yaml:"server" toml:"server"`
+	Database      DatabaseConfig      `yaml:"database" toml:"database"`
+	Auth          AuthConfig          `yaml:"auth" toml:"auth"`
+	OIDC          OIDCConfig          `yaml:"oidc" toml:"oidc"`
+	CORS          CORSConfig          `yaml:"cors" toml:"cors"`
+	Logging       LoggingConfig       `yaml:"logging" toml:"logging"`
+	RateLimit     RateLimitConfig     `yaml:"rate_limit" toml:"rate_limit"`
+	Notifications NotificationsConfig `yaml:"notifications" toml:"notifications"`
+	Bookings      BookingsConfig      `yaml:"bookings" toml:"bookings"`
+	Features      FeatureFlags        `yaml:"features" toml:"features"`
 }
 
-func LoadConfig() (*Config, error) {
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("Error loading .env file, using environment variables")
-	}
+type ServerConfig struct {
+	ListenAddr        string        `yaml:"listen_addr" toml:"listen_addr"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes" toml:"max_header_bytes"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	ShutdownDelay     time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay"`
+	ReadinessTimeout  time.Duration `yaml:"readiness_timeout" toml:"readiness_timeout"`
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	Name     string `yaml:"name" toml:"name"`
+	SSLMode  string `yaml:"ssl_mode" toml:"ssl_mode"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" toml:"slow_query_threshold"`
+	MigrateOnStart     bool          `yaml:"migrate_on_start" toml:"migrate_on_start"`
+}
+
+type AuthConfig struct {
+	// New tokens are signed with either JWTSecret (HS256) or the RSA or
+	// Ed25519 private key in the PEM file JWTPrivateKeyFile, and carry
+	// JWTKeyID as their kid.
+	JWTSecret         string `yaml:"jwt_secret" toml:"jwt_secret"`
+	JWTPrivateKeyFile string `yaml:"jwt_private_key_file" toml:"jwt_private_key_file"`
+	JWTKeyID          string `yaml:"jwt_key_id" toml:"jwt_key_id"`
+	// JWTVerificationKeys are further keys whose tokens are accepted: previous
+	// keys while a new one is rolled out, and the next key, so it is
+	// published before tokens are signed with it.
+	JWTVerificationKeys []JWTKey      `yaml:"jwt_verification_keys" toml:"jwt_verification_keys"`
+	JWTIssuer           string        `yaml:"jwt_issuer" toml:"jwt_issuer"`
+	JWTAudience         string        `yaml:"jwt_audience" toml:"jwt_audience"`
+	JWTClockSkew        time.Duration `yaml:"jwt_clock_skew" toml:"jwt_clock_skew"`
+
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" toml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl"`
+
+	PasswordResetTTL                time.Duration `yaml:"password_reset_ttl" toml:"password_reset_ttl"`
+	EmailVerificationTTL            time.Duration `yaml:"email_verification_ttl" toml:"email_verification_ttl"`
+	EmailVerificationResendInterval time.Duration `yaml:"email_verification_resend_interval" toml:"email_verification_resend_interval"`
+
+	MFAIssuer     string        `yaml:"mfa_issuer" toml:"mfa_issuer"`
+	MFAPendingTTL time.Duration `yaml:"mfa_pending_ttl" toml:"mfa_pending_ttl"`
+
+	PermissionCacheTTL time.Duration `yaml:"permission_cache_ttl" toml:"permission_cache_ttl"`
+}
+
+// JWTKey is an HMAC secret or a PEM file with an RSA or Ed25519 key, public
+// or private.
+type JWTKey struct {
+	ID     string `yaml:"id" toml:"id"`
+	Secret string `yaml:"secret,omitempty" toml:"secret,omitempty"`
+	File   string `yaml:"file,omitempty" toml:"file,omitempty"`
+}
+
+// OIDCConfig enables single sign-on with an OpenID Connect provider when
+// IssuerURL is set.
+type OIDCConfig struct {
+	IssuerURL    string `yaml:"issuer_url" toml:"issuer_url"`
+	ClientID     string `yaml:"client_id" toml:"client_id"`
+	ClientSecret string `yaml:"client_secret" toml:"client_secret"`
+	// RedirectURL is the frontend page the provider sends the browser back
+	// to, which completes the login with the API.
+	RedirectURL string   `yaml:"redirect_url" toml:"redirect_url"`
+	Scopes      []string `yaml:"scopes" toml:"scopes"`
+	// AutoProvision creates users who have no account yet.
+	AutoProvision bool          `yaml:"auto_provision" toml:"auto_provision"`
+	StateTTL      time.Duration `yaml:"state_ttl" toml:"state_ttl"`
+}
+
+// Enabled reports whether single sign-on is configured.
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != ""
+}
 
-	return &Config{
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "postgres"),
-		DBName:     getEnv("DB_NAME", "event_booking"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		JWTSecret:  getEnv("JWT_SECRET", "secret"),
-	}, nil
+type CORSConfig struct {
+	AllowedOrigins   []string `yaml:"allowed_origins" toml:"allowed_origins"`
+	AllowCredentials bool     `yaml:"allow_credentials" toml:"allow_credentials"`
 }
 
-func getEnv(key string, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+type LoggingConfig struct {
+	Level  string `yaml:"level" toml:"level"`
+	Format string `yaml:"format" toml:"format"`
+}
+
+type RateLimitConfig struct {
+	Store         string          `yaml:"store" toml:"store"`
+	TrustProxy    bool            `yaml:"trust_proxy" toml:"trust_proxy"`
+	Setup         ratelimit.Limit `yaml:"setup" toml:"setup"`
+	Register      ratelimit.Limit `yaml:"register" toml:"register"`
+	Login         ratelimit.Limit `yaml:"login" toml:"login"`
+	LoginEmail    ratelimit.Limit `yaml:"login_email" toml:"login_email"`
+	PasswordReset ratelimit.Limit `yaml:"password_reset" toml:"password_reset"`
+	Public        ratelimit.Limit `yaml:"public" toml:"public"`
+	User          ratelimit.Limit `yaml:"user" toml:"user"`
+
+	LoginLockout LockoutConfig `yaml:"login_lockout" toml:"login_lockout"`
+}
+
+type LockoutConfig struct {
+	Threshold int           `yaml:"threshold" toml:"threshold"`
+	Delay     time.Duration `yaml:"delay" toml:"delay"`
+	MaxDelay  time.Duration `yaml:"max_delay" toml:"max_delay"`
+	Window    time.Duration `yaml:"window" toml:"window"`
+}
+
+type NotificationsConfig struct {
+	// AppURL is the frontend that links in notifications point to.
+	AppURL string `yaml:"app_url" toml:"app_url"`
+	// LogFile is where notifications are written until they are emailed.
+	// They contain live reset and verification links, so only development
+	// may write them to the server log instead.
+	LogFile string `yaml:"log_file" toml:"log_file"`
+}
+
+type BookingsConfig struct {
+	HoldTTL           time.Duration `yaml:"hold_ttl" toml:"hold_ttl"`
+	HoldSweepInterval time.Duration `yaml:"hold_sweep_interval" toml:"hold_sweep_interval"`
+}
+
+type FeatureFlags struct {
+	RequireVerifiedEmail bool `yaml:"require_verified_email" toml:"require_verified_email"`
+	RequireAdminMFA      bool `yaml:"require_admin_mfa" toml:"require_admin_mfa"`
+}
+
+// Default returns the configuration used for settings that are neither in the
+// config file nor in the environment. It has no JWT signing key, which always
+// has to be set.
+func Default() Config {
+	return Config{
+		Env: EnvProduction,
+		Server: ServerConfig{
+			ListenAddr:        ":8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   30 * time.Second,
+			ReadinessTimeout:  2 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:               "localhost",
+			Port:               5432,
+			User:               "postgres",
+			Password:           "postgres",
+			Name:               "event_booking",
+			SSLMode:            "disable",
+			MaxOpenConns:       25,
+			MaxIdleConns:       25,
+			ConnMaxLifetime:    30 * time.Minute,
+			ConnMaxIdleTime:    5 * time.Minute,
+			SlowQueryThreshold: 200 * time.Millisecond,
+			MigrateOnStart:     true,
+		},
+		Auth: AuthConfig{
+			JWTKeyID:                        "default",
+			JWTIssuer:                       "event-booking",
+			JWTAudience:                     "event-booking-api",
+			JWTClockSkew:                    30 * time.Second,
+			AccessTokenTTL:                  15 * time.Minute,
+			RefreshTokenTTL:                 30 * 24 * time.Hour,
+			PasswordResetTTL:                time.Hour,
+			EmailVerificationTTL:            48 * time.Hour,
+			EmailVerificationResendInterval: time.Minute,
+			MFAIssuer:                       "EventBookingSystem",
+			MFAPendingTTL:                   5 * time.Minute,
+			PermissionCacheTTL:              time.Minute,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   []string{"http://localhost:5173"},
+			AllowCredentials: true,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		RateLimit: RateLimitConfig{
+			Store:         "memory",
+			Setup:         ratelimit.Limit{Burst: 5, Period: time.Hour},
+			Register:      ratelimit.Limit{Burst: 10, Period: time.Hour},
+			Login:         ratelimit.Limit{Burst: 20, Period: time.Minute},
+			LoginEmail:    ratelimit.Limit{Burst: 5, Period: time.Minute},
+			PasswordReset: ratelimit.Limit{Burst: 5, Period: time.Hour},
+			Public:        ratelimit.Limit{Burst: 60, Period: time.Minute},
+			User:          ratelimit.Limit{Burst: 300, Period: time.Minute},
+			LoginLockout: LockoutConfig{
+				Threshold: 5,
+				Delay:     time.Minute,
+				MaxDelay:  time.Hour,
+				Window:    24 * time.Hour,
+			},
+		},
+		OIDC: OIDCConfig{
+			Scopes:        []string{"openid", "email", "profile"},
+			AutoProvision: true,
+			StateTTL:      10 * time.Minute,
+		},
+		Notifications: NotificationsConfig{
+			AppURL: "http://localhost:5173",
+		},
+		Bookings: BookingsConfig{
+			HoldTTL:           15 * time.Minute,
+			HoldSweepInterval: 30 * time.Second,
+		},
 	}
-	return value
 }
 
-func ConnectDB(config *Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		config.DBHost, config.DBUser, config.DBPassword, config.DBName, config.DBPort)
+// LoadConfig reads the configuration from a .env file, the file named by
+// CONFIG_FILE and the environment, and validates it.
+func LoadConfig() (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("loading .env: %w", err)
+	}
+
+	config := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(path, &config); err != nil {
+			return nil, err
+		}
+	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
+	if err := applyEnv(&config); err != nil {
 		return nil, err
 	}
 
-	fmt.Println("Database connected")
-	return db, nil
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// IsDevelopment reports whether the server runs in development mode.
+func (c *Config) IsDevelopment() bool {
+	return c.Env == EnvDevelopment
 }
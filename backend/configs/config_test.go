@@ -0,0 +1,360 @@
+package configs
+
+import (
+	"eventBookingSystem/internal/ratelimit"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// validConfig returns a configuration that passes validation in production.
+func validConfig() Config {
+	c := Default()
+	c.Database.Password = "a-real-database-password"
+	c.Auth.JWTSecret = testSecret
+	c.Notifications.LogFile = "/var/log/event-booking/notifications.log"
+	return c
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		// wantErrs are the settings the error has to name, none if valid.
+		wantErrs []string
+	}{
+		{name: "valid production config", modify: func(c *Config) {}},
+		{
+			name:     "unknown environment",
+			modify:   func(c *Config) { c.Env = "prod" },
+			wantErrs: []string{"env:"},
+		},
+		{
+			name:     "notification log required outside development",
+			modify:   func(c *Config) { c.Notifications.LogFile = " " },
+			wantErrs: []string{"notifications.log_file"},
+		},
+		{
+			name: "notification log optional in development",
+			modify: func(c *Config) {
+				c.Env = EnvDevelopment
+				c.Notifications.LogFile = ""
+			},
+		},
+		{
+			name:     "weak JWT secret in production",
+			modify:   func(c *Config) { c.Auth.JWTSecret = "secret" },
+			wantErrs: []string{"auth.jwt_secret"},
+		},
+		{
+			name: "weak secrets in development",
+			modify: func(c *Config) {
+				c.Env = EnvDevelopment
+				c.Auth.JWTSecret = "secret"
+				c.Database.Password = "postgres"
+			},
+		},
+		{
+			name:     "default database password in production",
+			modify:   func(c *Config) { c.Database.Password = "postgres" },
+			wantErrs: []string{"database.password"},
+		},
+		{
+			name:     "both a JWT secret and a private key",
+			modify:   func(c *Config) { c.Auth.JWTPrivateKeyFile = "/etc/key.pem" },
+			wantErrs: []string{"auth.jwt_secret"},
+		},
+		{
+			name:     "neither a JWT secret nor a private key",
+			modify:   func(c *Config) { c.Auth.JWTSecret = "" },
+			wantErrs: []string{"auth.jwt_secret"},
+		},
+		{
+			name: "verification key ID used twice",
+			modify: func(c *Config) {
+				c.Auth.JWTVerificationKeys = []JWTKey{{ID: "default", Secret: testSecret}}
+			},
+			wantErrs: []string{"auth.jwt_verification_keys"},
+		},
+		{
+			name: "verification key with a secret and a file",
+			modify: func(c *Config) {
+				c.Auth.JWTVerificationKeys = []JWTKey{{ID: "old", Secret: testSecret, File: "/etc/old.pem"}}
+			},
+			wantErrs: []string{"auth.jwt_verification_keys"},
+		},
+		{
+			name:     "refresh tokens outlived by access tokens",
+			modify:   func(c *Config) { c.Auth.RefreshTokenTTL = c.Auth.AccessTokenTTL },
+			wantErrs: []string{"auth.refresh_token_ttl"},
+		},
+		{
+			name:     "wildcard origin with credentials",
+			modify:   func(c *Config) { c.CORS.AllowedOrigins = []string{"*"} },
+			wantErrs: []string{"cors.allowed_origins"},
+		},
+		{
+			name:     "origin with a path",
+			modify:   func(c *Config) { c.CORS.AllowedOrigins = []string{"https://example.com/app"} },
+			wantErrs: []string{"cors.allowed_origins"},
+		},
+		{
+			name:     "plain http single sign-on in production",
+			modify:   func(c *Config) { c.OIDC.IssuerURL = "http://sso.example.com"; c.OIDC.ClientID = "client" },
+			wantErrs: []string{"oidc.issuer_url"},
+		},
+		{
+			name: "single sign-on without openid scope",
+			modify: func(c *Config) {
+				c.OIDC.IssuerURL = "https://sso.example.com"
+				c.OIDC.ClientID = "client"
+				c.OIDC.Scopes = []string{"email"}
+			},
+			wantErrs: []string{"oidc.scopes"},
+		},
+		{
+			name:     "lockout delay longer than its maximum",
+			modify:   func(c *Config) { c.RateLimit.LoginLockout.MaxDelay = time.Second },
+			wantErrs: []string{"rate_limit.login_lockout.max_delay"},
+		},
+		{
+			name: "lockout disabled",
+			modify: func(c *Config) {
+				c.RateLimit.LoginLockout = LockoutConfig{}
+			},
+		},
+		{
+			name: "every problem at once",
+			modify: func(c *Config) {
+				c.Server.ListenAddr = "8080"
+				c.Database.Port = 0
+				c.Logging.Level = "loud"
+				c.RateLimit.Store = "redis"
+			},
+			wantErrs: []string{"server.listen_addr", "database.port", "logging", "rate_limit.store"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(&c)
+
+			err := c.Validate()
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate succeeded, want errors for %v", tt.wantErrs)
+			}
+			for _, setting := range tt.wantErrs {
+				if !strings.Contains(err.Error(), setting) {
+					t.Errorf("Validate error does not name %s:\n%v", setting, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		env     map[string]string
+		wantErr string
+		check   func(t *testing.T, c *Config)
+	}{
+		{
+			name: "environment only",
+			env: map[string]string{
+				"JWT_SECRET":            testSecret,
+				"DB_PASSWORD":           "a-real-database-password",
+				"NOTIFICATION_LOG":      "/tmp/notifications.log",
+				"CORS_ALLOWED_ORIGINS":  "https://a.example.com, https://b.example.com",
+				"RATE_LIMIT_LOGIN":      "off",
+				"JWT_VERIFICATION_KEYS": "old:" + testSecret + ",next:file:/etc/next.pem",
+			},
+			check: func(t *testing.T, c *Config) {
+				if len(c.CORS.AllowedOrigins) != 2 || c.CORS.AllowedOrigins[1] != "https://b.example.com" {
+					t.Errorf("allowed origins = %v", c.CORS.AllowedOrigins)
+				}
+				if !c.RateLimit.Login.Unlimited() {
+					t.Errorf("login limit = %s, want off", c.RateLimit.Login)
+				}
+				want := []JWTKey{{ID: "old", Secret: testSecret}, {ID: "next", File: "/etc/next.pem"}}
+				if len(c.Auth.JWTVerificationKeys) != 2 || c.Auth.JWTVerificationKeys[0] != want[0] || c.Auth.JWTVerificationKeys[1] != want[1] {
+					t.Errorf("verification keys = %+v, want %+v", c.Auth.JWTVerificationKeys, want)
+				}
+			},
+		},
+		{
+			name: "YAML file overridden by the environment",
+			file: "config.yaml",
+			content: `
+env: staging
+server:
+  listen_addr: :9090
+auth:
+  jwt_secret: ` + testSecret + `
+rate_limit:
+  login: 3/m
+notifications:
+  log_file: /var/log/notifications.log
+`,
+			env: map[string]string{"DB_PASSWORD": "a-real-database-password", "LISTEN_ADDR": ":7070"},
+			check: func(t *testing.T, c *Config) {
+				if c.Env != EnvStaging || c.Server.ListenAddr != ":7070" {
+					t.Errorf("env %q, listen address %q, want staging and :7070", c.Env, c.Server.ListenAddr)
+				}
+				if c.RateLimit.Login != (ratelimit.Limit{Burst: 3, Period: time.Minute}) {
+					t.Errorf("login limit = %s, want 3/m", c.RateLimit.Login)
+				}
+				if c.Server.ReadTimeout != Default().Server.ReadTimeout {
+					t.Errorf("read timeout = %s, want the default", c.Server.ReadTimeout)
+				}
+			},
+		},
+		{
+			name: "TOML file",
+			file: "config.toml",
+			content: `
+env = "development"
+
+[auth]
+jwt_secret = "secret"
+access_token_ttl = "5m"
+`,
+			check: func(t *testing.T, c *Config) {
+				if c.Auth.AccessTokenTTL != 5*time.Minute {
+					t.Errorf("access token TTL = %s, want 5m", c.Auth.AccessTokenTTL)
+				}
+			},
+		},
+		{
+			name:    "notification log required outside development",
+			env:     map[string]string{"JWT_SECRET": testSecret, "DB_PASSWORD": "a-real-database-password"},
+			wantErr: "notifications.log_file",
+		},
+		{
+			name:    "unknown setting in the file",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secrte: oops\n",
+			wantErr: "jwt_secrte",
+		},
+		{
+			name:    "unsupported file type",
+			file:    "config.json",
+			content: "{}",
+			wantErr: ".json",
+		},
+		{
+			name:    "invalid environment value",
+			env:     map[string]string{"APP_ENV": "development", "JWT_SECRET": "secret", "ACCESS_TOKEN_TTL": "15"},
+			wantErr: "ACCESS_TOKEN_TTL",
+		},
+		{
+			name:    "verification key without a secret",
+			env:     map[string]string{"APP_ENV": "development", "JWT_SECRET": "secret", "JWT_VERIFICATION_KEYS": "old"},
+			wantErr: "JWT_VERIFICATION_KEYS",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Run in an empty directory, so no .env file is picked up.
+			t.Chdir(t.TempDir())
+			clearEnv(t)
+			if tt.file != "" {
+				if err := os.WriteFile(tt.file, []byte(tt.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				t.Setenv("CONFIG_FILE", tt.file)
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			c, err := LoadConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig error = %v, want one naming %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if tt.check != nil {
+				tt.check(t, c)
+			}
+		})
+	}
+}
+
+// TestExampleConfig keeps config.example.yaml loadable.
+func TestExampleConfig(t *testing.T) {
+	path, err := filepath.Abs("../config.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(t.TempDir())
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("JWT_SECRET", testSecret)
+	t.Setenv("DB_PASSWORD", "a-real-database-password")
+
+	if _, err := LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	c := validConfig()
+	c.OIDC.ClientSecret = "client-secret"
+	c.Auth.JWTVerificationKeys = []JWTKey{{ID: "old", Secret: "old-secret"}, {ID: "next", File: "/etc/next.pem"}}
+
+	redactedConfig := c.Redacted()
+
+	for name, value := range map[string]string{
+		"database password":  redactedConfig.Database.Password,
+		"JWT secret":         redactedConfig.Auth.JWTSecret,
+		"OIDC client secret": redactedConfig.OIDC.ClientSecret,
+		"verification key":   redactedConfig.Auth.JWTVerificationKeys[0].Secret,
+	} {
+		if value != redacted {
+			t.Errorf("%s = %q, want it redacted", name, value)
+		}
+	}
+	if redactedConfig.Auth.JWTVerificationKeys[1].File != "/etc/next.pem" || redactedConfig.Auth.JWTVerificationKeys[1].Secret != "" {
+		t.Errorf("file key = %+v, want the file kept and no secret", redactedConfig.Auth.JWTVerificationKeys[1])
+	}
+	if c.Auth.JWTVerificationKeys[0].Secret != "old-secret" {
+		t.Error("Redacted changed the original config")
+	}
+}
+
+// clearEnv unsets the variables LoadConfig reads for the rest of the test.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, entry := range os.Environ() {
+		key, _, _ := strings.Cut(entry, "=")
+		if key == "CONFIG_FILE" || isConfigEnv(key) {
+			t.Setenv(key, "")
+		}
+	}
+}
+
+func isConfigEnv(key string) bool {
+	for _, prefix := range []string{"APP_", "LISTEN_", "HTTP_", "SHUTDOWN_", "READINESS_", "DB_", "MIGRATE_", "JWT_", "ACCESS_", "REFRESH_", "PASSWORD_", "EMAIL_", "MFA_", "PERMISSION_", "OIDC_", "CORS_", "LOG_", "RATE_LIMIT_", "LOGIN_", "NOTIFICATION_", "BOOKING_", "HOLD_", "REQUIRE_"} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package configs
+
+import (
+	"eventBookingSystem/internal/logging"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// DSN returns the connection URL of the database.
+func (c DatabaseConfig) DSN() string {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + c.Name,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+	}
+	return dsn.String()
+}
+
+func ConnectDB(config *Config) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(config.Database.DSN()), &gorm.Config{
+		Logger: logging.NewGormLogger(config.Database.SlowQueryThreshold),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(config.Database.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(config.Database.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(config.Database.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(config.Database.ConnMaxIdleTime)
+
+	slog.Info("Database connected")
+	return db, nil
+}
+
+// CloseDB closes the connection pool of db.
+func CloseDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
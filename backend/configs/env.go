@@ -0,0 +1,213 @@
+package configs
+
+import (
+	"errors"
+	"eventBookingSystem/internal/ratelimit"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// applyEnv overrides config with the environment variables that are set.
+func applyEnv(c *Config) error {
+	var env envReader
+
+	env.string("APP_ENV", &c.Env)
+
+	env.string("LISTEN_ADDR", &c.Server.ListenAddr)
+	env.duration("HTTP_READ_TIMEOUT", &c.Server.ReadTimeout)
+	env.duration("HTTP_READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout)
+	env.duration("HTTP_WRITE_TIMEOUT", &c.Server.WriteTimeout)
+	env.duration("HTTP_IDLE_TIMEOUT", &c.Server.IdleTimeout)
+	env.int("HTTP_MAX_HEADER_BYTES", &c.Server.MaxHeaderBytes)
+	env.duration("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+	env.duration("SHUTDOWN_DELAY", &c.Server.ShutdownDelay)
+	env.duration("READINESS_TIMEOUT", &c.Server.ReadinessTimeout)
+
+	env.string("DB_HOST", &c.Database.Host)
+	env.int("DB_PORT", &c.Database.Port)
+	env.string("DB_USER", &c.Database.User)
+	env.string("DB_PASSWORD", &c.Database.Password)
+	env.string("DB_NAME", &c.Database.Name)
+	env.string("DB_SSL_MODE", &c.Database.SSLMode)
+	env.int("DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns)
+	env.int("DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns)
+	env.duration("DB_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime)
+	env.duration("DB_CONN_MAX_IDLE_TIME", &c.Database.ConnMaxIdleTime)
+	env.duration("DB_SLOW_QUERY_THRESHOLD", &c.Database.SlowQueryThreshold)
+	env.bool("MIGRATE_ON_START", &c.Database.MigrateOnStart)
+
+	env.string("JWT_SECRET", &c.Auth.JWTSecret)
+	env.string("JWT_PRIVATE_KEY_FILE", &c.Auth.JWTPrivateKeyFile)
+	env.string("JWT_KEY_ID", &c.Auth.JWTKeyID)
+	env.keys("JWT_VERIFICATION_KEYS", &c.Auth.JWTVerificationKeys)
+	env.string("JWT_ISSUER", &c.Auth.JWTIssuer)
+	env.string("JWT_AUDIENCE", &c.Auth.JWTAudience)
+	env.duration("JWT_CLOCK_SKEW", &c.Auth.JWTClockSkew)
+	env.duration("ACCESS_TOKEN_TTL", &c.Auth.AccessTokenTTL)
+	env.duration("REFRESH_TOKEN_TTL", &c.Auth.RefreshTokenTTL)
+	env.duration("PASSWORD_RESET_TTL", &c.Auth.PasswordResetTTL)
+	env.duration("EMAIL_VERIFICATION_TTL", &c.Auth.EmailVerificationTTL)
+	env.duration("EMAIL_VERIFICATION_RESEND_INTERVAL", &c.Auth.EmailVerificationResendInterval)
+	env.string("MFA_ISSUER", &c.Auth.MFAIssuer)
+	env.duration("MFA_PENDING_TTL", &c.Auth.MFAPendingTTL)
+	env.duration("PERMISSION_CACHE_TTL", &c.Auth.PermissionCacheTTL)
+
+	env.string("OIDC_ISSUER_URL", &c.OIDC.IssuerURL)
+	env.string("OIDC_CLIENT_ID", &c.OIDC.ClientID)
+	env.string("OIDC_CLIENT_SECRET", &c.OIDC.ClientSecret)
+	env.string("OIDC_REDIRECT_URL", &c.OIDC.RedirectURL)
+	env.list("OIDC_SCOPES", &c.OIDC.Scopes)
+	env.bool("OIDC_AUTO_PROVISION", &c.OIDC.AutoProvision)
+	env.duration("OIDC_STATE_TTL", &c.OIDC.StateTTL)
+
+	env.list("CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins)
+	env.bool("CORS_ALLOW_CREDENTIALS", &c.CORS.AllowCredentials)
+
+	env.string("LOG_LEVEL", &c.Logging.Level)
+	env.string("LOG_FORMAT", &c.Logging.Format)
+
+	env.string("RATE_LIMIT_STORE", &c.RateLimit.Store)
+	env.bool("RATE_LIMIT_TRUST_PROXY", &c.RateLimit.TrustProxy)
+	env.limit("RATE_LIMIT_SETUP", &c.RateLimit.Setup)
+	env.limit("RATE_LIMIT_REGISTER", &c.RateLimit.Register)
+	env.limit("RATE_LIMIT_LOGIN", &c.RateLimit.Login)
+	env.limit("RATE_LIMIT_LOGIN_EMAIL", &c.RateLimit.LoginEmail)
+	env.limit("RATE_LIMIT_PASSWORD_RESET", &c.RateLimit.PasswordReset)
+	env.limit("RATE_LIMIT_PUBLIC", &c.RateLimit.Public)
+	env.limit("RATE_LIMIT_USER", &c.RateLimit.User)
+	env.int("LOGIN_LOCKOUT_THRESHOLD", &c.RateLimit.LoginLockout.Threshold)
+	env.duration("LOGIN_LOCKOUT_DELAY", &c.RateLimit.LoginLockout.Delay)
+	env.duration("LOGIN_LOCKOUT_MAX_DELAY", &c.RateLimit.LoginLockout.MaxDelay)
+	env.duration("LOGIN_LOCKOUT_WINDOW", &c.RateLimit.LoginLockout.Window)
+
+	env.string("APP_URL", &c.Notifications.AppURL)
+	env.string("NOTIFICATION_LOG", &c.Notifications.LogFile)
+
+	env.duration("BOOKING_HOLD_TTL", &c.Bookings.HoldTTL)
+	env.duration("HOLD_SWEEP_INTERVAL", &c.Bookings.HoldSweepInterval)
+
+	env.bool("REQUIRE_VERIFIED_EMAIL", &c.Features.RequireVerifiedEmail)
+	env.bool("REQUIRE_ADMIN_MFA", &c.Features.RequireAdminMFA)
+
+	return errors.Join(env.errs...)
+}
+
+// envReader sets settings from the environment variables that are set and
+// collects the values that do not parse.
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) lookup(key string) (string, bool) {
+	value, ok := os.LookupEnv(key)
+	return strings.TrimSpace(value), ok && strings.TrimSpace(value) != ""
+}
+
+func (e *envReader) invalid(key, value, want string) {
+	e.errs = append(e.errs, fmt.Errorf("%s: invalid value %q, want %s", key, value, want))
+}
+
+func (e *envReader) string(key string, target *string) {
+	if value, ok := e.lookup(key); ok {
+		*target = value
+	}
+}
+
+func (e *envReader) list(key string, target *[]string) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*target = items
+}
+
+// keys reads a comma-separated list of id:secret pairs, where the secret may
+// instead be file:path to name a PEM file.
+func (e *envReader) keys(key string, target *[]JWTKey) {
+	var items []string
+	e.list(key, &items)
+	if items == nil {
+		return
+	}
+
+	keys := make([]JWTKey, 0, len(items))
+	for _, item := range items {
+		id, secret, ok := strings.Cut(item, ":")
+		if !ok || id == "" || secret == "" {
+			e.errs = append(e.errs, fmt.Errorf("%s: invalid entry, want a comma-separated list of id:secret or id:file:path", key))
+			return
+		}
+		if path, ok := strings.CutPrefix(secret, "file:"); ok {
+			keys = append(keys, JWTKey{ID: id, File: path})
+			continue
+		}
+		keys = append(keys, JWTKey{ID: id, Secret: secret})
+	}
+	*target = keys
+}
+
+func (e *envReader) int(key string, target *int) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		e.invalid(key, value, "an integer")
+		return
+	}
+	*target = parsed
+}
+
+func (e *envReader) bool(key string, target *bool) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		e.invalid(key, value, "true or false")
+		return
+	}
+	*target = parsed
+}
+
+func (e *envReader) duration(key string, target *time.Duration) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		e.invalid(key, value, "a duration such as 30s or 15m")
+		return
+	}
+	*target = parsed
+}
+
+func (e *envReader) limit(key string, target *ratelimit.Limit) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+
+	parsed, err := ratelimit.ParseLimit(value)
+	if err != nil {
+		e.invalid(key, value, "a limit such as 10/m or off")
+		return
+	}
+	*target = parsed
+}
@@ -0,0 +1,44 @@
+package configs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// loadFile reads a YAML or TOML config file, chosen by its extension, into
+// config. Settings missing from the file keep their current value, unknown
+// settings are an error.
+func loadFile(path string, config *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), config)
+		if err != nil {
+			return fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parsing config file %s: unknown setting %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml, not %q", path, ext)
+	}
+	return nil
+}
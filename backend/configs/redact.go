@@ -0,0 +1,27 @@
+package configs
+
+// redacted replaces secrets in the output of Redacted.
+const redacted = "[REDACTED]"
+
+// Redacted returns a copy of the config with secrets replaced, for display.
+// Secrets that are not set stay empty.
+func (c Config) Redacted() Config {
+	c.Database.Password = redact(c.Database.Password)
+	c.Auth.JWTSecret = redact(c.Auth.JWTSecret)
+	keys := make([]JWTKey, len(c.Auth.JWTVerificationKeys))
+	for i, key := range c.Auth.JWTVerificationKeys {
+		keys[i] = JWTKey{ID: key.ID, Secret: redact(key.Secret), File: key.File}
+	}
+	c.Auth.JWTVerificationKeys = keys
+	c.OIDC.ClientSecret = redact(c.OIDC.ClientSecret)
+	c.OIDC.Scopes = append([]string(nil), c.OIDC.Scopes...)
+	c.CORS.AllowedOrigins = append([]string(nil), c.CORS.AllowedOrigins...)
+	return c
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
@@ -0,0 +1,192 @@
+package configs
+
+import (
+	"errors"
+	"eventBookingSystem/internal/logging"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minSecretLength is the shortest JWT secret accepted outside development,
+// 256 bits for HS256.
+const minSecretLength = 32
+
+// weakSecrets are well-known placeholder values that are refused outside
+// development whatever their length.
+var weakSecrets = []string{
+	"secret", "changeme", "change-me", "password", "postgres", "jwt_secret", "your-secret-key",
+}
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// Validate checks the whole configuration and reports every problem at once.
+func (c *Config) Validate() error {
+	var v validator
+
+	v.check(slices.Contains([]string{EnvDevelopment, EnvStaging, EnvProduction}, c.Env),
+		"env", "must be development, staging or production")
+
+	v.address("server.listen_addr", c.Server.ListenAddr)
+	v.positive("server.read_timeout", c.Server.ReadTimeout)
+	v.positive("server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	v.positive("server.write_timeout", c.Server.WriteTimeout)
+	v.positive("server.idle_timeout", c.Server.IdleTimeout)
+	v.check(c.Server.MaxHeaderBytes > 0, "server.max_header_bytes", "must be positive")
+	v.positive("server.shutdown_timeout", c.Server.ShutdownTimeout)
+	v.check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay", "must not be negative")
+	v.positive("server.readiness_timeout", c.Server.ReadinessTimeout)
+
+	v.required("database.host", c.Database.Host)
+	v.port("database.port", c.Database.Port)
+	v.required("database.user", c.Database.User)
+	v.required("database.name", c.Database.Name)
+	v.check(slices.Contains(sslModes, c.Database.SSLMode),
+		"database.ssl_mode", "must be one of "+strings.Join(sslModes, ", "))
+	v.check(c.Database.MaxOpenConns >= 0, "database.max_open_conns", "must not be negative, 0 is unlimited")
+	v.check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns", "must not be negative")
+	v.check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max_idle_conns", "must not exceed database.max_open_conns")
+	v.check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime", "must not be negative, 0 is unlimited")
+	v.check(c.Database.ConnMaxIdleTime >= 0, "database.conn_max_idle_time", "must not be negative, 0 is unlimited")
+	v.check(c.Database.SlowQueryThreshold >= 0, "database.slow_query_threshold", "must not be negative, 0 disables it")
+	if !c.IsDevelopment() {
+		v.check(!isWeakSecret(c.Database.Password),
+			"database.password", "must not be a default password outside development")
+	}
+
+	v.check((c.Auth.JWTSecret == "") != (c.Auth.JWTPrivateKeyFile == ""),
+		"auth.jwt_secret", "exactly one of auth.jwt_secret and auth.jwt_private_key_file is required")
+	v.secret(c, "auth.jwt_secret", c.Auth.JWTSecret)
+	v.required("auth.jwt_key_id", c.Auth.JWTKeyID)
+	keyIDs := map[string]bool{c.Auth.JWTKeyID: true}
+	for _, key := range c.Auth.JWTVerificationKeys {
+		v.check(key.ID != "" && (key.Secret == "") != (key.File == ""),
+			"auth.jwt_verification_keys", "every key needs an id and either a secret or a file")
+		v.check(!keyIDs[key.ID], "auth.jwt_verification_keys", fmt.Sprintf("key ID %q is used more than once", key.ID))
+		v.secret(c, "auth.jwt_verification_keys", key.Secret)
+		keyIDs[key.ID] = true
+	}
+	v.required("auth.jwt_issuer", c.Auth.JWTIssuer)
+	v.required("auth.jwt_audience", c.Auth.JWTAudience)
+	v.check(c.Auth.JWTClockSkew >= 0 && c.Auth.JWTClockSkew <= 5*time.Minute,
+		"auth.jwt_clock_skew", "must be between 0 and 5m")
+	v.positive("auth.access_token_ttl", c.Auth.AccessTokenTTL)
+	v.positive("auth.refresh_token_ttl", c.Auth.RefreshTokenTTL)
+	v.check(c.Auth.RefreshTokenTTL > c.Auth.AccessTokenTTL,
+		"auth.refresh_token_ttl", "must be longer than auth.access_token_ttl")
+	v.positive("auth.password_reset_ttl", c.Auth.PasswordResetTTL)
+	v.positive("auth.email_verification_ttl", c.Auth.EmailVerificationTTL)
+	v.check(c.Auth.EmailVerificationResendInterval >= 0,
+		"auth.email_verification_resend_interval", "must not be negative")
+	v.required("auth.mfa_issuer", c.Auth.MFAIssuer)
+	v.positive("auth.mfa_pending_ttl", c.Auth.MFAPendingTTL)
+	v.check(c.Auth.PermissionCacheTTL >= 0, "auth.permission_cache_ttl", "must not be negative, 0 disables the cache")
+
+	if c.OIDC.Enabled() {
+		v.check(isAbsoluteURL(c.OIDC.IssuerURL) && (c.IsDevelopment() || strings.HasPrefix(c.OIDC.IssuerURL, "https://")),
+			"oidc.issuer_url", "must be an absolute URL, https outside development")
+		v.required("oidc.client_id", c.OIDC.ClientID)
+		v.check(isAbsoluteURL(c.OIDC.RedirectURL), "oidc.redirect_url", "must be an absolute http(s) URL")
+		v.check(slices.Contains(c.OIDC.Scopes, "openid"), "oidc.scopes", `must contain "openid"`)
+		v.positive("oidc.state_ttl", c.OIDC.StateTTL)
+	}
+
+	v.check(len(c.CORS.AllowedOrigins) > 0, "cors.allowed_origins", "is required")
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			v.check(!c.CORS.AllowCredentials, "cors.allowed_origins", `must not contain "*" when cors.allow_credentials is set`)
+			continue
+		}
+		v.check(isOrigin(origin), "cors.allowed_origins", fmt.Sprintf("%q is not an origin like https://example.com", origin))
+	}
+
+	_, err := logging.New(io.Discard, c.Logging.Format, c.Logging.Level)
+	v.check(err == nil, "logging", fmt.Sprint(err))
+
+	v.check(c.RateLimit.Store == "memory" || c.RateLimit.Store == "postgres",
+		"rate_limit.store", "must be memory or postgres")
+	v.check(c.RateLimit.LoginLockout.Threshold >= 0,
+		"rate_limit.login_lockout.threshold", "must not be negative, 0 disables the lockout")
+	if c.RateLimit.LoginLockout.Threshold > 0 {
+		v.positive("rate_limit.login_lockout.delay", c.RateLimit.LoginLockout.Delay)
+		v.check(c.RateLimit.LoginLockout.MaxDelay >= c.RateLimit.LoginLockout.Delay,
+			"rate_limit.login_lockout.max_delay", "must not be shorter than rate_limit.login_lockout.delay")
+		v.positive("rate_limit.login_lockout.window", c.RateLimit.LoginLockout.Window)
+	}
+
+	v.check(isAbsoluteURL(c.Notifications.AppURL), "notifications.app_url", "must be an absolute http(s) URL")
+	if !c.IsDevelopment() {
+		v.check(strings.TrimSpace(c.Notifications.LogFile) != "",
+			"notifications.log_file", "is required outside development, notifications contain live links")
+	}
+
+	v.positive("bookings.hold_ttl", c.Bookings.HoldTTL)
+	v.positive("bookings.hold_sweep_interval", c.Bookings.HoldSweepInterval)
+
+	if len(v.errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(v.errs...))
+	}
+	return nil
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) check(ok bool, setting, msg string) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf("%s: %s", setting, msg))
+	}
+}
+
+func (v *validator) required(setting, value string) {
+	v.check(strings.TrimSpace(value) != "", setting, "is required")
+}
+
+func (v *validator) positive(setting string, value time.Duration) {
+	v.check(value > 0, setting, "must be positive")
+}
+
+// secret refuses weak JWT secrets outside development.
+func (v *validator) secret(c *Config, setting, secret string) {
+	if c.IsDevelopment() || secret == "" {
+		return
+	}
+	v.check(len(secret) >= minSecretLength && !isWeakSecret(secret),
+		setting, fmt.Sprintf("must be a random value of at least %d bytes outside development", minSecretLength))
+}
+
+func (v *validator) port(setting string, port int) {
+	v.check(port > 0 && port <= 65535, setting, "must be between 1 and 65535")
+}
+
+func (v *validator) address(setting, addr string) {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		v.check(false, setting, "must be host:port or :port")
+		return
+	}
+	number, err := strconv.Atoi(port)
+	v.check(err == nil && number >= 0 && number <= 65535, setting, "must have a port between 0 and 65535")
+}
+
+func isWeakSecret(secret string) bool {
+	return slices.Contains(weakSecrets, strings.ToLower(strings.TrimSpace(secret)))
+}
+
+func isOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.Path == "" && u.RawQuery == "" && u.Fragment == "" && u.User == nil
+}
+
+func isAbsoluteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
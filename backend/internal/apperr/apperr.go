@@ -0,0 +1,129 @@
+// Package apperr defines the typed errors handlers turn into API responses.
+//
+// Domain packages declare their errors with New (or implement Coded on their
+// own error types), and handlers hand every error to Write, which picks the
+// status code and renders the JSON error envelope.
+package apperr
+
+import (
+	"net/http"
+	"time"
+)
+
+// Code is the machine-readable kind of an error, included in every error
+// response.
+type Code string
+
+const (
+	CodeBadRequest      Code = "bad_request"
+	CodeValidation      Code = "validation_failed"
+	CodeUnauthenticated Code = "unauthenticated"
+	CodeForbidden       Code = "forbidden"
+	CodeNotFound        Code = "not_found"
+	CodeConflict        Code = "conflict"
+	CodeGone            Code = "gone"
+	CodePayloadTooLarge Code = "payload_too_large"
+	CodeTooManyRequests Code = "too_many_requests"
+	CodeInternal        Code = "internal"
+)
+
+var statusCodes = map[Code]int{
+	CodeBadRequest:      http.StatusBadRequest,
+	CodeValidation:      http.StatusBadRequest,
+	CodeUnauthenticated: http.StatusUnauthorized,
+	CodeForbidden:       http.StatusForbidden,
+	CodeNotFound:        http.StatusNotFound,
+	CodeConflict:        http.StatusConflict,
+	CodeGone:            http.StatusGone,
+	CodePayloadTooLarge: http.StatusRequestEntityTooLarge,
+	CodeTooManyRequests: http.StatusTooManyRequests,
+	CodeInternal:        http.StatusInternalServerError,
+}
+
+// Status returns the HTTP status code for the code.
+func (c Code) Status() int {
+	if status, ok := statusCodes[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Coded is implemented by errors that know their API code. Their message is
+// shown to the client as is, so it must not contain internal details.
+type Coded interface {
+	error
+	ErrorCode() Code
+}
+
+// Retryable is implemented by errors that tell the client when to try again.
+type Retryable interface {
+	RetryDelay() time.Duration
+}
+
+// Error is a client-facing error with an optional cause that is only logged.
+type Error struct {
+	Code    Code
+	Message string
+	// Fields holds per-field messages of validation errors.
+	Fields map[string]string
+	Cause  error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) ErrorCode() Code {
+	return e.Code
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// New returns an error with the given code. Domain packages use it to
+// declare their sentinel errors.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func BadRequest(message string) *Error {
+	return New(CodeBadRequest, message)
+}
+
+func Unauthenticated(message string) *Error {
+	return New(CodeUnauthenticated, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(CodeConflict, message)
+}
+
+func Gone(message string) *Error {
+	return New(CodeGone, message)
+}
+
+// Internal returns an error for failures the client cannot do anything
+// about. Only message is sent; cause is logged.
+func Internal(message string, cause error) *Error {
+	return &Error{Code: CodeInternal, Message: message, Cause: cause}
+}
+
+// Validation returns an error listing what is wrong with each field of the
+// request.
+func Validation(fields map[string]string) *Error {
+	return &Error{Code: CodeValidation, Message: "Request validation failed", Fields: fields}
+}
+
+// Field returns a validation error for a single field.
+func Field(field, message string) *Error {
+	return Validation(map[string]string{field: message})
+}
@@ -0,0 +1,89 @@
+package apperr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"eventBookingSystem/internal/logging"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// uniqueViolation is the Postgres SQLSTATE of unique constraint violations.
+const uniqueViolation = "23505"
+
+// Envelope is the body of every error response:
+//
+//	{"error": {"code": "validation_failed", "message": "...", "fields": {"email": "..."}}}
+type Envelope struct {
+	Error Body `json:"error"`
+}
+
+type Body struct {
+	Code    Code              `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Write sends err as a JSON error response. Errors that are not Coded are
+// mapped from well-known database errors, or else logged with the logger of
+// the request and reported as an internal error without details.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	body := From(r.Context(), err)
+
+	var retryable Retryable
+	if errors.As(err, &retryable) {
+		seconds := math.Ceil(retryable.RetryDelay().Seconds())
+		w.Header().Set("Retry-After", strconv.Itoa(int(max(seconds, 1))))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(body.Code.Status())
+	json.NewEncoder(w).Encode(Envelope{Error: body})
+}
+
+// From converts err into the body of its error response. Coded errors keep
+// their full message, so context wrapped around them with %w must be safe to
+// show to the client.
+func From(ctx context.Context, err error) Body {
+	var appErr *Error
+	if errors.As(err, &appErr) && appErr.Code == CodeInternal {
+		logging.FromContext(ctx).ErrorContext(ctx, "Internal error", "message", appErr.Message, "error", appErr.Cause)
+		return Body{Code: CodeInternal, Message: appErr.Message}
+	}
+
+	var coded Coded
+	if errors.As(err, &coded) {
+		body := Body{Code: coded.ErrorCode(), Message: err.Error()}
+		if appErr != nil {
+			body.Fields = appErr.Fields
+		}
+		return body
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Body{Code: CodeNotFound, Message: "Resource not found"}
+	}
+
+	if _, ok := UniqueViolation(err); ok {
+		return Body{Code: CodeConflict, Message: "Resource already exists"}
+	}
+
+	logging.FromContext(ctx).ErrorContext(ctx, "Internal error", "error", err)
+	return Body{Code: CodeInternal, Message: "Internal server error"}
+}
+
+// UniqueViolation reports whether err is a Postgres unique constraint
+// violation and returns the name of the violated constraint.
+func UniqueViolation(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return pgErr.ConstraintName, true
+	}
+	return "", false
+}
@@ -0,0 +1,127 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// retryError is a Coded error that asks the client to wait.
+type retryError struct{ delay time.Duration }
+
+func (e retryError) Error() string             { return "Slow down" }
+func (e retryError) ErrorCode() Code           { return CodeTooManyRequests }
+func (e retryError) RetryDelay() time.Duration { return e.delay }
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantStatus     int
+		wantBody       Body
+		wantRetryAfter string
+	}{
+		{
+			name:       "coded error",
+			err:        NotFound("Event not found"),
+			wantStatus: http.StatusNotFound,
+			wantBody:   Body{Code: CodeNotFound, Message: "Event not found"},
+		},
+		{
+			name:       "wrapped coded error keeps the context",
+			err:        fmt.Errorf("%w: seat 12", Conflict("Seat taken")),
+			wantStatus: http.StatusConflict,
+			wantBody:   Body{Code: CodeConflict, Message: "Seat taken: seat 12"},
+		},
+		{
+			name:       "validation error",
+			err:        Validation(map[string]string{"email": "Is required"}),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   Body{Code: CodeValidation, Message: "Request validation failed", Fields: map[string]string{"email": "Is required"}},
+		},
+		{
+			name:       "internal error hides its cause",
+			err:        Internal("Could not book", errors.New("pq: connection refused")),
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   Body{Code: CodeInternal, Message: "Could not book"},
+		},
+		{
+			name:       "record not found",
+			err:        fmt.Errorf("load event: %w", gorm.ErrRecordNotFound),
+			wantStatus: http.StatusNotFound,
+			wantBody:   Body{Code: CodeNotFound, Message: "Resource not found"},
+		},
+		{
+			name:       "unique violation",
+			err:        &pgconn.PgError{Code: uniqueViolation, ConstraintName: "users_email_key"},
+			wantStatus: http.StatusConflict,
+			wantBody:   Body{Code: CodeConflict, Message: "Resource already exists"},
+		},
+		{
+			name:       "unknown error",
+			err:        errors.New("secret internal detail"),
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   Body{Code: CodeInternal, Message: "Internal server error"},
+		},
+		{
+			name:           "retryable error rounds up",
+			err:            retryError{delay: 1500 * time.Millisecond},
+			wantStatus:     http.StatusTooManyRequests,
+			wantBody:       Body{Code: CodeTooManyRequests, Message: "Slow down"},
+			wantRetryAfter: "2",
+		},
+		{
+			name:           "retryable error waits at least a second",
+			err:            retryError{delay: time.Millisecond},
+			wantStatus:     http.StatusTooManyRequests,
+			wantBody:       Body{Code: CodeTooManyRequests, Message: "Slow down"},
+			wantRetryAfter: "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Write(w, httptest.NewRequest(http.MethodGet, "/", nil), tt.err)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q", got)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+
+			var envelope Envelope
+			if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+				t.Fatalf("decode %s: %v", w.Body, err)
+			}
+			if !reflect.DeepEqual(envelope.Error, tt.wantBody) {
+				t.Errorf("body = %+v, want %+v", envelope.Error, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCodeStatus(t *testing.T) {
+	for code, want := range map[Code]int{
+		CodeBadRequest:      http.StatusBadRequest,
+		CodeUnauthenticated: http.StatusUnauthorized,
+		CodeGone:            http.StatusGone,
+		CodePayloadTooLarge: http.StatusRequestEntityTooLarge,
+		Code("unknown"):     http.StatusInternalServerError,
+	} {
+		if got := code.Status(); got != want {
+			t.Errorf("%s.Status() = %d, want %d", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Package oidc is the client side of an OpenID Connect login with the
+// authorization code flow and PKCE (RFC 7636).
+//
+// The provider is discovered from its issuer URL on first use, not on
+// startup, so the server still starts while the provider is unreachable.
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// Options configure a Client.
+type Options struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is where the provider sends the browser back to with the
+	// authorization code.
+	RedirectURL string
+	Scopes      []string
+	// HTTPClient talks to the provider. It defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Identity is the verified identity from an ID token.
+type Identity struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// Client logs users in with one provider.
+type Client struct {
+	Options
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func NewClient(options Options) *Client {
+	if options.HTTPClient == nil {
+		options.HTTPClient = http.DefaultClient
+	}
+	return &Client{Options: options}
+}
+
+// AuthCodeURL returns the URL of the provider's login page. state is handed
+// back with the code, nonce ends up in the ID token and codeVerifier has to
+// be presented when exchanging the code; all three must be random and used
+// once.
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	oauth, _, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(codeVerifier)), nil
+}
+
+// Exchange redeems an authorization code and verifies the ID token that
+// comes with it: its signature, issuer, audience, expiry and nonce.
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	oauth, verifier, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = c.context(ctx)
+	token, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response has no ID token")
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verify ID token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("verify ID token: nonce does not match")
+	}
+
+	var claims struct {
+		Email             string    `json:"email"`
+		EmailVerified     claimBool `json:"email_verified"`
+		Name              string    `json:"name"`
+		PreferredUsername string    `json:"preferred_username"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("decode ID token claims: %w", err)
+	}
+
+	return &Identity{
+		Issuer:            idToken.Issuer,
+		Subject:           idToken.Subject,
+		Email:             claims.Email,
+		EmailVerified:     bool(claims.EmailVerified),
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+	}, nil
+}
+
+// discover fetches the provider's metadata once it is first needed. A
+// failed attempt is retried on the next call.
+func (c *Client) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.oauth != nil {
+		return c.oauth, c.verifier, nil
+	}
+
+	// The provider keeps the context to fetch its signing keys later, so it
+	// must not be canceled with the request.
+	provider, err := oidc.NewProvider(c.context(context.WithoutCancel(ctx)), c.IssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("discover OpenID provider %s: %w", c.IssuerURL, err)
+	}
+
+	c.oauth = &oauth2.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  c.RedirectURL,
+		Scopes:       c.Scopes,
+	}
+	c.verifier = provider.Verifier(&oidc.Config{ClientID: c.ClientID})
+	return c.oauth, c.verifier, nil
+}
+
+// context makes both oauth2 and go-oidc use the configured HTTP client.
+func (c *Client) context(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, c.HTTPClient)
+	return oidc.ClientContext(ctx, c.HTTPClient)
+}
+
+// claimBool accepts the email_verified claim both as a JSON boolean and as
+// the string some providers send.
+type claimBool bool
+
+func (b *claimBool) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", `"true"`:
+		*b = true
+	case "false", `"false"`, "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean claim %s", data)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package oidc_test
+
+import (
+	"eventBookingSystem/internal/auth/oidc"
+	"eventBookingSystem/internal/auth/oidc/oidctest"
+	"testing"
+)
+
+func TestClientExchange(t *testing.T) {
+	provider := oidctest.NewProvider(t)
+	client := oidc.NewClient(oidc.Options{
+		IssuerURL:    provider.URL,
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  "https://app.example.com/callback",
+		Scopes:       []string{"openid", "email"},
+	})
+	login := oidctest.Login{Subject: "subject", Email: "ada@example.com", EmailVerified: true, PreferredUsername: "ada"}
+
+	tests := []struct {
+		name         string
+		login        oidctest.Login
+		codeVerifier string
+		nonce        string
+		wantErr      bool
+	}{
+		{name: "valid", login: login, codeVerifier: "verifier", nonce: "nonce"},
+		{name: "wrong code verifier", login: login, codeVerifier: "other verifier", nonce: "nonce", wantErr: true},
+		{name: "wrong nonce", login: login, codeVerifier: "verifier", nonce: "other nonce", wantErr: true},
+		{name: "nonce replaced by provider", login: oidctest.Login{Subject: "subject", Nonce: "replayed"}, codeVerifier: "verifier", nonce: "nonce", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+			authURL, err := client.AuthCodeURL(ctx, "state", "nonce", "verifier")
+			if err != nil {
+				t.Fatalf("AuthCodeURL: %v", err)
+			}
+			code, state := provider.Authorize(t, authURL, tt.login)
+			if state != "state" {
+				t.Errorf("state = %q, want %q", state, "state")
+			}
+
+			identity, err := client.Exchange(ctx, code, tt.codeVerifier, tt.nonce)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Exchange = %+v, want an error", identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+
+			want := oidc.Identity{
+				Issuer:            provider.URL,
+				Subject:           "subject",
+				Email:             "ada@example.com",
+				EmailVerified:     true,
+				PreferredUsername: "ada",
+			}
+			if *identity != want {
+				t.Errorf("identity = %+v, want %+v", *identity, want)
+			}
+
+			if _, err := client.Exchange(ctx, code, tt.codeVerifier, tt.nonce); err == nil {
+				t.Error("second Exchange of the same code succeeded")
+			}
+		})
+	}
+}
+
+func TestClientUnreachableProvider(t *testing.T) {
+	provider := oidctest.NewProvider(t)
+	issuer := provider.URL
+	provider.Close()
+
+	client := oidc.NewClient(oidc.Options{IssuerURL: issuer, ClientID: "client"})
+	if _, err := client.AuthCodeURL(t.Context(), "state", "nonce", "verifier"); err == nil {
+		t.Error("AuthCodeURL succeeded without a provider")
+	}
+}
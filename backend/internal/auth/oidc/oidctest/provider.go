@@ -0,0 +1,211 @@
+// Package oidctest runs an OpenID Connect provider in the process for tests.
+// It serves discovery, its signing keys and the token endpoint; the login
+// page is skipped by Authorize, which hands out a code for an authorization
+// URL directly.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "test-key"
+
+// Login is the identity a user logs in with at the provider.
+type Login struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	// Nonce replaces the nonce of the authorization request in the ID token
+	// if set.
+	Nonce string
+}
+
+// Provider is an OpenID Connect provider on a local HTTP server.
+type Provider struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]grant
+}
+
+// grant is an authorization code waiting to be exchanged.
+type grant struct {
+	login         Login
+	nonce         string
+	codeChallenge string
+	redirectURL   string
+}
+
+// NewProvider starts a provider that is shut down with the test.
+func NewProvider(t *testing.T) *Provider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate provider key: %v", err)
+	}
+
+	p := &Provider{
+		ClientID:     "test-client",
+		ClientSecret: "test-secret",
+		key:          key,
+		codes:        make(map[string]grant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("GET /keys", p.keys)
+	mux.HandleFunc("POST /token", p.token)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+// Authorize logs the user in for the authorization URL of a client and
+// returns the code and state the provider would redirect back with.
+func (p *Provider) Authorize(t *testing.T, authURL string, login Login) (code, state string) {
+	t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse authorization URL: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != p.ClientID {
+		t.Fatalf("authorization URL has client_id %q, want %q", query.Get("client_id"), p.ClientID)
+	}
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("authorization URL has code_challenge_method %q, want S256", query.Get("code_challenge_method"))
+	}
+
+	code = rand.Text()
+	p.mu.Lock()
+	p.codes[code] = grant{
+		login:         login,
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		redirectURL:   query.Get("redirect_uri"),
+	}
+	p.mu.Unlock()
+
+	return code, query.Get("state")
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.URL,
+		"authorization_endpoint":                p.URL + "/authorize",
+		"token_endpoint":                        p.URL + "/token",
+		"jwks_uri":                              p.URL + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (p *Provider) keys(w http.ResponseWriter, r *http.Request) {
+	public := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}},
+	})
+}
+
+// token redeems an authorization code once, if the client proves it holds
+// the code verifier of the authorization request.
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != p.ClientID || clientSecret != p.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	code := r.PostForm.Get("code")
+	grant, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+
+	if r.PostForm.Get("grant_type") != "authorization_code" || !ok || r.PostForm.Get("redirect_uri") != grant.redirectURL {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(challenge[:]) != grant.codeChallenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	nonce := grant.nonce
+	if grant.login.Nonce != "" {
+		nonce = grant.login.Nonce
+	}
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                p.URL,
+		"sub":                grant.login.Subject,
+		"aud":                p.ClientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              nonce,
+		"email":              grant.login.Email,
+		"email_verified":     grant.login.EmailVerified,
+		"preferred_username": grant.login.PreferredUsername,
+	})
+	idToken.Header["kid"] = keyID
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": rand.Text(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
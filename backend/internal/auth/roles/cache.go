@@ -0,0 +1,60 @@
+package roles
+
+import (
+	"sync"
+	"time"
+)
+
+// permissionCache keeps the effective permissions of recently seen users so
+// permission checks do not hit the database on every request. Entries expire
+// after ttl, which bounds how stale another replica's view can get after a
+// role change.
+type permissionCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[string]cachedPermissions
+}
+
+type cachedPermissions struct {
+	permissions map[string]bool
+	expiresAt   time.Time
+}
+
+func newPermissionCache(ttl time.Duration) *permissionCache {
+	return &permissionCache{ttl: ttl, entries: make(map[string]cachedPermissions)}
+}
+
+func (c *permissionCache) get(userID string) (map[string]bool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[userID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.permissions, true
+}
+
+func (c *permissionCache) set(userID string, permissions []string) map[string]bool {
+	set := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		set[permission] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[userID] = cachedPermissions{permissions: set, expiresAt: time.Now().Add(c.ttl)}
+	return set
+}
+
+func (c *permissionCache) invalidate(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, userID)
+}
+
+func (c *permissionCache) invalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cachedPermissions)
+}
@@ -0,0 +1,135 @@
+package roles
+
+import (
+	"encoding/json"
+	"eventBookingSystem/internal/apperr"
+	"eventBookingSystem/internal/request"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type RoleHandler struct {
+	RoleService RoleService
+}
+
+func NewRoleHandler(roleService RoleService) *RoleHandler {
+	return &RoleHandler{RoleService: roleService}
+}
+
+func (h *RoleHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.RoleService.ListRoles(r.Context())
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roles)
+}
+
+func (h *RoleHandler) GetRole(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	role, err := h.RoleService.GetRole(r.Context(), name)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(role)
+}
+
+func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var req RoleRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	role, err := h.RoleService.CreateRole(r.Context(), req.Name, req.Description, req.Permissions)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(role)
+}
+
+func (h *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var req RoleRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	role, err := h.RoleService.UpdateRole(r.Context(), name, req.Description, req.Permissions)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(role)
+}
+
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	if err := h.RoleService.DeleteRole(r.Context(), name); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *RoleHandler) GetUserRoles(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userID")
+	if _, err := uuid.Parse(userID); err != nil {
+		apperr.Write(w, r, apperr.BadRequest("Invalid user ID"))
+		return
+	}
+
+	roles, err := h.RoleService.GetUserRoles(r.Context(), userID)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"roles": roles})
+}
+
+// SetUserRoles replaces all roles of a user.
+func (h *RoleHandler) SetUserRoles(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userID")
+	if _, err := uuid.Parse(userID); err != nil {
+		apperr.Write(w, r, apperr.BadRequest("Invalid user ID"))
+		return
+	}
+
+	var req SetUserRolesRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	if err := h.RoleService.SetUserRoles(r.Context(), userID, req.Roles); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListPermissions returns every permission a role can be granted.
+func (h *RoleHandler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AllPermissions)
+}
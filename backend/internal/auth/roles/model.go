@@ -0,0 +1,44 @@
+package roles
+
+import (
+	"eventBookingSystem/internal/apperr"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrRoleNotFound    = apperr.NotFound("role not found")
+	ErrRoleExists      = apperr.Conflict("role already exists")
+	ErrDefaultRole     = apperr.Conflict("default roles cannot be deleted")
+	ErrInvalidRoleName = apperr.BadRequest("role name must be 2-50 lowercase letters, digits, '-' or '_', starting with a letter")
+)
+
+type Role struct {
+	Name        string   `gorm:"type:varchar(50);primaryKey"`
+	Description string   `gorm:"type:text"`
+	Permissions []string `gorm:"type:jsonb;serializer:json;not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// UserRole assigns a role to a user. A user has the union of the permissions
+// of all their roles.
+type UserRole struct {
+	UserID    string `gorm:"type:uuid;primaryKey"`
+	RoleName  string `gorm:"type:varchar(50);primaryKey;index"`
+	CreatedAt time.Time
+}
+
+// UnknownPermissionError is returned when a role is given a permission the
+// application does not know.
+type UnknownPermissionError struct {
+	Permission string
+}
+
+func (e *UnknownPermissionError) Error() string {
+	return fmt.Sprintf("unknown permission %q", e.Permission)
+}
+
+func (e *UnknownPermissionError) ErrorCode() apperr.Code {
+	return apperr.CodeBadRequest
+}
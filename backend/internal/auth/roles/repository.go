@@ -0,0 +1,144 @@
+package roles
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RoleRepository interface {
+	Create(ctx context.Context, role *Role) error
+	GetByName(ctx context.Context, name string) (*Role, error)
+	GetAll(ctx context.Context) ([]Role, error)
+	Update(ctx context.Context, role *Role) error
+	Delete(ctx context.Context, name string) error
+	GetUserRoles(ctx context.Context, userID string) ([]string, error)
+	SetUserRoles(ctx context.Context, userID string, roleNames []string) error
+	AddUserRole(ctx context.Context, userID, roleName string) error
+	GetUserPermissions(ctx context.Context, userID string) ([]string, error)
+}
+
+type RoleRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &RoleRepositoryImpl{DB: db}
+}
+
+func (r *RoleRepositoryImpl) Create(ctx context.Context, role *Role) error {
+	result := r.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRoleExists
+	}
+	return nil
+}
+
+func (r *RoleRepositoryImpl) GetByName(ctx context.Context, name string) (*Role, error) {
+	var role Role
+	err := r.DB.WithContext(ctx).First(&role, "name = ?", name).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoleNotFound
+	}
+	return &role, err
+}
+
+func (r *RoleRepositoryImpl) GetAll(ctx context.Context) ([]Role, error) {
+	var roles []Role
+	err := r.DB.WithContext(ctx).Order("name").Find(&roles).Error
+	return roles, err
+}
+
+func (r *RoleRepositoryImpl) Update(ctx context.Context, role *Role) error {
+	return r.DB.WithContext(ctx).Save(role).Error
+}
+
+// Delete removes the role and takes it away from every user that had it.
+func (r *RoleRepositoryImpl) Delete(ctx context.Context, name string) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_name = ?", name).Delete(&UserRole{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&Role{}, "name = ?", name)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRoleNotFound
+		}
+		return nil
+	})
+}
+
+func (r *RoleRepositoryImpl) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	var names []string
+	err := r.DB.WithContext(ctx).Model(&UserRole{}).
+		Where("user_id = ?", userID).
+		Order("role_name").
+		Pluck("role_name", &names).Error
+	return names, err
+}
+
+// SetUserRoles replaces all of the user's roles.
+func (r *RoleRepositoryImpl) SetUserRoles(ctx context.Context, userID string, roleNames []string) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&UserRole{}).Error; err != nil {
+			return err
+		}
+		if len(roleNames) == 0 {
+			return nil
+		}
+
+		userRoles := make([]UserRole, len(roleNames))
+		for i, name := range roleNames {
+			userRoles[i] = UserRole{UserID: userID, RoleName: name}
+		}
+		return tx.Create(&userRoles).Error
+	})
+}
+
+func (r *RoleRepositoryImpl) AddUserRole(ctx context.Context, userID, roleName string) error {
+	return r.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&UserRole{UserID: userID, RoleName: roleName}).Error
+}
+
+// GetUserPermissions returns the permissions of all of the user's roles,
+// possibly with duplicates.
+func (r *RoleRepositoryImpl) GetUserPermissions(ctx context.Context, userID string) ([]string, error) {
+	var roles []Role
+	err := r.DB.WithContext(ctx).
+		Joins("JOIN user_roles ON user_roles.role_name = roles.name").
+		Where("user_roles.user_id = ?", userID).
+		Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var permissions []string
+	for _, role := range roles {
+		permissions = append(permissions, role.Permissions...)
+	}
+	return permissions, nil
+}
+
+// Seed creates the default roles that do not exist yet. It is safe to run on
+// every start. Users from before role management got their roles in
+// migration 0005, and admin roles seeded before the manage_any permissions
+// got those in migration 0007.
+func Seed(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for name, permissions := range DefaultRolePermissions {
+			err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&Role{Name: name, Permissions: permissions}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,34 @@
+package roles
+
+import (
+	"eventBookingSystem/internal/request"
+	"strings"
+)
+
+const maxRoleDescriptionLength = 500
+
+// RoleRequest is the body of creating and updating a role. The name and the
+// permissions are checked by the service, updates ignore the name.
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+func (r *RoleRequest) Normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Description = strings.TrimSpace(r.Description)
+}
+
+func (r *RoleRequest) Validate(v *request.Validator) {
+	v.MaxLength("description", r.Description, maxRoleDescriptionLength)
+	v.Each("permissions", r.Permissions, v.Required)
+}
+
+type SetUserRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+func (r *SetUserRolesRequest) Validate(v *request.Validator) {
+	v.Each("roles", r.Roles, v.Required)
+}
@@ -1,10 +1,9 @@
 package roles
 
-import "slices"
-
 const (
-	RoleUser  = "user"
-	RoleAdmin = "admin"
+	RoleUser      = "user"
+	RoleOrganizer = "organizer"
+	RoleAdmin     = "admin"
 )
 
 const (
@@ -13,35 +12,65 @@ const (
 	PermissionUpdateEvents   = "events:update"
 	PermissionDeleteEvents   = "events:delete"
 	PermissionManageUsers    = "users:manage"
+	PermissionManageRoles    = "roles:manage"
 	PermissionCreateBookings = "bookings:create"
 	PermissionReadBookings   = "bookings:read"
 	PermissionCancelBookings = "bookings:cancel"
+
+	// PermissionManageAnyBookings lets a user read and change bookings of
+	// other users, not only their own.
+	PermissionManageAnyBookings = "bookings:manage_any"
+
+	// PermissionManageAnyEvents lets a user change events organized by
+	// others and transfer event ownership.
+	PermissionManageAnyEvents = "events:manage_any"
 )
 
-var RolePermissions = map[string][]string{
+// AllPermissions lists every permission the application checks. Roles can
+// only be granted permissions from this list.
+var AllPermissions = []string{
+	PermissionReadEvents,
+	PermissionCreateEvents,
+	PermissionUpdateEvents,
+	PermissionDeleteEvents,
+	PermissionManageUsers,
+	PermissionManageRoles,
+	PermissionCreateBookings,
+	PermissionReadBookings,
+	PermissionCancelBookings,
+	PermissionManageAnyBookings,
+	PermissionManageAnyEvents,
+}
+
+// DefaultRolePermissions are the roles seeded into an empty database. Once
+// seeded, roles are managed through the API and this map is not consulted.
+var DefaultRolePermissions = map[string][]string{
 	RoleUser: {
 		PermissionReadEvents,
 		PermissionCreateBookings,
 		PermissionReadBookings,
 		PermissionCancelBookings,
 	},
+	RoleOrganizer: {
+		PermissionReadEvents,
+		PermissionCreateEvents,
+		PermissionUpdateEvents,
+		PermissionDeleteEvents,
+		PermissionCreateBookings,
+		PermissionReadBookings,
+		PermissionCancelBookings,
+	},
 	RoleAdmin: {
 		PermissionReadEvents,
 		PermissionCreateEvents,
 		PermissionUpdateEvents,
 		PermissionDeleteEvents,
 		PermissionManageUsers,
+		PermissionManageRoles,
 		PermissionReadBookings,
 		PermissionCreateBookings,
 		PermissionCancelBookings,
+		PermissionManageAnyBookings,
+		PermissionManageAnyEvents,
 	},
 }
-
-func HasPermission(role, permission string) bool {
-	permissions, exists := RolePermissions[role]
-	if !exists {
-		return false
-	}
-
-	return slices.Contains(permissions, permission)
-}
@@ -0,0 +1,178 @@
+package roles
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+
+type RoleService interface {
+	ListRoles(ctx context.Context) ([]Role, error)
+	GetRole(ctx context.Context, name string) (*Role, error)
+	CreateRole(ctx context.Context, name, description string, permissions []string) (*Role, error)
+	UpdateRole(ctx context.Context, name, description string, permissions []string) (*Role, error)
+	DeleteRole(ctx context.Context, name string) error
+	GetUserRoles(ctx context.Context, userID string) ([]string, error)
+	SetUserRoles(ctx context.Context, userID string, roleNames []string) error
+	AssignRole(ctx context.Context, userID, roleName string) error
+	UserHasRole(ctx context.Context, userID, roleName string) (bool, error)
+	UserHasPermission(ctx context.Context, userID, permission string) (bool, error)
+}
+
+type RoleServiceImpl struct {
+	RoleRepository RoleRepository
+	cache          *permissionCache
+}
+
+func NewRoleService(roleRepository RoleRepository, cacheTTL time.Duration) RoleService {
+	return &RoleServiceImpl{
+		RoleRepository: roleRepository,
+		cache:          newPermissionCache(cacheTTL),
+	}
+}
+
+func (s *RoleServiceImpl) ListRoles(ctx context.Context) ([]Role, error) {
+	return s.RoleRepository.GetAll(ctx)
+}
+
+func (s *RoleServiceImpl) GetRole(ctx context.Context, name string) (*Role, error) {
+	return s.RoleRepository.GetByName(ctx, name)
+}
+
+func (s *RoleServiceImpl) CreateRole(ctx context.Context, name, description string, permissions []string) (*Role, error) {
+	if !roleNamePattern.MatchString(name) {
+		return nil, ErrInvalidRoleName
+	}
+
+	permissions, err := normalizePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &Role{
+		Name:        name,
+		Description: strings.TrimSpace(description),
+		Permissions: permissions,
+	}
+
+	if err := s.RoleRepository.Create(ctx, role); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func (s *RoleServiceImpl) UpdateRole(ctx context.Context, name, description string, permissions []string) (*Role, error) {
+	role, err := s.RoleRepository.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err = normalizePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Description = strings.TrimSpace(description)
+	role.Permissions = permissions
+
+	if err := s.RoleRepository.Update(ctx, role); err != nil {
+		return nil, err
+	}
+
+	s.cache.invalidateAll()
+	return role, nil
+}
+
+func (s *RoleServiceImpl) DeleteRole(ctx context.Context, name string) error {
+	if _, ok := DefaultRolePermissions[name]; ok {
+		return ErrDefaultRole
+	}
+
+	if err := s.RoleRepository.Delete(ctx, name); err != nil {
+		return err
+	}
+
+	s.cache.invalidateAll()
+	return nil
+}
+
+func (s *RoleServiceImpl) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	return s.RoleRepository.GetUserRoles(ctx, userID)
+}
+
+// SetUserRoles replaces the user's roles. Every role must exist.
+func (s *RoleServiceImpl) SetUserRoles(ctx context.Context, userID string, roleNames []string) error {
+	slices.Sort(roleNames)
+	roleNames = slices.Compact(roleNames)
+	for _, name := range roleNames {
+		if _, err := s.RoleRepository.GetByName(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	if err := s.RoleRepository.SetUserRoles(ctx, userID, roleNames); err != nil {
+		return err
+	}
+
+	s.cache.invalidate(userID)
+	return nil
+}
+
+// AssignRole gives the user one more role.
+func (s *RoleServiceImpl) AssignRole(ctx context.Context, userID, roleName string) error {
+	if _, err := s.RoleRepository.GetByName(ctx, roleName); err != nil {
+		return err
+	}
+
+	if err := s.RoleRepository.AddUserRole(ctx, userID, roleName); err != nil {
+		return err
+	}
+
+	s.cache.invalidate(userID)
+	return nil
+}
+
+// UserHasRole reports whether the user has been given the role. It satisfies
+// users.RoleChecker.
+func (s *RoleServiceImpl) UserHasRole(ctx context.Context, userID, roleName string) (bool, error) {
+	names, err := s.RoleRepository.GetUserRoles(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(names, roleName), nil
+}
+
+// UserHasPermission reports whether any of the user's roles grants the
+// permission. It satisfies middleware.PermissionChecker.
+func (s *RoleServiceImpl) UserHasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	permissions, ok := s.cache.get(userID)
+	if !ok {
+		loaded, err := s.RoleRepository.GetUserPermissions(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		permissions = s.cache.set(userID, loaded)
+	}
+
+	return permissions[permission], nil
+}
+
+// normalizePermissions sorts and de-duplicates the permissions, rejecting
+// any the application does not know.
+func normalizePermissions(permissions []string) ([]string, error) {
+	normalized := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		if !slices.Contains(AllPermissions, permission) {
+			return nil, &UnknownPermissionError{Permission: permission}
+		}
+		normalized = append(normalized, permission)
+	}
+
+	slices.Sort(normalized)
+	return slices.Compact(normalized), nil
+}
@@ -0,0 +1,257 @@
+package roles
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeRoleRepository keeps roles in memory and counts permission lookups.
+type fakeRoleRepository struct {
+	roles             map[string]Role
+	userRoles         map[string][]string
+	permissionLookups int
+}
+
+func newFakeRoleRepository() *fakeRoleRepository {
+	r := &fakeRoleRepository{roles: make(map[string]Role), userRoles: make(map[string][]string)}
+	for name, permissions := range DefaultRolePermissions {
+		r.roles[name] = Role{Name: name, Permissions: permissions}
+	}
+	return r
+}
+
+func (r *fakeRoleRepository) Create(ctx context.Context, role *Role) error {
+	if _, ok := r.roles[role.Name]; ok {
+		return ErrRoleExists
+	}
+	r.roles[role.Name] = *role
+	return nil
+}
+
+func (r *fakeRoleRepository) GetByName(ctx context.Context, name string) (*Role, error) {
+	role, ok := r.roles[name]
+	if !ok {
+		return nil, ErrRoleNotFound
+	}
+	return &role, nil
+}
+
+func (r *fakeRoleRepository) GetAll(ctx context.Context) ([]Role, error) {
+	var roles []Role
+	for _, role := range r.roles {
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+func (r *fakeRoleRepository) Update(ctx context.Context, role *Role) error {
+	r.roles[role.Name] = *role
+	return nil
+}
+
+func (r *fakeRoleRepository) Delete(ctx context.Context, name string) error {
+	if _, ok := r.roles[name]; !ok {
+		return ErrRoleNotFound
+	}
+	delete(r.roles, name)
+	for userID, names := range r.userRoles {
+		r.userRoles[userID] = slices.DeleteFunc(names, func(n string) bool { return n == name })
+	}
+	return nil
+}
+
+func (r *fakeRoleRepository) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	return r.userRoles[userID], nil
+}
+
+func (r *fakeRoleRepository) SetUserRoles(ctx context.Context, userID string, roleNames []string) error {
+	r.userRoles[userID] = roleNames
+	return nil
+}
+
+func (r *fakeRoleRepository) AddUserRole(ctx context.Context, userID, roleName string) error {
+	if !slices.Contains(r.userRoles[userID], roleName) {
+		r.userRoles[userID] = append(r.userRoles[userID], roleName)
+	}
+	return nil
+}
+
+func (r *fakeRoleRepository) GetUserPermissions(ctx context.Context, userID string) ([]string, error) {
+	r.permissionLookups++
+	var permissions []string
+	for _, name := range r.userRoles[userID] {
+		permissions = append(permissions, r.roles[name].Permissions...)
+	}
+	return permissions, nil
+}
+
+func TestCreateRole(t *testing.T) {
+	tests := []struct {
+		name            string
+		roleName        string
+		permissions     []string
+		wantPermissions []string
+		wantErr         error
+		// wantUnknown is the permission reported as unknown.
+		wantUnknown string
+	}{
+		{
+			name:            "sorts and de-duplicates permissions",
+			roleName:        "support",
+			permissions:     []string{PermissionReadBookings, PermissionReadEvents, PermissionReadBookings},
+			wantPermissions: []string{PermissionReadBookings, PermissionReadEvents},
+		},
+		{
+			name:            "without permissions",
+			roleName:        "guest_2",
+			wantPermissions: []string{},
+		},
+		{
+			name:        "unknown permission",
+			roleName:    "support",
+			permissions: []string{"bookings:delete_all"},
+			wantUnknown: "bookings:delete_all",
+		},
+		{name: "upper case name", roleName: "Support", wantErr: ErrInvalidRoleName},
+		{name: "name too short", roleName: "s", wantErr: ErrInvalidRoleName},
+		{name: "name starting with a digit", roleName: "2nd", wantErr: ErrInvalidRoleName},
+		{name: "existing role", roleName: RoleAdmin, wantErr: ErrRoleExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewRoleService(newFakeRoleRepository(), time.Minute)
+
+			role, err := service.CreateRole(t.Context(), tt.roleName, "  Description  ", tt.permissions)
+			if tt.wantUnknown != "" {
+				var unknown *UnknownPermissionError
+				if !errors.As(err, &unknown) || unknown.Permission != tt.wantUnknown {
+					t.Fatalf("CreateRole = %v, want %q reported as unknown", err, tt.wantUnknown)
+				}
+				return
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CreateRole = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+			if !slices.Equal(role.Permissions, tt.wantPermissions) || role.Description != "Description" {
+				t.Errorf("role = %+v, want permissions %v and a trimmed description", role, tt.wantPermissions)
+			}
+		})
+	}
+}
+
+func TestUserHasPermission(t *testing.T) {
+	ctx := t.Context()
+
+	tests := []struct {
+		name string
+		// change runs after the permissions of ada were cached.
+		change func(t *testing.T, service RoleService)
+		want   bool
+		// wantLookups is how often the permissions are loaded.
+		wantLookups int
+	}{
+		{
+			name:        "cached",
+			change:      func(t *testing.T, service RoleService) {},
+			want:        false,
+			wantLookups: 1,
+		},
+		{
+			name: "role assigned",
+			change: func(t *testing.T, service RoleService) {
+				if err := service.AssignRole(ctx, "ada", RoleOrganizer); err != nil {
+					t.Fatalf("AssignRole: %v", err)
+				}
+			},
+			want:        true,
+			wantLookups: 2,
+		},
+		{
+			name: "roles replaced",
+			change: func(t *testing.T, service RoleService) {
+				if err := service.SetUserRoles(ctx, "ada", []string{RoleOrganizer, RoleUser, RoleOrganizer}); err != nil {
+					t.Fatalf("SetUserRoles: %v", err)
+				}
+			},
+			want:        true,
+			wantLookups: 2,
+		},
+		{
+			name: "role updated",
+			change: func(t *testing.T, service RoleService) {
+				if _, err := service.UpdateRole(ctx, RoleUser, "", append(DefaultRolePermissions[RoleUser], PermissionCreateEvents)); err != nil {
+					t.Fatalf("UpdateRole: %v", err)
+				}
+			},
+			want:        true,
+			wantLookups: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeRoleRepository()
+			repository.userRoles["ada"] = []string{RoleUser}
+			service := NewRoleService(repository, time.Minute)
+
+			if ok, err := service.UserHasPermission(ctx, "ada", PermissionCreateEvents); err != nil || ok {
+				t.Fatalf("UserHasPermission before the change = %v, %v, want false", ok, err)
+			}
+			tt.change(t, service)
+
+			ok, err := service.UserHasPermission(ctx, "ada", PermissionCreateEvents)
+			if err != nil {
+				t.Fatalf("UserHasPermission: %v", err)
+			}
+			if ok != tt.want {
+				t.Errorf("UserHasPermission = %v, want %v", ok, tt.want)
+			}
+			if repository.permissionLookups != tt.wantLookups {
+				t.Errorf("loaded permissions %d times, want %d", repository.permissionLookups, tt.wantLookups)
+			}
+		})
+	}
+}
+
+func TestDeleteRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		roleName string
+		wantErr  error
+	}{
+		{"custom role", "support", nil},
+		{"default role", RoleUser, ErrDefaultRole},
+		{"unknown role", "nobody", ErrRoleNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+			repository := newFakeRoleRepository()
+			repository.roles["support"] = Role{Name: "support", Permissions: []string{PermissionManageAnyBookings}}
+			repository.userRoles["ada"] = []string{"support"}
+			service := NewRoleService(repository, time.Minute)
+
+			// Cache the permissions the role grants.
+			if ok, _ := service.UserHasPermission(ctx, "ada", PermissionManageAnyBookings); !ok {
+				t.Fatal("support role does not grant its permission")
+			}
+
+			if err := service.DeleteRole(ctx, tt.roleName); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteRole = %v, want %v", err, tt.wantErr)
+			}
+
+			ok, _ := service.UserHasPermission(ctx, "ada", PermissionManageAnyBookings)
+			if want := tt.roleName != "support"; ok != want {
+				t.Errorf("UserHasPermission after DeleteRole = %v, want %v", ok, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+)
+
+// JWK is the public part of a signing key as a JSON Web Key (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// N and E are the modulus and exponent of RSA keys.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Curve and X are the curve and public key of Ed25519 keys.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// newJWKS lists the public keys, the signing key first. HMAC secrets are
+// never published.
+func newJWKS(signingKey Key, verificationKeys []Key) JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range append([]Key{signingKey}, verificationKeys...) {
+		if jwk, ok := key.jwk(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
+
+func (k Key) jwk() (JWK, bool) {
+	jwk := JWK{KeyID: k.ID, Use: "sig"}
+	switch public := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.Algorithm = "RS256"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Algorithm = "EdDSA"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+// ServeJWKS handles GET /.well-known/jwks.json with the public keys that
+// tokens may be signed with, so that other services can verify them.
+func (m *Manager) ServeJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	// Verifiers may cache the keys for a while; a new key is published as a
+	// verification key before it is used for signing.
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Write(m.jwks)
+}
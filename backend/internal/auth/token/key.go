@@ -0,0 +1,132 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits is the smallest RSA key accepted for signing or verification.
+const minRSABits = 2048
+
+// Key is a key identified by its key ID: either an HMAC secret, or an RSA or
+// Ed25519 key. An asymmetric key without a private key only verifies tokens.
+type Key struct {
+	ID         string
+	Secret     []byte
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// HMACKey returns a key that signs and verifies with secret using HS256.
+func HMACKey(id string, secret []byte) Key {
+	return Key{ID: id, Secret: secret}
+}
+
+// LoadKeyFile reads a PEM encoded key from path. See ParsePEMKey.
+func LoadKeyFile(id, path string) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+
+	key, err := ParsePEMKey(id, data)
+	if err != nil {
+		return Key{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// ParsePEMKey parses an RSA or Ed25519 key. A private key ("PRIVATE KEY" or
+// "RSA PRIVATE KEY") signs and verifies, a public key ("PUBLIC KEY" or "RSA
+// PUBLIC KEY") only verifies.
+func ParsePEMKey(id string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return Key{}, err
+	}
+
+	key := Key{ID: id}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.PrivateKey, key.PublicKey = k, &k.PublicKey
+	case ed25519.PrivateKey:
+		key.PrivateKey, key.PublicKey = k, k.Public()
+	case *rsa.PublicKey, ed25519.PublicKey:
+		key.PublicKey = k
+	default:
+		return Key{}, fmt.Errorf("unsupported key type %T, want RSA or Ed25519", parsed)
+	}
+
+	if _, err := key.method(); err != nil {
+		return Key{}, err
+	}
+	return key, nil
+}
+
+// method returns the signing method of the key.
+func (k Key) method() (jwt.SigningMethod, error) {
+	if len(k.Secret) > 0 {
+		if k.PublicKey != nil || k.PrivateKey != nil {
+			return nil, errors.New("has both a secret and a key pair")
+		}
+		return jwt.SigningMethodHS256, nil
+	}
+
+	switch public := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key has %d bits, want at least %d", public.N.BitLen(), minRSABits)
+		}
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	case nil:
+		return nil, errors.New("needs a secret or a key")
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, want RSA or Ed25519", public)
+	}
+}
+
+func (k Key) canSign() bool {
+	return len(k.Secret) > 0 || k.PrivateKey != nil
+}
+
+func (k Key) signingKey() interface{} {
+	if len(k.Secret) > 0 {
+		return k.Secret
+	}
+	return k.PrivateKey
+}
+
+func (k Key) verificationKey() interface{} {
+	if len(k.Secret) > 0 {
+		return k.Secret
+	}
+	return k.PublicKey
+}
@@ -0,0 +1,197 @@
+// Package token issues and verifies the signed JWTs used as access tokens and
+// as the intermediate token of a two-factor login.
+//
+// Tokens are signed with the current signing key and carry its ID in the
+// "kid" header. Verification accepts the signing key and any additional
+// verification keys, so a new key can be rolled out while tokens signed with
+// the previous one are still valid. Keys are HMAC secrets (HS256) or RSA
+// (RS256) and Ed25519 (EdDSA) keys; the public halves of the latter are
+// published as a JWK set so other services can verify tokens themselves.
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Purposes keep tokens from being used for something else than what they
+// were issued for.
+const (
+	PurposeAccess = "access"
+	PurposeMFA    = "mfa"
+)
+
+// ScopeMFAEnrollment marks access tokens of accounts that must enroll in
+// two-factor authentication before they can use anything else.
+const ScopeMFAEnrollment = "mfa_enroll"
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the claims of issued tokens. The user ID is the subject.
+type Claims struct {
+	Purpose   string `json:"purpose"`
+	SessionID string `json:"sid,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the user the token was issued to.
+func (c *Claims) UserID() string {
+	return c.Subject
+}
+
+// Options configure a Manager.
+type Options struct {
+	Issuer string
+	// Audience is the audience of access tokens. Tokens for other purposes
+	// get an audience of their own, see PurposeAudience.
+	Audience string
+	// TTL is the lifetime of tokens issued without an explicit one.
+	TTL time.Duration
+	// ClockSkew is how far expiry and not-before times may be off, to
+	// tolerate clock drift between servers.
+	ClockSkew time.Duration
+}
+
+// Manager issues and verifies tokens.
+type Manager struct {
+	Options
+	signingKey Key
+	keys       map[string]Key
+	methods    []string
+	jwks       []byte
+	Now        func() time.Time
+}
+
+// NewManager creates a Manager that signs with signingKey and also accepts
+// tokens signed with any of verificationKeys.
+func NewManager(options Options, signingKey Key, verificationKeys ...Key) (*Manager, error) {
+	if !signingKey.canSign() {
+		return nil, fmt.Errorf("token key %q cannot sign: a public key only verifies", signingKey.ID)
+	}
+
+	keys := make(map[string]Key, len(verificationKeys)+1)
+	var methods []string
+	for _, key := range append([]Key{signingKey}, verificationKeys...) {
+		if key.ID == "" {
+			return nil, errors.New("token keys need an ID")
+		}
+		if _, ok := keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate token key ID %q", key.ID)
+		}
+		method, err := key.method()
+		if err != nil {
+			return nil, fmt.Errorf("token key %q: %w", key.ID, err)
+		}
+		keys[key.ID] = key
+		if !slices.Contains(methods, method.Alg()) {
+			methods = append(methods, method.Alg())
+		}
+	}
+
+	jwks, err := json.Marshal(newJWKS(signingKey, verificationKeys))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Manager{Options: options, signingKey: signingKey, keys: keys, methods: methods, jwks: jwks, Now: time.Now}, nil
+}
+
+// PurposeAudience returns the audience of tokens issued for purpose. Only
+// access tokens have the configured audience, so services that verify access
+// tokens with the published keys reject the intermediate token of a
+// two-factor login, which proves only the password.
+func (m *Manager) PurposeAudience(purpose string) string {
+	if purpose == PurposeAccess {
+		return m.Audience
+	}
+	return m.Audience + "/" + purpose
+}
+
+// Issue signs claims as a token that expires after ttl, or after the default
+// TTL if ttl is 0. Issuer, audience and times are filled in, and the typ
+// header names the purpose. It returns the token and when it expires.
+func (m *Manager) Issue(claims Claims, ttl time.Duration) (string, time.Time, error) {
+	if ttl <= 0 {
+		ttl = m.TTL
+	}
+
+	now := m.Now()
+	expiresAt := now.Add(ttl)
+	claims.Issuer = m.Issuer
+	claims.Audience = jwt.ClaimStrings{m.PurposeAudience(claims.Purpose)}
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+
+	method, _ := m.signingKey.method()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = m.signingKey.ID
+	token.Header["typ"] = tokenType(claims.Purpose)
+
+	signed, err := token.SignedString(m.signingKey.signingKey())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// Verify checks the signature, issuer, audience and validity period of a
+// token issued for purpose and returns its claims. Every failure is reported
+// as ErrInvalidToken wrapping the cause.
+func (m *Manager) Verify(tokenString, purpose string) (*Claims, error) {
+	var claims Claims
+	parsed, err := jwt.ParseWithClaims(tokenString, &claims, m.key,
+		jwt.WithValidMethods(m.methods),
+		jwt.WithIssuer(m.Issuer),
+		jwt.WithAudience(m.PurposeAudience(purpose)),
+		jwt.WithLeeway(m.ClockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(m.Now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if typ, _ := parsed.Header["typ"].(string); typ != tokenType(purpose) {
+		return nil, fmt.Errorf("%w: token type %q, not %q", ErrInvalidToken, typ, tokenType(purpose))
+	}
+	if claims.Purpose != purpose {
+		return nil, fmt.Errorf("%w: issued for %q, not %q", ErrInvalidToken, claims.Purpose, purpose)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+	return &claims, nil
+}
+
+// tokenType is the typ header of tokens issued for purpose. Access tokens use
+// the type of RFC 9068.
+func tokenType(purpose string) string {
+	if purpose == PurposeAccess {
+		return "at+jwt"
+	}
+	return purpose + "+jwt"
+}
+
+// key looks up the verification key named by the token's kid header. The
+// token has to use the algorithm of that key, so that a public key can never
+// be mistaken for an HMAC secret.
+func (m *Manager) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := m.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	method, _ := key.method()
+	if token.Method.Alg() != method.Alg() {
+		return nil, fmt.Errorf("key %q is for %s, not %s", kid, method.Alg(), token.Method.Alg())
+	}
+	return key.verificationKey(), nil
+}
@@ -0,0 +1,406 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testOptions = Options{Issuer: "https://api.example.com", Audience: "event-booking", TTL: time.Minute}
+
+var (
+	rsaKeysOnce sync.Once
+	rsaKeys     [2]*rsa.PrivateKey
+)
+
+// testRSAKey returns one of two RSA keys shared by the tests, since
+// generating them is slow.
+func testRSAKey(t *testing.T, i int) *rsa.PrivateKey {
+	t.Helper()
+	rsaKeysOnce.Do(func() {
+		for i := range rsaKeys {
+			key, err := rsa.GenerateKey(rand.Reader, minRSABits)
+			if err != nil {
+				panic(err)
+			}
+			rsaKeys[i] = key
+		}
+	})
+	return rsaKeys[i]
+}
+
+func rsaKey(t *testing.T, id string, i int) Key {
+	private := testRSAKey(t, i)
+	return Key{ID: id, PrivateKey: private, PublicKey: &private.PublicKey}
+}
+
+func publicOnly(key Key) Key {
+	return Key{ID: key.ID, PublicKey: key.PublicKey}
+}
+
+func newTestManager(t *testing.T, options Options, signingKey Key, verificationKeys ...Key) *Manager {
+	t.Helper()
+	manager, err := NewManager(options, signingKey, verificationKeys...)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return manager
+}
+
+func accessClaims(subject string) Claims {
+	return Claims{Purpose: PurposeAccess, RegisteredClaims: jwt.RegisteredClaims{Subject: subject}}
+}
+
+// sign signs claims with header fields and a key of its own choice, the way
+// an attacker could.
+func sign(t *testing.T, method jwt.SigningMethod, header map[string]interface{}, claims jwt.Claims, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	for name, value := range header {
+		token.Header[name] = value
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signed
+}
+
+func TestVerify(t *testing.T) {
+	current := rsaKey(t, "2024-06", 0)
+	previous := rsaKey(t, "2024-01", 1)
+	verifier := newTestManager(t, testOptions, current, publicOnly(previous))
+
+	// validClaims are the claims Issue would set for an access token.
+	validClaims := func() Claims {
+		now := time.Now()
+		claims := accessClaims("ada")
+		claims.Issuer = testOptions.Issuer
+		claims.Audience = jwt.ClaimStrings{testOptions.Audience}
+		claims.IssuedAt = jwt.NewNumericDate(now)
+		claims.ExpiresAt = jwt.NewNumericDate(now.Add(time.Minute))
+		return claims
+	}
+	publicKeyDER, _ := x509.MarshalPKIXPublicKey(current.PublicKey)
+
+	tests := []struct {
+		name    string
+		token   func(t *testing.T) string
+		purpose string
+		wantErr bool
+	}{
+		{
+			name: "signed with the signing key",
+			token: func(t *testing.T) string {
+				signed, _, _ := verifier.Issue(accessClaims("ada"), 0)
+				return signed
+			},
+			purpose: PurposeAccess,
+		},
+		{
+			name: "signed with the previous key",
+			token: func(t *testing.T) string {
+				signed, _, _ := newTestManager(t, testOptions, previous).Issue(accessClaims("ada"), 0)
+				return signed
+			},
+			purpose: PurposeAccess,
+		},
+		{
+			name: "signed with an unknown key",
+			token: func(t *testing.T) string {
+				signed, _, _ := newTestManager(t, testOptions, HMACKey("other", []byte("secret"))).Issue(accessClaims("ada"), 0)
+				return signed
+			},
+			purpose: PurposeAccess,
+			wantErr: true,
+		},
+		{
+			name: "known key ID with another key",
+			token: func(t *testing.T) string {
+				signed, _, _ := newTestManager(t, testOptions, rsaKey(t, current.ID, 1)).Issue(accessClaims("ada"), 0)
+				return signed
+			},
+			purpose: PurposeAccess,
+			wantErr: true,
+		},
+		{
+			name: "HS256 with the public key as secret",
+			token: func(t *testing.T) string {
+				header := map[string]interface{}{"kid": current.ID, "typ": "at+jwt"}
+				return sign(t, jwt.SigningMethodHS256, header, validClaims(), publicKeyDER)
+			},
+			purpose: PurposeAccess,
+			wantErr: true,
+		},
+		{
+			name: "unsigned",
+			token: func(t *testing.T) string {
+				header := map[string]interface{}{"kid": current.ID, "typ": "at+jwt"}
+				return sign(t, jwt.SigningMethodNone, header, validClaims(), jwt.UnsafeAllowNoneSignatureType)
+			},
+			purpose: PurposeAccess,
+			wantErr: true,
+		},
+		{
+			name: "expired",
+			token: func(t *testing.T) string {
+				issuer := newTestManager(t, testOptions, current)
+				issuer.Now = func() time.Time { return time.Now().Add(-time.Hour) }
+				signed, _, _ := issuer.Issue(accessClaims("ada"), 0)
+				return signed
+			},
+			purpose: PurposeAccess,
+			wantErr: true,
+		},
+		{
+			name: "other issuer",
+			token: func(t *testing.T) string {
+				options := testOptions
+				options.Issuer = "https://evil.example.com"
+				signed, _, _ := newTestManager(t, options, current).Issue(accessClaims("ada"), 0)
+				return signed
+			},
+			purpose: PurposeAccess,
+			wantErr: true,
+		},
+		{
+			name: "other audience",
+			token: func(t *testing.T) string {
+				options := testOptions
+				options.Audience = "other-api"
+				signed, _, _ := newTestManager(t, options, current).Issue(accessClaims("ada"), 0)
+				return signed
+			},
+			purpose: PurposeAccess,
+			wantErr: true,
+		},
+		{
+			name: "two-factor token as access token",
+			token: func(t *testing.T) string {
+				signed, _, _ := verifier.Issue(Claims{Purpose: PurposeMFA, RegisteredClaims: jwt.RegisteredClaims{Subject: "ada"}}, 0)
+				return signed
+			},
+			purpose: PurposeAccess,
+			wantErr: true,
+		},
+		{
+			name: "access token as two-factor token",
+			token: func(t *testing.T) string {
+				signed, _, _ := verifier.Issue(accessClaims("ada"), 0)
+				return signed
+			},
+			purpose: PurposeMFA,
+			wantErr: true,
+		},
+		{
+			name: "two-factor purpose with the access audience",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				claims.Purpose = PurposeMFA
+				header := map[string]interface{}{"kid": current.ID, "typ": "at+jwt"}
+				return sign(t, jwt.SigningMethodRS256, header, claims, current.PrivateKey)
+			},
+			purpose: PurposeAccess,
+			wantErr: true,
+		},
+		{
+			name: "access claims with another type",
+			token: func(t *testing.T) string {
+				header := map[string]interface{}{"kid": current.ID, "typ": "JWT"}
+				return sign(t, jwt.SigningMethodRS256, header, validClaims(), current.PrivateKey)
+			},
+			purpose: PurposeAccess,
+			wantErr: true,
+		},
+		{
+			name: "without subject",
+			token: func(t *testing.T) string {
+				signed, _, _ := verifier.Issue(accessClaims(""), 0)
+				return signed
+			},
+			purpose: PurposeAccess,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(tt.token(t), tt.purpose)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("Verify = %+v, %v, want ErrInvalidToken", claims, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if claims.UserID() != "ada" {
+				t.Errorf("user = %q, want ada", claims.UserID())
+			}
+		})
+	}
+}
+
+func TestIssue(t *testing.T) {
+	manager := newTestManager(t, testOptions, HMACKey("hmac", []byte("secret")))
+	now := time.Unix(1700000000, 0)
+	manager.Now = func() time.Time { return now }
+
+	tests := []struct {
+		name         string
+		purpose      string
+		ttl          time.Duration
+		wantTyp      string
+		wantAudience string
+		wantExpiry   time.Time
+	}{
+		{"access token with the default TTL", PurposeAccess, 0, "at+jwt", "event-booking", now.Add(time.Minute)},
+		{"two-factor token with its own TTL", PurposeMFA, 5 * time.Minute, "mfa+jwt", "event-booking/mfa", now.Add(5 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed, expiresAt, err := manager.Issue(Claims{Purpose: tt.purpose, RegisteredClaims: jwt.RegisteredClaims{Subject: "ada"}}, tt.ttl)
+			if err != nil {
+				t.Fatalf("Issue: %v", err)
+			}
+			if !expiresAt.Equal(tt.wantExpiry) {
+				t.Errorf("expires at %s, want %s", expiresAt, tt.wantExpiry)
+			}
+
+			var claims Claims
+			parsed, _, err := jwt.NewParser().ParseUnverified(signed, &claims)
+			if err != nil {
+				t.Fatalf("ParseUnverified: %v", err)
+			}
+			if parsed.Header["kid"] != "hmac" || parsed.Header["typ"] != tt.wantTyp {
+				t.Errorf("header = %v, want kid hmac and typ %s", parsed.Header, tt.wantTyp)
+			}
+			if len(claims.Audience) != 1 || claims.Audience[0] != tt.wantAudience {
+				t.Errorf("audience = %v, want %s", claims.Audience, tt.wantAudience)
+			}
+			if claims.Issuer != testOptions.Issuer {
+				t.Errorf("issuer = %q, want %q", claims.Issuer, testOptions.Issuer)
+			}
+		})
+	}
+}
+
+func TestNewManager(t *testing.T) {
+	signing := rsaKey(t, "current", 0)
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		signingKey       Key
+		verificationKeys []Key
+		wantErr          bool
+	}{
+		{name: "HMAC secret", signingKey: HMACKey("hmac", []byte("secret"))},
+		{name: "RSA with a previous key", signingKey: signing, verificationKeys: []Key{publicOnly(rsaKey(t, "previous", 1))}},
+		{name: "public key cannot sign", signingKey: publicOnly(signing), wantErr: true},
+		{name: "missing key ID", signingKey: HMACKey("", []byte("secret")), wantErr: true},
+		{name: "duplicate key ID", signingKey: signing, verificationKeys: []Key{publicOnly(rsaKey(t, "current", 1))}, wantErr: true},
+		{name: "RSA key too small", signingKey: Key{ID: "small", PrivateKey: small, PublicKey: &small.PublicKey}, wantErr: true},
+		{name: "secret and key pair", signingKey: Key{ID: "both", Secret: []byte("secret"), PrivateKey: signing.PrivateKey, PublicKey: signing.PublicKey}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewManager(testOptions, tt.signingKey, tt.verificationKeys...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewManager error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServeJWKS(t *testing.T) {
+	current := rsaKey(t, "rsa", 0)
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	manager := newTestManager(t, testOptions, current,
+		HMACKey("hmac", []byte("secret")),
+		Key{ID: "ed25519", PublicKey: edPublic},
+	)
+
+	w := httptest.NewRecorder()
+	manager.ServeJWKS(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	if got := w.Header().Get("Content-Type"); got != "application/jwk-set+json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	var jwks JWKS
+	if err := json.Unmarshal(w.Body.Bytes(), &jwks); err != nil {
+		t.Fatalf("decode JWKS: %v", err)
+	}
+
+	// The signing key comes first and the HMAC secret is left out.
+	if len(jwks.Keys) != 2 || jwks.Keys[0].KeyID != "rsa" || jwks.Keys[1].KeyID != "ed25519" {
+		t.Fatalf("keys = %+v, want rsa and ed25519", jwks.Keys)
+	}
+
+	rsaJWK := jwks.Keys[0]
+	n, _ := base64.RawURLEncoding.DecodeString(rsaJWK.N)
+	e, _ := base64.RawURLEncoding.DecodeString(rsaJWK.E)
+	published := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	if rsaJWK.KeyType != "RSA" || rsaJWK.Algorithm != "RS256" || !published.Equal(current.PublicKey) {
+		t.Errorf("RSA key = %+v, want the public key of the signing key", rsaJWK)
+	}
+
+	edJWK := jwks.Keys[1]
+	x, _ := base64.RawURLEncoding.DecodeString(edJWK.X)
+	if edJWK.KeyType != "OKP" || edJWK.Curve != "Ed25519" || edJWK.Algorithm != "EdDSA" || !edPublic.Equal(ed25519.PublicKey(x)) {
+		t.Errorf("Ed25519 key = %+v, want the verification key", edJWK)
+	}
+}
+
+func TestParsePEMKey(t *testing.T) {
+	private := testRSAKey(t, 0)
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(private)
+	pkix, _ := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	edPKCS8, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+
+	tests := []struct {
+		name     string
+		pem      []byte
+		wantSign bool
+		wantErr  bool
+	}{
+		{name: "PKCS #8 RSA private key", pem: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), wantSign: true},
+		{name: "PKCS #1 RSA private key", pem: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)}), wantSign: true},
+		{name: "PKCS #8 Ed25519 private key", pem: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edPKCS8}), wantSign: true},
+		{name: "public key", pem: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix})},
+		{name: "PKCS #1 public key", pem: pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&private.PublicKey)})},
+		{name: "certificate", pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: pkix}), wantErr: true},
+		{name: "not PEM", pem: []byte("secret"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePEMKey("key", tt.pem)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePEMKey error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && key.canSign() != tt.wantSign {
+				t.Errorf("can sign = %v, want %v", key.canSign(), tt.wantSign)
+			}
+		})
+	}
+}
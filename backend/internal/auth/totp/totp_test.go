@@ -0,0 +1,116 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// The RFC lists 8 digit codes; 6 digit codes are their last 6 digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("Code(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code := func(at time.Time) string {
+		c, err := Code(rfcSecret, at)
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current period", rfcSecret, code(now), Step(now), true},
+		{"previous period", rfcSecret, code(now.Add(-Period)), Step(now) - 1, true},
+		{"next period", rfcSecret, code(now.Add(Period)), Step(now) + 1, true},
+		{"two periods ago", rfcSecret, code(now.Add(-2 * Period)), 0, false},
+		{"lowercase secret with spaces", " " + strings.ToLower(rfcSecret) + " ", code(now), Step(now), true},
+		{"wrong code", rfcSecret, "000000", 0, false},
+		{"too short", rfcSecret, code(now)[:5], 0, false},
+		{"too long", rfcSecret, code(now) + "0", 0, false},
+		{"invalid secret", "not base32!", code(now), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(tt.secret, tt.code, now)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	first, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	second, _ := GenerateSecret()
+	if first == second {
+		t.Error("GenerateSecret returned the same secret twice")
+	}
+
+	key, err := decodeSecret(first)
+	if err != nil || len(key) != 20 {
+		t.Errorf("secret decodes to %d bytes, %v, want 20 bytes", len(key), err)
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Event Booking", "ada@example.com", rfcSecret)
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("parse %s: %v", uri, err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("URI = %s, want otpauth://totp/...", uri)
+	}
+	if parsed.Path != "/Event Booking:ada@example.com" {
+		t.Errorf("label = %q, want issuer:account", parsed.Path)
+	}
+
+	query := parsed.Query()
+	for key, want := range map[string]string{
+		"secret":    rfcSecret,
+		"issuer":    "Event Booking",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
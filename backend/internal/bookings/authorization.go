@@ -0,0 +1,14 @@
+package bookings
+
+// Actor is the authenticated user a booking operation is performed for.
+type Actor struct {
+	UserID string
+	// ManageAny is set when the user may act on other users' bookings.
+	ManageAny bool
+}
+
+// CanAccess reports whether the actor may read or change the bookings of
+// the given user.
+func (a Actor) CanAccess(userID string) bool {
+	return a.ManageAny || a.UserID == userID
+}
@@ -2,83 +2,117 @@ package bookings
 
 import (
 	"encoding/json"
+	"eventBookingSystem/internal/apperr"
+	"eventBookingSystem/internal/auth/roles"
+	"eventBookingSystem/internal/events"
 	"eventBookingSystem/internal/middleware"
-	"fmt"
+	"eventBookingSystem/internal/request"
 	"net/http"
-	"strings"
 
 	"github.com/google/uuid"
 )
 
 type BookingHandler struct {
-	BookingService BookingService
+	BookingService    BookingService
+	PermissionChecker middleware.PermissionChecker
 }
 
-func NewBookingHandler(bookingService BookingService) *BookingHandler {
-	return &BookingHandler{BookingService: bookingService}
+func NewBookingHandler(bookingService BookingService, permissionChecker middleware.PermissionChecker) *BookingHandler {
+	return &BookingHandler{BookingService: bookingService, PermissionChecker: permissionChecker}
 }
 
-func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+// actor returns the caller of the request, including whether they may act on
+// other users' bookings.
+func (h *BookingHandler) actor(r *http.Request) (Actor, error) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
 
-	var req struct {
-		EventID string `json:"eventID"`
-		Seats   int    `json:"seats"`
+	manageAny, err := h.PermissionChecker.UserHasPermission(r.Context(), userID, roles.PermissionManageAnyBookings)
+	if err != nil {
+		return Actor{}, err
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	return Actor{UserID: userID, ManageAny: manageAny}, nil
+}
+
+func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
+	var req BookingRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	// Input validation
-	if _, err := uuid.Parse(req.EventID); err != nil {
-		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+	// Get the user ID from the request context
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	booking, err := h.BookingService.CreateBooking(r.Context(), userID, req.EventID, req.Seats)
+	if err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	if req.Seats <= 0 {
-		http.Error(w, "Seats must be a positive integer", http.StatusBadRequest)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(booking)
+}
+
+func (h *BookingHandler) HoldSeats(w http.ResponseWriter, r *http.Request) {
+	var req BookingRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	// Get the user ID from the request context
 	userID := r.Context().Value(middleware.UserIDKey).(string)
 
-	booking, err := h.BookingService.CreateBooking(userID, req.EventID, req.Seats)
+	booking, err := h.BookingService.HoldSeats(r.Context(), userID, req.EventID, req.Seats)
 	if err != nil {
-		http.Error(w, "Failed to create booking", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(booking)
 }
 
-func (h *BookingHandler) GetBookingByID(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) != 4 {
-		http.Error(w, "Invalid URL", http.StatusBadRequest)
+func (h *BookingHandler) ConfirmHold(w http.ResponseWriter, r *http.Request) {
+	bookingID := r.PathValue("id")
+	if _, err := uuid.Parse(bookingID); err != nil {
+		apperr.Write(w, r, apperr.BadRequest("Invalid booking ID"))
+		return
+	}
+
+	actor, err := h.actor(r)
+	if err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	bookingID := parts[3]
-	if bookingID == "" {
-		http.Error(w, "Booking ID is required", http.StatusBadRequest)
+	booking, err := h.BookingService.ConfirmHold(r.Context(), bookingID, actor)
+	if err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(booking)
+}
+
+func (h *BookingHandler) GetBookingByID(w http.ResponseWriter, r *http.Request) {
+	bookingID := r.PathValue("id")
 	if _, err := uuid.Parse(bookingID); err != nil {
-		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+		apperr.Write(w, r, apperr.BadRequest("Invalid booking ID"))
 		return
 	}
 
-	booking, err := h.BookingService.GetBookingByID(bookingID)
+	actor, err := h.actor(r)
 	if err != nil {
-		http.Error(w, "Booking not found", http.StatusNotFound)
+		apperr.Write(w, r, err)
+		return
+	}
+
+	booking, err := h.BookingService.GetBookingByID(r.Context(), bookingID, actor)
+	if err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -86,19 +120,26 @@ func (h *BookingHandler) GetBookingByID(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(booking)
 }
 
+// GetBookingsByUserID lists the bookings of the user in the path. Only the
+// caller's own bookings can be listed unless they may manage any booking.
 func (h *BookingHandler) GetBookingsByUserID(w http.ResponseWriter, r *http.Request) {
-	// Get the user ID from the request context
-	userID := r.Context().Value(middleware.UserIDKey).(string)
+	userID := r.PathValue("userID")
 
 	// Input validation
 	if _, err := uuid.Parse(userID); err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		apperr.Write(w, r, apperr.BadRequest("Invalid user ID"))
+		return
+	}
+
+	actor, err := h.actor(r)
+	if err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	bookings, err := h.BookingService.GetBookingsByUserID(userID)
+	bookings, err := h.BookingService.GetBookingsByUserID(r.Context(), userID, actor)
 	if err != nil {
-		http.Error(w, "Failed to get bookings", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -107,60 +148,62 @@ func (h *BookingHandler) GetBookingsByUserID(w http.ResponseWriter, r *http.Requ
 }
 
 func (h *BookingHandler) CancelBooking(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) != 4 {
-		http.Error(w, "Invalid URL", http.StatusBadRequest)
-		return
-	}
-
-	bookingID := parts[3]
-	if bookingID == "" {
-		http.Error(w, "Booking ID is required", http.StatusBadRequest)
+	bookingID := r.PathValue("id")
+	if _, err := uuid.Parse(bookingID); err != nil {
+		apperr.Write(w, r, apperr.BadRequest("Invalid booking ID"))
 		return
 	}
 
-	if _, err := uuid.Parse(bookingID); err != nil {
-		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+	actor, err := h.actor(r)
+	if err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	err := h.BookingService.CancelBooking(bookingID)
+	err = h.BookingService.CancelBooking(r.Context(), bookingID, actor)
 	if err != nil {
-		http.Error(w, "Failed to cancel booking", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *BookingHandler) HandleBookings(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodPost:
-		h.CreateBooking(w, r)
-	case http.MethodGet:
-		parts := strings.Split(r.URL.Path, "/")
-		fmt.Println(parts)
-		if len(parts) > 3 {
-			if parts[2] == "users" {
-				h.GetBookingsByUserID(w, r)
-				return
-			}
-			bookingID := parts[3]
-			if bookingID != "" {
-				h.GetBookingByID(w, r)
-				return
-			}
-		}
-		http.Error(w, "Invalid URL", http.StatusBadRequest)
-		return
-	case http.MethodDelete:
-		h.CancelBooking(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
+// GetEventBookings lists all bookings of an event the caller organizes.
+func (h *BookingHandler) GetEventBookings(w http.ResponseWriter, r *http.Request) {
+	h.organizerView(w, r, func(eventID string, actor events.Actor) (interface{}, error) {
+		return h.BookingService.GetEventBookings(r.Context(), eventID, actor)
+	})
 }
 
-// func isValidUUID(u string) bool {
-// 	_, err := uuid.Parse(u)
-// 	return err == nil
-// }
+// GetEventAttendees lists the users with confirmed seats for an event the
+// caller organizes.
+func (h *BookingHandler) GetEventAttendees(w http.ResponseWriter, r *http.Request) {
+	h.organizerView(w, r, func(eventID string, actor events.Actor) (interface{}, error) {
+		return h.BookingService.GetEventAttendees(r.Context(), eventID, actor)
+	})
+}
+
+func (h *BookingHandler) organizerView(w http.ResponseWriter, r *http.Request, view func(eventID string, actor events.Actor) (interface{}, error)) {
+	eventID := r.PathValue("id")
+	if _, err := uuid.Parse(eventID); err != nil {
+		apperr.Write(w, r, apperr.BadRequest("Invalid event ID"))
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	manageAny, err := h.PermissionChecker.UserHasPermission(r.Context(), userID, roles.PermissionManageAnyEvents)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	result, err := view(eventID, events.Actor{UserID: userID, ManageAny: manageAny})
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,204 @@
+package bookings
+
+import (
+	"context"
+	"errors"
+	"eventBookingSystem/internal/auth/roles"
+	"eventBookingSystem/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testEventID   = "0b9f5d3e-7c1a-4f2b-9a6e-2d8c4b1e5f70"
+	testBookingID = "5e2a7c90-3b4d-4e8f-a1c6-9d0b2f7e4a13"
+	testUserID    = "8c3f1e2d-6a5b-4c7d-9e0f-1a2b3c4d5e6f"
+)
+
+// fakePermissions grants each user the permissions it lists.
+type fakePermissions map[string][]string
+
+func (p fakePermissions) UserHasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	return slices.Contains(p[userID], permission), nil
+}
+
+var (
+	memberPermissions = []string{roles.PermissionCreateBookings, roles.PermissionReadBookings, roles.PermissionCancelBookings}
+	testPermissions   = fakePermissions{
+		"ada":      memberPermissions,
+		"grace":    memberPermissions,
+		testUserID: memberPermissions,
+		"admin":    append(slices.Clone(memberPermissions), roles.PermissionManageAnyBookings),
+		"reader":   {roles.PermissionReadBookings},
+	}
+)
+
+// newTestMux registers the booking routes with their permissions the way
+// cmd/server does, minus authentication.
+func newTestMux(handler *BookingHandler) *http.ServeMux {
+	protect := func(permission string, h http.HandlerFunc) http.Handler {
+		return middleware.RequirePermission(handler.PermissionChecker, permission)(h)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/bookings", protect(roles.PermissionCreateBookings, handler.CreateBooking))
+	mux.Handle("POST /api/bookings/holds", protect(roles.PermissionCreateBookings, handler.HoldSeats))
+	mux.Handle("POST /api/bookings/{id}/confirm", protect(roles.PermissionCreateBookings, handler.ConfirmHold))
+	mux.Handle("GET /api/bookings/{id}", protect(roles.PermissionReadBookings, handler.GetBookingByID))
+	mux.Handle("GET /api/bookings/users/{userID}", protect(roles.PermissionReadBookings, handler.GetBookingsByUserID))
+	mux.Handle("DELETE /api/bookings/{id}", protect(roles.PermissionCancelBookings, handler.CancelBooking))
+	return mux
+}
+
+// serve runs the request as userID through the booking routes.
+func serve(handler *BookingHandler, r *http.Request, userID string) *httptest.ResponseRecorder {
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, userID))
+	w := httptest.NewRecorder()
+	newTestMux(handler).ServeHTTP(w, r)
+	return w
+}
+
+func TestCreateBookingHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		createErr  error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "created",
+			body:       `{"eventID": "` + testEventID + `", "seats": 2}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "sold out",
+			body:       `{"eventID": "` + testEventID + `", "seats": 2}`,
+			createErr:  &InsufficientSeatsError{EventID: testEventID, Requested: 2},
+			wantStatus: http.StatusConflict,
+			wantBody:   "event " + testEventID + " is sold out",
+		},
+		{
+			name:       "not enough seats left",
+			body:       `{"eventID": "` + testEventID + `", "seats": 2}`,
+			createErr:  &InsufficientSeatsError{EventID: testEventID, Requested: 2, Available: 1},
+			wantStatus: http.StatusConflict,
+			wantBody:   "has only 1 seats available, 2 requested",
+		},
+		{
+			name:       "unknown event",
+			body:       `{"eventID": "` + testEventID + `", "seats": 2}`,
+			createErr:  ErrEventNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "database failure",
+			body:       `{"eventID": "` + testEventID + `", "seats": 2}`,
+			createErr:  errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "no seats",
+			body:       `{"eventID": "` + testEventID + `", "seats": 0}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeBookingRepository()
+			repository.createErr = tt.createErr
+			handler := NewBookingHandler(NewBookingService(repository, nil, time.Minute), testPermissions)
+
+			w := serve(handler, httptest.NewRequest(http.MethodPost, "/api/bookings", strings.NewReader(tt.body)), "ada")
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want it to contain %q", w.Body, tt.wantBody)
+			}
+			if created := len(repository.bookings) == 1; created != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("created = %v with status %d", created, w.Code)
+			}
+		})
+	}
+}
+
+func TestBookingOwnership(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		userID     string
+		wantStatus int
+	}{
+		{"owner reads", http.MethodGet, "/api/bookings/" + testBookingID, "ada", http.StatusOK},
+		{"other user reads", http.MethodGet, "/api/bookings/" + testBookingID, "grace", http.StatusNotFound},
+		{"manager reads", http.MethodGet, "/api/bookings/" + testBookingID, "admin", http.StatusOK},
+		{"owner lists", http.MethodGet, "/api/bookings/users/" + testUserID, testUserID, http.StatusOK},
+		{"other user lists", http.MethodGet, "/api/bookings/users/" + testUserID, "grace", http.StatusNotFound},
+		{"manager lists", http.MethodGet, "/api/bookings/users/" + testUserID, "admin", http.StatusOK},
+		{"owner cancels", http.MethodDelete, "/api/bookings/" + testBookingID, "ada", http.StatusNoContent},
+		{"other user cancels", http.MethodDelete, "/api/bookings/" + testBookingID, "grace", http.StatusNotFound},
+		{"manager cancels", http.MethodDelete, "/api/bookings/" + testBookingID, "admin", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeBookingRepository(
+				Booking{ID: testBookingID, UserID: "ada", EventID: testEventID, Seats: 1, Status: StatusBooked},
+				Booking{ID: "other", UserID: testUserID, EventID: testEventID, Seats: 1, Status: StatusBooked},
+			)
+			handler := NewBookingHandler(NewBookingService(repository, nil, time.Minute), testPermissions)
+
+			w := serve(handler, httptest.NewRequest(tt.method, tt.path, nil), tt.userID)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			cancelled := repository.bookings[testBookingID].Status == StatusCancelled
+			if want := tt.method == http.MethodDelete && tt.wantStatus == http.StatusNoContent; cancelled != want {
+				t.Errorf("cancelled = %v, want %v", cancelled, want)
+			}
+		})
+	}
+}
+
+func TestBookingRoutes(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		userID     string
+		wantStatus int
+	}{
+		{"read", http.MethodGet, "/api/bookings/" + testBookingID, "ada", http.StatusOK},
+		{"confirm of a booking", http.MethodPost, "/api/bookings/" + testBookingID + "/confirm", "ada", http.StatusConflict},
+		{"invalid booking ID", http.MethodGet, "/api/bookings/42", "ada", http.StatusBadRequest},
+		{"create without permission", http.MethodPost, "/api/bookings", "reader", http.StatusForbidden},
+		{"hold without permission", http.MethodPost, "/api/bookings/holds", "reader", http.StatusForbidden},
+		{"cancel without permission", http.MethodDelete, "/api/bookings/" + testBookingID, "reader", http.StatusForbidden},
+		{"unknown user", http.MethodGet, "/api/bookings/" + testBookingID, "alan", http.StatusForbidden},
+		{"wrong method", http.MethodPut, "/api/bookings/" + testBookingID, "ada", http.StatusMethodNotAllowed},
+		{"wrong method on collection", http.MethodGet, "/api/bookings", "ada", http.StatusMethodNotAllowed},
+		{"confirm by GET", http.MethodGet, "/api/bookings/" + testBookingID + "/confirm", "ada", http.StatusMethodNotAllowed},
+		{"unknown path", http.MethodGet, "/api/bookings/" + testBookingID + "/seats", "ada", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeBookingRepository(Booking{ID: testBookingID, UserID: "ada", EventID: testEventID, Seats: 1, Status: StatusBooked})
+			handler := NewBookingHandler(NewBookingService(repository, nil, time.Minute), testPermissions)
+
+			w := serve(handler, httptest.NewRequest(tt.method, tt.path, nil), tt.userID)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if status := repository.bookings[testBookingID].Status; status != StatusBooked {
+				t.Errorf("status = %s, want the booking unchanged", status)
+			}
+		})
+	}
+}
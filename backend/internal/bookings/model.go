@@ -1,19 +1,125 @@
 package bookings
 
 import (
+	"eventBookingSystem/internal/apperr"
+	"fmt"
+	"slices"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// Status is the lifecycle state of a booking.
+//
+//	held ──> booked ──> cancelled
+//	  │
+//	  ├────> cancelled
+//	  └────> expired
+type Status string
+
+const (
+	StatusHeld      Status = "held"
+	StatusBooked    Status = "booked"
+	StatusCancelled Status = "cancelled"
+	StatusExpired   Status = "expired"
+)
+
+var transitions = map[Status][]Status{
+	StatusHeld:   {StatusBooked, StatusCancelled, StatusExpired},
+	StatusBooked: {StatusCancelled},
+}
+
+// CanTransitionTo reports whether a booking in state s may move to next.
+func (s Status) CanTransitionTo(next Status) bool {
+	return slices.Contains(transitions[s], next)
+}
+
+// TakesSeats reports whether a booking in state s counts against the event's
+// capacity.
+func (s Status) TakesSeats() bool {
+	return s == StatusHeld || s == StatusBooked
+}
+
+var (
+	ErrEventNotFound   = apperr.NotFound("event not found")
+	ErrBookingNotFound = apperr.NotFound("booking not found")
+	ErrUserNotFound    = apperr.NotFound("user not found")
+	ErrHoldExpired     = apperr.Gone("hold has expired")
+	ErrStaleBooking    = apperr.Conflict("booking was modified concurrently")
+)
+
 type Booking struct {
-	ID        string `gorm:"type:uuid;primaryKey"`
-	UserID    string `gorm:"type:uuid;not null"`
-	EventID   string `gorm:"type:uuid;not null"`
-	Seats     int    `gorm:"not null"`
-	Status    string `gorm:"type:varchar(10);default:'booked'"`
-	Role      string `gorm:"type:varchar(10);default:'user'"`
+	ID        string     `gorm:"type:uuid;primaryKey"`
+	UserID    string     `gorm:"type:uuid;not null"`
+	EventID   string     `gorm:"type:uuid;not null"`
+	Seats     int        `gorm:"not null"`
+	Status    Status     `gorm:"type:varchar(10);default:'booked';index"`
+	ExpiresAt *time.Time `gorm:"index"`
+	Role      string     `gorm:"type:varchar(10);default:'user'"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
+
+// Attendee is a user holding confirmed seats for an event.
+type Attendee struct {
+	UserID   string `json:"userID"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Seats    int    `json:"seats"`
+}
+
+// TransitionTo moves the booking to the next state, rejecting transitions the
+// state machine does not allow.
+func (b *Booking) TransitionTo(next Status) error {
+	if !b.Status.CanTransitionTo(next) {
+		return &InvalidTransitionError{From: b.Status, To: next}
+	}
+	b.Status = next
+	if next != StatusHeld {
+		b.ExpiresAt = nil
+	}
+	return nil
+}
+
+// InsufficientSeatsError is returned when a booking asks for more seats than
+// the event has left. Waitlisted are the seats held back for the head of the
+// event's waitlist; they are not included in Available.
+type InsufficientSeatsError struct {
+	EventID    string
+	Requested  int
+	Available  int
+	Waitlisted int
+}
+
+func (e *InsufficientSeatsError) Error() string {
+	if e.Waitlisted > 0 {
+		if e.Available <= 0 {
+			return fmt.Sprintf("event %s has a waitlist, join it instead", e.EventID)
+		}
+		return fmt.Sprintf("event %s has only %d seats available besides its waitlist, %d requested", e.EventID, e.Available, e.Requested)
+	}
+	if e.Available <= 0 {
+		return fmt.Sprintf("event %s is sold out", e.EventID)
+	}
+	return fmt.Sprintf("event %s has only %d seats available, %d requested", e.EventID, e.Available, e.Requested)
+}
+
+func (e *InsufficientSeatsError) ErrorCode() apperr.Code {
+	return apperr.CodeConflict
+}
+
+// InvalidTransitionError is returned when a booking cannot move from its
+// current state to the requested one.
+type InvalidTransitionError struct {
+	From Status
+	To   Status
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("cannot change booking from %s to %s", e.From, e.To)
+}
+
+func (e *InvalidTransitionError) ErrorCode() apperr.Code {
+	return apperr.CodeConflict
+}
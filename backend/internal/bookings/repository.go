@@ -1,16 +1,27 @@
 package bookings
 
 import (
+	"context"
+	"errors"
+	"eventBookingSystem/internal/events"
+	"time"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BookingRepository interface {
-	Create(booking *Booking) error
-	GetByID(id string) (*Booking, error)
-	GetByUserID(userID string) ([]Booking, error)
-	GetByEventID(eventID string) ([]Booking, error)
-	Update(booking *Booking) error
-	Delete(id string) error
+	Create(ctx context.Context, booking *Booking) error
+	CreateWithinCapacity(ctx context.Context, booking *Booking) error
+	GetByID(ctx context.Context, id string) (*Booking, error)
+	GetByUserID(ctx context.Context, userID string) ([]Booking, error)
+	GetByEventID(ctx context.Context, eventID string) ([]Booking, error)
+	GetEvent(ctx context.Context, eventID string) (*events.Event, error)
+	GetAttendees(ctx context.Context, eventID string) ([]Attendee, error)
+	Update(ctx context.Context, booking *Booking) error
+	UpdateStatus(ctx context.Context, booking *Booking, from Status) error
+	ExpireHolds(ctx context.Context, now time.Time) ([]Booking, error)
+	Delete(ctx context.Context, id string) error
 }
 
 type BookingRepositoryImpl struct {
@@ -21,32 +32,180 @@ func NewBookingRepository(db *gorm.DB) BookingRepository {
 	return &BookingRepositoryImpl{DB: db}
 }
 
-func (r *BookingRepositoryImpl) Create(booking *Booking) error {
-	return r.DB.Create(booking).Error
+func (r *BookingRepositoryImpl) Create(ctx context.Context, booking *Booking) error {
+	return r.DB.WithContext(ctx).Create(booking).Error
+}
+
+// CreateWithinCapacity inserts the booking only if the event still has enough
+// free seats. While users are waiting for the event, the seats the head of the
+// waitlist asked for are held back, so direct bookings cannot jump the queue.
+// The event row is locked for the duration of the transaction so concurrent
+// bookings for the same event are serialized.
+func (r *BookingRepositoryImpl) CreateWithinCapacity(ctx context.Context, booking *Booking) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		event, err := LockEvent(tx, booking.EventID)
+		if err != nil {
+			return err
+		}
+
+		available, err := AvailableSeats(tx, event)
+		if err != nil {
+			return err
+		}
+
+		waitlisted, err := waitlistHeadSeats(tx, event.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkCapacity(booking, available, waitlisted); err != nil {
+			return err
+		}
+
+		return tx.Create(booking).Error
+	})
 }
 
-func (r *BookingRepositoryImpl) GetByID(id string) (*Booking, error) {
+func (r *BookingRepositoryImpl) GetByID(ctx context.Context, id string) (*Booking, error) {
 	var booking Booking
-	err := r.DB.First(&booking, "id = ?", id).Error
+	err := r.DB.WithContext(ctx).First(&booking, "id = ?", id).Error
 	return &booking, err
 }
 
-func (r *BookingRepositoryImpl) GetByUserID(userID string) ([]Booking, error) {
+func (r *BookingRepositoryImpl) GetByUserID(ctx context.Context, userID string) ([]Booking, error) {
 	var bookings []Booking
-	err := r.DB.Where("user_id = ?", userID).Find(&bookings).Error
+	err := r.DB.WithContext(ctx).Where("user_id = ?", userID).Find(&bookings).Error
 	return bookings, err
 }
 
-func (r *BookingRepositoryImpl) GetByEventID(eventID string) ([]Booking, error) {
+func (r *BookingRepositoryImpl) GetByEventID(ctx context.Context, eventID string) ([]Booking, error) {
 	var bookings []Booking
-	err := r.DB.Where("event_id = ?", eventID).Find(&bookings).Error
+	err := r.DB.WithContext(ctx).Where("event_id = ?", eventID).Find(&bookings).Error
 	return bookings, err
 }
 
-func (r *BookingRepositoryImpl) Update(booking *Booking) error {
-	return r.DB.Save(booking).Error
+func (r *BookingRepositoryImpl) GetEvent(ctx context.Context, eventID string) (*events.Event, error) {
+	var event events.Event
+	err := r.DB.WithContext(ctx).First(&event, "id = ?", eventID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEventNotFound
+	}
+	return &event, err
+}
+
+// GetAttendees returns the users with confirmed bookings for the event and
+// how many seats each of them booked in total.
+func (r *BookingRepositoryImpl) GetAttendees(ctx context.Context, eventID string) ([]Attendee, error) {
+	var attendees []Attendee
+	err := r.DB.WithContext(ctx).Model(&Booking{}).
+		Select("users.id AS user_id, users.username, users.email, SUM(bookings.seats) AS seats").
+		Joins("JOIN users ON users.id = bookings.user_id").
+		Where("bookings.event_id = ? AND bookings.status = ?", eventID, StatusBooked).
+		Group("users.id, users.username, users.email").
+		Order("users.username").
+		Scan(&attendees).Error
+	return attendees, err
+}
+
+func (r *BookingRepositoryImpl) Update(ctx context.Context, booking *Booking) error {
+	return r.DB.WithContext(ctx).Save(booking).Error
+}
+
+// UpdateStatus persists the booking's new status only if the stored status is
+// still from, so concurrent transitions of the same booking cannot both win.
+func (r *BookingRepositoryImpl) UpdateStatus(ctx context.Context, booking *Booking, from Status) error {
+	result := r.DB.WithContext(ctx).Model(&Booking{}).
+		Where("id = ? AND status = ?", booking.ID, from).
+		Updates(map[string]interface{}{
+			"status":     booking.Status,
+			"expires_at": booking.ExpiresAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStaleBooking
+	}
+	return nil
+}
+
+// ExpireHolds marks every hold whose expiry has passed as expired and returns
+// the affected bookings. Like TransitionTo, it clears the expiry along with the
+// status. Expiring only frees seats, so it does not take the event lock.
+func (r *BookingRepositoryImpl) ExpireHolds(ctx context.Context, now time.Time) ([]Booking, error) {
+	var expired []Booking
+	err := r.DB.WithContext(ctx).Model(&expired).
+		Clauses(clause.Returning{}).
+		Where("status = ? AND expires_at <= ?", StatusHeld, now).
+		Updates(map[string]interface{}{
+			"status":     StatusExpired,
+			"expires_at": nil,
+		}).Error
+	return expired, err
+}
+
+func (r *BookingRepositoryImpl) Delete(ctx context.Context, id string) error {
+	return r.DB.WithContext(ctx).Delete(&Booking{}, "id = ?", id).Error
+}
+
+// LockEvent loads the event and takes a row lock on it until the surrounding
+// transaction ends. Every code path that takes seats of an event, or reads the
+// free seats to hand them out, must hold this lock; paths that only free seats,
+// such as cancelling or expiring bookings, may skip it.
+func LockEvent(tx *gorm.DB, eventID string) (*events.Event, error) {
+	var event events.Event
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, "id = ?", eventID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// checkCapacity accepts the booking if it leaves enough of the available seats
+// for the waiting request at the head of the waitlist, which asked for
+// waitlisted seats, or 0 if nobody is waiting.
+func checkCapacity(booking *Booking, available, waitlisted int) error {
+	if booking.Seats > available-waitlisted {
+		return &InsufficientSeatsError{
+			EventID:    booking.EventID,
+			Requested:  booking.Seats,
+			Available:  max(available-waitlisted, 0),
+			Waitlisted: waitlisted,
+		}
+	}
+	return nil
+}
+
+// waitlistHeadSeats returns how many seats the first waiting entry of the
+// event's waitlist asked for, or 0 if nobody is waiting. The waitlist package
+// builds on this one, so its table is queried directly.
+func waitlistHeadSeats(tx *gorm.DB, eventID string) (int, error) {
+	var seats []int
+	err := tx.Table("waitlist_entries").
+		Where("event_id = ? AND status = ?", eventID, "waiting").
+		Order("created_at, id").
+		Limit(1).
+		Pluck("seats", &seats).Error
+	if err != nil || len(seats) == 0 {
+		return 0, err
+	}
+	return seats[0], nil
 }
 
-func (r *BookingRepositoryImpl) Delete(id string) error {
-	return r.DB.Delete(&Booking{}, "id = ?", id).Error
+// AvailableSeats returns how many seats of the event are not taken by
+// confirmed bookings or holds. Holds keep their seats until the sweeper has
+// expired them.
+func AvailableSeats(tx *gorm.DB, event *events.Event) (int, error) {
+	var taken int
+	err := tx.Model(&Booking{}).
+		Where("event_id = ? AND status IN ?", event.ID, []Status{StatusHeld, StatusBooked}).
+		Select("COALESCE(SUM(seats), 0)").
+		Scan(&taken).Error
+	if err != nil {
+		return 0, err
+	}
+	return max(event.Capacity-taken, 0), nil
 }
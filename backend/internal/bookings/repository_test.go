@@ -0,0 +1,46 @@
+package bookings
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckCapacity(t *testing.T) {
+	tests := []struct {
+		name       string
+		seats      int
+		available  int
+		waitlisted int
+		wantErr    bool
+		wantLeft   int
+	}{
+		{"fits without waitlist", 3, 5, 0, false, 0},
+		{"exactly fits without waitlist", 5, 5, 0, false, 0},
+		{"too many without waitlist", 6, 5, 0, true, 5},
+		{"sold out", 1, 0, 0, true, 0},
+		{"leaves room for the head of the waitlist", 2, 5, 3, false, 0},
+		{"would take the head's seats", 3, 5, 3, true, 2},
+		{"head cannot be served yet", 1, 2, 4, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			booking := &Booking{EventID: "event", Seats: tt.seats}
+			err := checkCapacity(booking, tt.available, tt.waitlisted)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("checkCapacity = %v, want nil", err)
+				}
+				return
+			}
+
+			var insufficient *InsufficientSeatsError
+			if !errors.As(err, &insufficient) {
+				t.Fatalf("checkCapacity = %v, want InsufficientSeatsError", err)
+			}
+			if insufficient.Available != tt.wantLeft || insufficient.Waitlisted != tt.waitlisted {
+				t.Errorf("available = %d, waitlisted = %d, want %d, %d",
+					insufficient.Available, insufficient.Waitlisted, tt.wantLeft, tt.waitlisted)
+			}
+		})
+	}
+}
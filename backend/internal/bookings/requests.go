@@ -0,0 +1,14 @@
+package bookings
+
+import "eventBookingSystem/internal/request"
+
+// BookingRequest is the body of booking or holding seats.
+type BookingRequest struct {
+	EventID string `json:"eventID"`
+	Seats   int    `json:"seats"`
+}
+
+func (r *BookingRequest) Validate(v *request.Validator) {
+	v.UUID("eventID", r.EventID)
+	v.Positive("seats", r.Seats)
+}
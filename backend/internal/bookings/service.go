@@ -1,55 +1,230 @@
 package bookings
 
 import (
+	"context"
+	"errors"
+	"eventBookingSystem/internal/events"
+	"eventBookingSystem/internal/logging"
+	"eventBookingSystem/internal/metrics"
+	"time"
+
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type BookingService interface {
-	CreateBooking(userID, eventID string, seats int) (*Booking, error)
-	GetBookingByID(id string) (*Booking, error)
-	GetBookingsByUserID(userID string) ([]Booking, error)
-	CancelBooking(id string) error
+	CreateBooking(ctx context.Context, userID, eventID string, seats int) (*Booking, error)
+	HoldSeats(ctx context.Context, userID, eventID string, seats int) (*Booking, error)
+	ConfirmHold(ctx context.Context, id string, actor Actor) (*Booking, error)
+	ExpireHolds(ctx context.Context) (int, error)
+	GetBookingByID(ctx context.Context, id string, actor Actor) (*Booking, error)
+	GetBookingsByUserID(ctx context.Context, userID string, actor Actor) ([]Booking, error)
+	CancelBooking(ctx context.Context, id string, actor Actor) error
+	GetEventBookings(ctx context.Context, eventID string, actor events.Actor) ([]Booking, error)
+	GetEventAttendees(ctx context.Context, eventID string, actor events.Actor) ([]Attendee, error)
+}
+
+// SeatReleaseListener is notified after seats of an event have been freed,
+// e.g. by a cancellation.
+type SeatReleaseListener interface {
+	SeatsReleased(ctx context.Context, eventID string) error
 }
 
 type BookingServiceImpl struct {
-	BookingRepository BookingRepository
+	BookingRepository   BookingRepository
+	SeatReleaseListener SeatReleaseListener
+	HoldTTL             time.Duration
 }
 
-func NewBookingService(bookingRepository BookingRepository) BookingService {
-	return &BookingServiceImpl{BookingRepository: bookingRepository}
+func NewBookingService(bookingRepository BookingRepository, listener SeatReleaseListener, holdTTL time.Duration) BookingService {
+	return &BookingServiceImpl{
+		BookingRepository:   bookingRepository,
+		SeatReleaseListener: listener,
+		HoldTTL:             holdTTL,
+	}
 }
 
-func (s *BookingServiceImpl) CreateBooking(userID, eventID string, seats int) (*Booking, error) {
+func (s *BookingServiceImpl) CreateBooking(ctx context.Context, userID, eventID string, seats int) (*Booking, error) {
 	booking := &Booking{
 		ID:      uuid.New().String(),
 		UserID:  userID,
 		EventID: eventID,
 		Seats:   seats,
-		Status:  "booked",
+		Status:  StatusBooked,
+	}
+
+	err := s.BookingRepository.CreateWithinCapacity(ctx, booking)
+	if err != nil {
+		return nil, err
 	}
 
-	err := s.BookingRepository.Create(booking)
+	metrics.BookingCreated(string(booking.Status))
+	metrics.SeatsSold(booking.EventID, booking.Seats)
+	return booking, nil
+}
+
+// HoldSeats reserves seats for HoldTTL. The hold takes capacity like a
+// booking until it is confirmed, cancelled or expired.
+func (s *BookingServiceImpl) HoldSeats(ctx context.Context, userID, eventID string, seats int) (*Booking, error) {
+	expiresAt := time.Now().Add(s.HoldTTL)
+	booking := &Booking{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		EventID:   eventID,
+		Seats:     seats,
+		Status:    StatusHeld,
+		ExpiresAt: &expiresAt,
+	}
+
+	err := s.BookingRepository.CreateWithinCapacity(ctx, booking)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.BookingCreated(string(booking.Status))
+	return booking, nil
+}
+
+func (s *BookingServiceImpl) ConfirmHold(ctx context.Context, id string, actor Actor) (*Booking, error) {
+	booking, err := s.accessibleBooking(ctx, id, actor)
 	if err != nil {
 		return nil, err
 	}
 
+	if booking.Status == StatusHeld && booking.ExpiresAt != nil && !booking.ExpiresAt.After(time.Now()) {
+		if err := s.transition(ctx, booking, StatusExpired); err != nil && !errors.Is(err, ErrStaleBooking) {
+			return nil, err
+		}
+		return nil, ErrHoldExpired
+	}
+
+	if err := s.transition(ctx, booking, StatusBooked); err != nil {
+		return nil, err
+	}
+	metrics.SeatsSold(booking.EventID, booking.Seats)
+
 	return booking, nil
 }
 
-func (s *BookingServiceImpl) GetBookingByID(id string) (*Booking, error) {
-	return s.BookingRepository.GetByID(id)
+// ExpireHolds expires every hold past its deadline and hands the freed seats
+// to the SeatReleaseListener. It returns the number of expired holds.
+func (s *BookingServiceImpl) ExpireHolds(ctx context.Context) (int, error) {
+	expired, err := s.BookingRepository.ExpireHolds(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	released := make(map[string]bool)
+	for _, booking := range expired {
+		if !released[booking.EventID] {
+			released[booking.EventID] = true
+			s.releaseSeats(ctx, booking.EventID)
+		}
+	}
+
+	return len(expired), nil
 }
 
-func (s *BookingServiceImpl) GetBookingsByUserID(userID string) ([]Booking, error) {
-	return s.BookingRepository.GetByUserID(userID)
+func (s *BookingServiceImpl) GetBookingByID(ctx context.Context, id string, actor Actor) (*Booking, error) {
+	return s.accessibleBooking(ctx, id, actor)
+}
+
+func (s *BookingServiceImpl) GetBookingsByUserID(ctx context.Context, userID string, actor Actor) ([]Booking, error) {
+	if !actor.CanAccess(userID) {
+		return nil, ErrUserNotFound
+	}
+	return s.BookingRepository.GetByUserID(ctx, userID)
 }
 
-func (s *BookingServiceImpl) CancelBooking(id string) error {
-	booking, err := s.BookingRepository.GetByID(id)
+func (s *BookingServiceImpl) CancelBooking(ctx context.Context, id string, actor Actor) error {
+	booking, err := s.accessibleBooking(ctx, id, actor)
 	if err != nil {
 		return err
 	}
 
-	booking.Status = "cancelled"
-	return s.BookingRepository.Update(booking)
+	if booking.Status == StatusCancelled {
+		return nil
+	}
+
+	if err := s.transition(ctx, booking, StatusCancelled); err != nil {
+		return err
+	}
+	metrics.BookingCancelled()
+	return nil
+}
+
+// GetEventBookings lists all bookings of an event for its organizer.
+func (s *BookingServiceImpl) GetEventBookings(ctx context.Context, eventID string, actor events.Actor) ([]Booking, error) {
+	if err := s.checkOrganizer(ctx, eventID, actor); err != nil {
+		return nil, err
+	}
+	return s.BookingRepository.GetByEventID(ctx, eventID)
+}
+
+// GetEventAttendees lists the users with confirmed seats for an event for its
+// organizer.
+func (s *BookingServiceImpl) GetEventAttendees(ctx context.Context, eventID string, actor events.Actor) ([]Attendee, error) {
+	if err := s.checkOrganizer(ctx, eventID, actor); err != nil {
+		return nil, err
+	}
+	return s.BookingRepository.GetAttendees(ctx, eventID)
+}
+
+func (s *BookingServiceImpl) checkOrganizer(ctx context.Context, eventID string, actor events.Actor) error {
+	event, err := s.BookingRepository.GetEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+
+	if !actor.CanManage(event) {
+		return events.ErrNotEventOrganizer
+	}
+	return nil
+}
+
+// accessibleBooking loads the booking if the actor may act on it. Bookings of
+// other users are reported as not found so their existence is not leaked.
+func (s *BookingServiceImpl) accessibleBooking(ctx context.Context, id string, actor Actor) (*Booking, error) {
+	booking, err := s.BookingRepository.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBookingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !actor.CanAccess(booking.UserID) {
+		return nil, ErrBookingNotFound
+	}
+	return booking, nil
+}
+
+// transition moves the booking to next and releases its seats when it stops
+// taking capacity.
+func (s *BookingServiceImpl) transition(ctx context.Context, booking *Booking, next Status) error {
+	from := booking.Status
+	if err := booking.TransitionTo(next); err != nil {
+		return err
+	}
+
+	if err := s.BookingRepository.UpdateStatus(ctx, booking, from); err != nil {
+		booking.Status = from
+		return err
+	}
+
+	if from.TakesSeats() && !next.TakesSeats() {
+		s.releaseSeats(ctx, booking.EventID)
+	}
+	return nil
+}
+
+// releaseSeats notifies the listener that seats were freed. The status change
+// has already been committed at this point, so failures are only logged.
+func (s *BookingServiceImpl) releaseSeats(ctx context.Context, eventID string) {
+	if s.SeatReleaseListener == nil {
+		return
+	}
+	if err := s.SeatReleaseListener.SeatsReleased(ctx, eventID); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Failed to process released seats", "event_id", eventID, "error", err)
+	}
 }
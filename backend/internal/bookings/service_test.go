@@ -0,0 +1,373 @@
+package bookings
+
+import (
+	"context"
+	"errors"
+	"eventBookingSystem/internal/events"
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeBookingRepository keeps bookings in memory. Capacity is not checked,
+// CreateWithinCapacity fails with createErr instead.
+type fakeBookingRepository struct {
+	bookings  map[string]Booking
+	events    map[string]events.Event
+	createErr error
+}
+
+func newFakeBookingRepository(bookings ...Booking) *fakeBookingRepository {
+	r := &fakeBookingRepository{bookings: make(map[string]Booking), events: make(map[string]events.Event)}
+	for _, booking := range bookings {
+		r.bookings[booking.ID] = booking
+	}
+	return r
+}
+
+func (r *fakeBookingRepository) Create(ctx context.Context, booking *Booking) error {
+	r.bookings[booking.ID] = *booking
+	return nil
+}
+
+func (r *fakeBookingRepository) CreateWithinCapacity(ctx context.Context, booking *Booking) error {
+	if r.createErr != nil {
+		return r.createErr
+	}
+	return r.Create(ctx, booking)
+}
+
+func (r *fakeBookingRepository) GetByID(ctx context.Context, id string) (*Booking, error) {
+	booking, ok := r.bookings[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &booking, nil
+}
+
+func (r *fakeBookingRepository) GetByUserID(ctx context.Context, userID string) ([]Booking, error) {
+	var bookings []Booking
+	for _, booking := range r.bookings {
+		if booking.UserID == userID {
+			bookings = append(bookings, booking)
+		}
+	}
+	return bookings, nil
+}
+
+func (r *fakeBookingRepository) GetByEventID(ctx context.Context, eventID string) ([]Booking, error) {
+	var bookings []Booking
+	for _, booking := range r.bookings {
+		if booking.EventID == eventID {
+			bookings = append(bookings, booking)
+		}
+	}
+	return bookings, nil
+}
+
+func (r *fakeBookingRepository) GetEvent(ctx context.Context, eventID string) (*events.Event, error) {
+	event, ok := r.events[eventID]
+	if !ok {
+		return nil, ErrEventNotFound
+	}
+	return &event, nil
+}
+
+func (r *fakeBookingRepository) GetAttendees(ctx context.Context, eventID string) ([]Attendee, error) {
+	var attendees []Attendee
+	for _, booking := range r.bookings {
+		if booking.EventID == eventID && booking.Status == StatusBooked {
+			attendees = append(attendees, Attendee{UserID: booking.UserID, Seats: booking.Seats})
+		}
+	}
+	return attendees, nil
+}
+
+func (r *fakeBookingRepository) Update(ctx context.Context, booking *Booking) error {
+	r.bookings[booking.ID] = *booking
+	return nil
+}
+
+func (r *fakeBookingRepository) UpdateStatus(ctx context.Context, booking *Booking, from Status) error {
+	stored, ok := r.bookings[booking.ID]
+	if !ok || stored.Status != from {
+		return ErrStaleBooking
+	}
+	r.bookings[booking.ID] = *booking
+	return nil
+}
+
+func (r *fakeBookingRepository) ExpireHolds(ctx context.Context, now time.Time) ([]Booking, error) {
+	var expired []Booking
+	for id, booking := range r.bookings {
+		if booking.Status == StatusHeld && booking.ExpiresAt != nil && !booking.ExpiresAt.After(now) {
+			booking.Status = StatusExpired
+			booking.ExpiresAt = nil
+			r.bookings[id] = booking
+			expired = append(expired, booking)
+		}
+	}
+	return expired, nil
+}
+
+func (r *fakeBookingRepository) Delete(ctx context.Context, id string) error {
+	delete(r.bookings, id)
+	return nil
+}
+
+// recordingListener records the events seats were released for.
+type recordingListener struct {
+	released []string
+}
+
+func (l *recordingListener) SeatsReleased(ctx context.Context, eventID string) error {
+	l.released = append(l.released, eventID)
+	return nil
+}
+
+func TestStatusTransitions(t *testing.T) {
+	statuses := []Status{StatusHeld, StatusBooked, StatusCancelled, StatusExpired}
+	allowed := map[Status][]Status{
+		StatusHeld:   {StatusBooked, StatusCancelled, StatusExpired},
+		StatusBooked: {StatusCancelled},
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			booking := &Booking{Status: from, ExpiresAt: &time.Time{}}
+			err := booking.TransitionTo(to)
+
+			if want := slices.Contains(allowed[from], to); (err == nil) != want {
+				t.Errorf("%s -> %s: error = %v, want allowed %v", from, to, err, want)
+				continue
+			}
+			if err != nil {
+				var transitionErr *InvalidTransitionError
+				if !errors.As(err, &transitionErr) || booking.Status != from {
+					t.Errorf("%s -> %s: error = %v and status %s, want InvalidTransitionError and unchanged", from, to, err, booking.Status)
+				}
+				continue
+			}
+			if booking.Status != to || booking.ExpiresAt != nil {
+				t.Errorf("%s -> %s: status %s expiring %v, want %s without expiry", from, to, booking.Status, booking.ExpiresAt, to)
+			}
+		}
+	}
+}
+
+func TestConfirmHold(t *testing.T) {
+	future := time.Now().Add(time.Minute)
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		booking Booking
+		actor   Actor
+		wantErr error
+		// wantInvalid reports whether the hold cannot be confirmed from its status.
+		wantInvalid  bool
+		wantStatus   Status
+		wantReleased []string
+	}{
+		{
+			name:       "active hold",
+			booking:    Booking{ID: "b", UserID: "ada", EventID: "e", Status: StatusHeld, ExpiresAt: &future},
+			actor:      Actor{UserID: "ada"},
+			wantStatus: StatusBooked,
+		},
+		{
+			name:         "expired hold",
+			booking:      Booking{ID: "b", UserID: "ada", EventID: "e", Status: StatusHeld, ExpiresAt: &past},
+			actor:        Actor{UserID: "ada"},
+			wantErr:      ErrHoldExpired,
+			wantStatus:   StatusExpired,
+			wantReleased: []string{"e"},
+		},
+		{
+			name:       "hold of another user",
+			booking:    Booking{ID: "b", UserID: "grace", EventID: "e", Status: StatusHeld, ExpiresAt: &future},
+			actor:      Actor{UserID: "ada"},
+			wantErr:    ErrBookingNotFound,
+			wantStatus: StatusHeld,
+		},
+		{
+			name:        "cancelled booking",
+			booking:     Booking{ID: "b", UserID: "ada", EventID: "e", Status: StatusCancelled},
+			actor:       Actor{UserID: "ada"},
+			wantInvalid: true,
+			wantStatus:  StatusCancelled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeBookingRepository(tt.booking)
+			listener := &recordingListener{}
+			service := NewBookingService(repository, listener, time.Minute)
+
+			_, err := service.ConfirmHold(t.Context(), "b", tt.actor)
+			if tt.wantInvalid {
+				var transitionErr *InvalidTransitionError
+				if !errors.As(err, &transitionErr) {
+					t.Fatalf("ConfirmHold = %v, want InvalidTransitionError", err)
+				}
+			} else if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ConfirmHold = %v, want %v", err, tt.wantErr)
+			}
+
+			if status := repository.bookings["b"].Status; status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", status, tt.wantStatus)
+			}
+			if !slices.Equal(listener.released, tt.wantReleased) {
+				t.Errorf("released seats of %v, want %v", listener.released, tt.wantReleased)
+			}
+		})
+	}
+}
+
+func TestCancelBooking(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       Status
+		actor        Actor
+		wantErr      error
+		wantStatus   Status
+		wantReleased []string
+	}{
+		{"own booking", StatusBooked, Actor{UserID: "ada"}, nil, StatusCancelled, []string{"e"}},
+		{"own hold", StatusHeld, Actor{UserID: "ada"}, nil, StatusCancelled, []string{"e"}},
+		{"already cancelled", StatusCancelled, Actor{UserID: "ada"}, nil, StatusCancelled, nil},
+		{"booking of another user", StatusBooked, Actor{UserID: "grace"}, ErrBookingNotFound, StatusBooked, nil},
+		{"booking of another user as manager", StatusBooked, Actor{UserID: "grace", ManageAny: true}, nil, StatusCancelled, []string{"e"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeBookingRepository(Booking{ID: "b", UserID: "ada", EventID: "e", Status: tt.status})
+			listener := &recordingListener{}
+			service := NewBookingService(repository, listener, time.Minute)
+
+			if err := service.CancelBooking(t.Context(), "b", tt.actor); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CancelBooking = %v, want %v", err, tt.wantErr)
+			}
+			if status := repository.bookings["b"].Status; status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", status, tt.wantStatus)
+			}
+			if !slices.Equal(listener.released, tt.wantReleased) {
+				t.Errorf("released seats of %v, want %v", listener.released, tt.wantReleased)
+			}
+		})
+	}
+
+	service := NewBookingService(newFakeBookingRepository(), nil, time.Minute)
+	if err := service.CancelBooking(t.Context(), "missing", Actor{UserID: "ada"}); !errors.Is(err, ErrBookingNotFound) {
+		t.Errorf("CancelBooking of a missing booking = %v, want ErrBookingNotFound", err)
+	}
+}
+
+func TestGetBookings(t *testing.T) {
+	tests := []struct {
+		name    string
+		actor   Actor
+		wantErr error
+		// wantListErr is the error of listing the bookings of the owner.
+		wantListErr error
+	}{
+		{"owner", Actor{UserID: "ada"}, nil, nil},
+		{"other user", Actor{UserID: "grace"}, ErrBookingNotFound, ErrUserNotFound},
+		{"other user as manager", Actor{UserID: "grace", ManageAny: true}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewBookingService(newFakeBookingRepository(Booking{ID: "b", UserID: "ada", EventID: "e", Status: StatusBooked}), nil, time.Minute)
+
+			booking, err := service.GetBookingByID(t.Context(), "b", tt.actor)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetBookingByID = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && booking.ID != "b" {
+				t.Errorf("GetBookingByID = %+v, want booking b", booking)
+			}
+
+			bookings, err := service.GetBookingsByUserID(t.Context(), "ada", tt.actor)
+			if !errors.Is(err, tt.wantListErr) {
+				t.Fatalf("GetBookingsByUserID = %v, want %v", err, tt.wantListErr)
+			}
+			if err == nil && len(bookings) != 1 {
+				t.Errorf("GetBookingsByUserID = %+v, want booking b", bookings)
+			}
+		})
+	}
+}
+
+func TestExpireHolds(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Minute)
+	repository := newFakeBookingRepository(
+		Booking{ID: "1", EventID: "a", Status: StatusHeld, ExpiresAt: &past},
+		Booking{ID: "2", EventID: "a", Status: StatusHeld, ExpiresAt: &past},
+		Booking{ID: "3", EventID: "b", Status: StatusHeld, ExpiresAt: &past},
+		Booking{ID: "4", EventID: "c", Status: StatusHeld, ExpiresAt: &future},
+		Booking{ID: "5", EventID: "c", Status: StatusBooked},
+	)
+	listener := &recordingListener{}
+	service := NewBookingService(repository, listener, time.Minute)
+
+	count, err := service.ExpireHolds(t.Context())
+	if err != nil {
+		t.Fatalf("ExpireHolds: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expired %d holds, want 3", count)
+	}
+
+	// Each event is released once, however many of its holds expired.
+	slices.Sort(listener.released)
+	if !slices.Equal(listener.released, []string{"a", "b"}) {
+		t.Errorf("released seats of %v, want [a b]", listener.released)
+	}
+}
+
+func TestGetEventBookings(t *testing.T) {
+	organizer := "ada"
+
+	tests := []struct {
+		name    string
+		eventID string
+		actor   events.Actor
+		wantErr error
+	}{
+		{"organizer", "e", events.Actor{UserID: "ada"}, nil},
+		{"other user", "e", events.Actor{UserID: "grace"}, events.ErrNotEventOrganizer},
+		{"other user as manager", "e", events.Actor{UserID: "grace", ManageAny: true}, nil},
+		{"event without organizer", "legacy", events.Actor{UserID: "ada"}, events.ErrNotEventOrganizer},
+		{"missing event", "missing", events.Actor{UserID: "ada", ManageAny: true}, ErrEventNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeBookingRepository(
+				Booking{ID: "1", UserID: "grace", EventID: tt.eventID, Seats: 2, Status: StatusBooked},
+				Booking{ID: "2", UserID: "linus", EventID: tt.eventID, Seats: 1, Status: StatusHeld},
+			)
+			repository.events["e"] = events.Event{ID: "e", OrganizerID: &organizer}
+			repository.events["legacy"] = events.Event{ID: "legacy"}
+			service := NewBookingService(repository, nil, time.Minute)
+
+			bookings, err := service.GetEventBookings(t.Context(), tt.eventID, tt.actor)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetEventBookings = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && len(bookings) != 2 {
+				t.Errorf("GetEventBookings = %+v, want both bookings", bookings)
+			}
+
+			attendees, err := service.GetEventAttendees(t.Context(), tt.eventID, tt.actor)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetEventAttendees = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (len(attendees) != 1 || attendees[0].UserID != "grace") {
+				t.Errorf("GetEventAttendees = %+v, want grace only", attendees)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package bookings
+
+import (
+	"context"
+	"eventBookingSystem/internal/logging"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// HoldSweeper periodically expires seat holds that were not confirmed in time.
+type HoldSweeper struct {
+	BookingService BookingService
+	Interval       time.Duration
+
+	// lastSuccess is the Unix time of the last successful sweep, or of the
+	// start of Run before the first one.
+	lastSuccess atomic.Int64
+}
+
+func NewHoldSweeper(bookingService BookingService, interval time.Duration) *HoldSweeper {
+	return &HoldSweeper{BookingService: bookingService, Interval: interval}
+}
+
+// Check is a readiness check that fails when the sweeper has not succeeded
+// for three intervals, or is not running.
+func (s *HoldSweeper) Check(ctx context.Context) error {
+	last := s.lastSuccess.Load()
+	if last == 0 {
+		return fmt.Errorf("hold sweeper is not running")
+	}
+	if since := time.Since(time.Unix(last, 0)); since > 3*s.Interval {
+		return fmt.Errorf("no successful sweep for %s", since.Round(time.Second))
+	}
+	return nil
+}
+
+// Run sweeps expired holds every Interval until ctx is cancelled.
+func (s *HoldSweeper) Run(ctx context.Context) {
+	s.lastSuccess.Store(time.Now().Unix())
+	defer s.lastSuccess.Store(0)
+
+	ctx = logging.With(ctx, "worker", "hold_sweeper")
+	logger := logging.FromContext(ctx)
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := s.BookingService.ExpireHolds(ctx)
+			if err != nil {
+				logger.ErrorContext(ctx, "Failed to expire seat holds", "error", err)
+				continue
+			}
+			s.lastSuccess.Store(time.Now().Unix())
+			if count > 0 {
+				logger.InfoContext(ctx, "Expired seat holds", "count", count)
+			}
+		}
+	}
+}
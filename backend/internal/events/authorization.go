@@ -0,0 +1,18 @@
+package events
+
+// Actor is the authenticated user an event operation is performed for.
+type Actor struct {
+	UserID string
+	// ManageAny is set when the user may change events organized by others.
+	ManageAny bool
+}
+
+// CanManage reports whether the actor may change or inspect the bookings of
+// the event. Events created before organizers were tracked have no
+// organizer and can only be managed by users with ManageAny.
+func (a Actor) CanManage(event *Event) bool {
+	if a.ManageAny {
+		return true
+	}
+	return event.OrganizerID != nil && *event.OrganizerID == a.UserID
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"eventBookingSystem/internal/apperr"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Sort fields accepted by EventFilter.SortBy, mapped to their columns.
+var sortColumns = map[string]string{
+	"date":      "date",
+	"title":     "title",
+	"capacity":  "capacity",
+	"createdAt": "created_at",
+}
+
+var (
+	ErrInvalidFilter = apperr.BadRequest("invalid event filter")
+	ErrInvalidCursor = fmt.Errorf("%w: invalid cursor", ErrInvalidFilter)
+)
+
+// EventFilter selects, orders and paginates events. Zero values mean "no
+// restriction". When Cursor is set it takes precedence over Page.
+type EventFilter struct {
+	From          *time.Time
+	To            *time.Time
+	Location      string
+	Search        string
+	UpcomingOnly  bool
+	AvailableOnly bool
+	OrganizerID   string
+	SortBy        string
+	SortDesc      bool
+	Page          int
+	PageSize      int
+	Cursor        string
+}
+
+// EventPage is one page of an event listing.
+type EventPage struct {
+	Items      []Event `json:"items"`
+	Total      int64   `json:"total"`
+	Page       int     `json:"page,omitempty"`
+	PageSize   int     `json:"pageSize"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+}
+
+// Normalize fills in defaults and rejects values the repository cannot use.
+func (f *EventFilter) Normalize() error {
+	if f.SortBy == "" {
+		f.SortBy = "date"
+	}
+	if _, ok := sortColumns[f.SortBy]; !ok {
+		return fmt.Errorf("%w: unsupported sort field %q", ErrInvalidFilter, f.SortBy)
+	}
+
+	if f.PageSize <= 0 {
+		f.PageSize = DefaultPageSize
+	}
+	if f.PageSize > MaxPageSize {
+		f.PageSize = MaxPageSize
+	}
+
+	if f.Cursor == "" && f.Page <= 0 {
+		f.Page = 1
+	}
+
+	if f.From != nil && f.To != nil && f.To.Before(*f.From) {
+		return fmt.Errorf("%w: 'to' must not be before 'from'", ErrInvalidFilter)
+	}
+
+	return nil
+}
+
+// cursor is the keyset position of the last event on a page: the value of the
+// sort column and the event ID as a tie-breaker.
+type cursor struct {
+	SortBy string `json:"s"`
+	Value  string `json:"v"`
+	ID     string `json:"id"`
+}
+
+func encodeCursor(sortBy string, event *Event) string {
+	c := cursor{SortBy: sortBy, ID: event.ID}
+	switch sortBy {
+	case "date":
+		c.Value = event.Date.Format(time.RFC3339Nano)
+	case "createdAt":
+		c.Value = event.CreatedAt.Format(time.RFC3339Nano)
+	case "title":
+		c.Value = event.Title
+	case "capacity":
+		c.Value = strconv.Itoa(event.Capacity)
+	}
+
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the sort column value stored in the cursor, typed for
+// the column it belongs to.
+func decodeCursor(raw, sortBy string) (interface{}, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, "", ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.SortBy != sortBy || c.ID == "" {
+		return nil, "", ErrInvalidCursor
+	}
+
+	switch sortBy {
+	case "date", "createdAt":
+		value, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		return value, c.ID, nil
+	case "capacity":
+		value, err := strconv.Atoi(c.Value)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		return value, c.ID, nil
+	default:
+		return c.Value, c.ID, nil
+	}
+}
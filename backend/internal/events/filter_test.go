@@ -0,0 +1,157 @@
+package events
+
+import (
+	"errors"
+	"eventBookingSystem/internal/apperr"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestEventFilterNormalize(t *testing.T) {
+	from := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	before := from.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		filter  EventFilter
+		want    EventFilter
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			filter: EventFilter{},
+			want:   EventFilter{SortBy: "date", Page: 1, PageSize: DefaultPageSize},
+		},
+		{
+			name:   "page size capped",
+			filter: EventFilter{SortBy: "title", Page: 3, PageSize: 1000},
+			want:   EventFilter{SortBy: "title", Page: 3, PageSize: MaxPageSize},
+		},
+		{
+			name:   "cursor without page",
+			filter: EventFilter{Cursor: "abc"},
+			want:   EventFilter{SortBy: "date", PageSize: DefaultPageSize, Cursor: "abc"},
+		},
+		{
+			name:    "unknown sort field",
+			filter:  EventFilter{SortBy: "created_at"},
+			wantErr: true,
+		},
+		{
+			name:    "to before from",
+			filter:  EventFilter{From: &from, To: &before},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Normalize()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidFilter) {
+					t.Fatalf("Normalize = %v, want ErrInvalidFilter", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize: %v", err)
+			}
+			if tt.filter != tt.want {
+				t.Errorf("Normalize = %+v, want %+v", tt.filter, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursor(t *testing.T) {
+	date := time.Date(2026, 5, 1, 12, 30, 0, 123, time.UTC)
+	event := &Event{ID: "id", Title: "Jazz", Capacity: 42, Date: date, CreatedAt: date.Add(-time.Hour)}
+
+	tests := []struct {
+		sortBy string
+		want   interface{}
+	}{
+		{"date", date},
+		{"createdAt", date.Add(-time.Hour)},
+		{"title", "Jazz"},
+		{"capacity", 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			value, id, err := decodeCursor(encodeCursor(tt.sortBy, event), tt.sortBy)
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if value != tt.want || id != "id" {
+				t.Errorf("decodeCursor = %v, %q, want %v, id", value, id, tt.want)
+			}
+		})
+	}
+
+	for name, raw := range map[string]string{
+		"not base64":          "%%%",
+		"not JSON":            "bm90IGpzb24",
+		"other sort field":    encodeCursor("title", event),
+		"without an event ID": encodeCursor("date", &Event{Date: date}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := decodeCursor(raw, "date"); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decodeCursor = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestParseEventFilter(t *testing.T) {
+	from := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		query     string
+		want      EventFilter
+		wantField string
+	}{
+		{
+			name:  "empty",
+			query: "",
+			want:  EventFilter{},
+		},
+		{
+			name:  "every parameter",
+			query: "location=+Berlin+&q=jazz&sort=title&order=DESC&from=2026-05-01T00:00:00Z&upcoming=true&available=1&page=2&pageSize=10&cursor=abc",
+			want: EventFilter{
+				Location: "Berlin", Search: "jazz", SortBy: "title", SortDesc: true, From: &from,
+				UpcomingOnly: true, AvailableOnly: true, Page: 2, PageSize: 10, Cursor: "abc",
+			},
+		},
+		{name: "invalid date", query: "to=tomorrow", wantField: "to"},
+		{name: "invalid flag", query: "upcoming=yes", wantField: "upcoming"},
+		{name: "zero page", query: "page=0", wantField: "page"},
+		{name: "invalid page size", query: "pageSize=ten", wantField: "pageSize"},
+		{name: "invalid order", query: "order=random", wantField: "order"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			filter, err := parseEventFilter(query)
+			if tt.wantField != "" {
+				var appErr *apperr.Error
+				if !errors.As(err, &appErr) || appErr.Fields[tt.wantField] == "" {
+					t.Fatalf("parseEventFilter = %v, want an error for %s", err, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseEventFilter: %v", err)
+			}
+
+			if (filter.From == nil) != (tt.want.From == nil) || (filter.From != nil && !filter.From.Equal(*tt.want.From)) {
+				t.Errorf("from = %v, want %v", filter.From, tt.want.From)
+			}
+			filter.From, tt.want.From = nil, nil
+			if filter != tt.want {
+				t.Errorf("parseEventFilter = %+v, want %+v", filter, tt.want)
+			}
+		})
+	}
+}
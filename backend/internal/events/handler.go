@@ -2,97 +2,165 @@ package events
 
 import (
 	"encoding/json"
+	"eventBookingSystem/internal/apperr"
+	"eventBookingSystem/internal/auth/roles"
+	"eventBookingSystem/internal/middleware"
+	"eventBookingSystem/internal/request"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type EventHandler struct {
-	EventService EventService
+	EventService      EventService
+	PermissionChecker middleware.PermissionChecker
 }
 
-func NewEventHandler(eventService EventService) *EventHandler {
-	return &EventHandler{EventService: eventService}
+func NewEventHandler(eventService EventService, permissionChecker middleware.PermissionChecker) *EventHandler {
+	return &EventHandler{EventService: eventService, PermissionChecker: permissionChecker}
 }
 
+// actor returns the caller of the request, including whether they may manage
+// events organized by others.
+func (h *EventHandler) actor(r *http.Request) (Actor, error) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	manageAny, err := h.PermissionChecker.UserHasPermission(r.Context(), userID, roles.PermissionManageAnyEvents)
+	if err != nil {
+		return Actor{}, err
+	}
+
+	return Actor{UserID: userID, ManageAny: manageAny}, nil
+}
+
+// ListEvents supports the query parameters from, to (RFC3339), location, q,
+// upcoming, available, sort (date, title, capacity, createdAt), order (asc,
+// desc), page, pageSize and cursor.
 func (h *EventHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
-	events, err := h.EventService.GetAllEvents()
+	filter, err := parseEventFilter(r.URL.Query())
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	page, err := h.EventService.ListEvents(r.Context(), filter)
 	if err != nil {
-		http.Error(w, "Failed to get events", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(events)
+	json.NewEncoder(w).Encode(page)
 }
 
-func (h *EventHandler) HandleEvents(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		// Extract event ID from the URL path
-		parts := strings.Split(r.URL.Path, "/")
-		if len(parts) > 3 {
-			eventID := parts[3]
-			if eventID != "" {
-				h.GetEventDetails(w, r)
+// SearchEvents handles GET /api/events/search?q=...&limit=...&offset=...
+func (h *EventHandler) SearchEvents(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		apperr.Write(w, r, apperr.BadRequest("Search query is required"))
+		return
+	}
+
+	var limit, offset int
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{{"limit", &limit}, {"offset", &offset}} {
+		if value := r.URL.Query().Get(param.name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				apperr.Write(w, r, apperr.Field(param.name, fmt.Sprintf("invalid '%s', expected a non-negative integer", param.name)))
 				return
 			}
+			*param.target = parsed
 		}
-		h.ListEvents(w, r)
-	case http.MethodPost:
-		h.CreateEvent(w, r)
-	case http.MethodPut:
-		h.UpdateEvent(w, r)
-	case http.MethodDelete:
-		h.DeleteEvent(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
-}
 
-func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		Date        string `json:"date"`
-		Location    string `json:"location"`
-		Capacity    int    `json:"capacity"`
+	results, err := h.EventService.SearchEvents(r.Context(), query, limit, offset)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+func parseEventFilter(query url.Values) (EventFilter, error) {
+	filter := EventFilter{
+		Location: strings.TrimSpace(query.Get("location")),
+		Search:   strings.TrimSpace(query.Get("q")),
+		SortBy:   query.Get("sort"),
+		Cursor:   query.Get("cursor"),
 	}
 
-	// Input validation
-	if strings.TrimSpace(req.Title) == "" {
-		http.Error(w, "Title is required", http.StatusBadRequest)
-		return
+	for _, param := range []struct {
+		name   string
+		target **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if value := query.Get(param.name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, apperr.Field(param.name, fmt.Sprintf("invalid '%s' date, expected RFC3339", param.name))
+			}
+			*param.target = &parsed
+		}
 	}
 
-	if strings.TrimSpace(req.Date) == "" {
-		http.Error(w, "Date is required", http.StatusBadRequest)
-		return
+	for _, param := range []struct {
+		name   string
+		target *bool
+	}{{"upcoming", &filter.UpcomingOnly}, {"available", &filter.AvailableOnly}} {
+		if value := query.Get(param.name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return filter, apperr.Field(param.name, fmt.Sprintf("invalid '%s' flag, expected true or false", param.name))
+			}
+			*param.target = parsed
+		}
 	}
 
-	if _, err := time.Parse(time.RFC3339, req.Date); err != nil {
-		http.Error(w, "Invalid date format", http.StatusBadRequest)
-		return
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{{"page", &filter.Page}, {"pageSize", &filter.PageSize}} {
+		if value := query.Get(param.name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				return filter, apperr.Field(param.name, fmt.Sprintf("invalid '%s', expected a positive integer", param.name))
+			}
+			*param.target = parsed
+		}
 	}
 
-	if strings.TrimSpace(req.Location) == "" {
-		http.Error(w, "Location is required", http.StatusBadRequest)
-		return
+	switch strings.ToLower(query.Get("order")) {
+	case "", "asc":
+	case "desc":
+		filter.SortDesc = true
+	default:
+		return filter, apperr.Field("order", "invalid 'order', expected asc or desc")
 	}
 
-	if req.Capacity <= 0 {
-		http.Error(w, "Capacity must be a positive integer", http.StatusBadRequest)
+	return filter, nil
+}
+
+func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	var req EventRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	event, err := h.EventService.CreateEvent(req.Title, req.Description, req.Date, req.Location, req.Capacity)
+	organizerID := r.Context().Value(middleware.UserIDKey).(string)
+
+	event, err := h.EventService.CreateEvent(r.Context(), req.Title, req.Description, req.Date, req.Location, req.Capacity, organizerID)
 	if err != nil {
-		http.Error(w, "Failed to create event", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -101,21 +169,15 @@ func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *EventHandler) GetEventDetails(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) != 4 {
-		http.Error(w, "Invalid URL", http.StatusBadRequest)
-		return
-	}
-
-	eventID := parts[3]
-	if eventID == "" {
-		http.Error(w, "Event ID is required", http.StatusBadRequest)
+	eventID := r.PathValue("id")
+	if _, err := uuid.Parse(eventID); err != nil {
+		apperr.Write(w, r, apperr.BadRequest("Invalid event ID"))
 		return
 	}
 
-	event, err := h.EventService.GetEventByID(eventID)
+	event, err := h.EventService.GetEventByID(r.Context(), eventID)
 	if err != nil {
-		http.Error(w, "Event not found", http.StatusNotFound)
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -124,103 +186,109 @@ func (h *EventHandler) GetEventDetails(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) != 4 {
-		http.Error(w, "Invalid URL", http.StatusBadRequest)
+	eventID := r.PathValue("id")
+	if _, err := uuid.Parse(eventID); err != nil {
+		apperr.Write(w, r, apperr.BadRequest("Invalid event ID"))
 		return
 	}
 
-	eventID := parts[3]
-	if eventID == "" {
-		http.Error(w, "Event ID is required", http.StatusBadRequest)
+	var req EventRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	var req struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		Date        string `json:"date"`
-		Location    string `json:"location"`
-		Capacity    int    `json:"capacity"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	actor, err := h.actor(r)
+	if err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	// Input validation
-	if strings.TrimSpace(req.Title) == "" {
-		http.Error(w, "Title is required", http.StatusBadRequest)
+	existingEvent, err := h.EventService.GetManagedEvent(r.Context(), eventID, actor)
+	if err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	if strings.TrimSpace(req.Date) == "" {
-		http.Error(w, "Date is required", http.StatusBadRequest)
-		return
-	}
+	existingEvent.Title = req.Title
+	existingEvent.Description = req.Description
+	existingEvent.Date = req.ParsedDate()
+	existingEvent.Location = req.Location
+	existingEvent.Capacity = req.Capacity
 
-	if _, err := time.Parse(time.RFC3339, req.Date); err != nil {
-		http.Error(w, "Invalid date format", http.StatusBadRequest)
+	err = h.EventService.UpdateEvent(r.Context(), existingEvent)
+	if err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	if strings.TrimSpace(req.Location) == "" {
-		http.Error(w, "Location is required", http.StatusBadRequest)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingEvent)
+}
+
+func (h *EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	eventID := r.PathValue("id")
+	if _, err := uuid.Parse(eventID); err != nil {
+		apperr.Write(w, r, apperr.BadRequest("Invalid event ID"))
 		return
 	}
 
-	if req.Capacity <= 0 {
-		http.Error(w, "Capacity must be a positive integer", http.StatusBadRequest)
+	actor, err := h.actor(r)
+	if err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	existingEvent, err := h.EventService.GetEventByID(eventID)
+	err = h.EventService.DeleteEvent(r.Context(), eventID, actor)
 	if err != nil {
-		http.Error(w, "Event not found", http.StatusNotFound)
+		apperr.Write(w, r, err)
 		return
 	}
 
-	parsedDate, err := time.Parse(time.RFC3339, req.Date)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListOrganizerEvents lists the events organized by the caller. It accepts
+// the same query parameters as ListEvents.
+func (h *EventHandler) ListOrganizerEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseEventFilter(r.URL.Query())
 	if err != nil {
-		http.Error(w, "Invalid date format", http.StatusBadRequest)
+		apperr.Write(w, r, err)
 		return
 	}
+	filter.OrganizerID = r.Context().Value(middleware.UserIDKey).(string)
 
-	existingEvent.Title = req.Title
-	existingEvent.Description = req.Description
-	existingEvent.Date = parsedDate
-	existingEvent.Location = req.Location
-	existingEvent.Capacity = req.Capacity
-
-	err = h.EventService.UpdateEvent(existingEvent)
+	page, err := h.EventService.ListEvents(r.Context(), filter)
 	if err != nil {
-		http.Error(w, "Failed to update event", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(existingEvent)
+	json.NewEncoder(w).Encode(page)
 }
 
-func (h *EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) != 4 {
-		http.Error(w, "Invalid URL", http.StatusBadRequest)
+// TransferOwnership handles POST /api/admin/events/{eventID}/transfer and
+// makes another user the event's organizer.
+func (h *EventHandler) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+	eventID := r.PathValue("id")
+	if _, err := uuid.Parse(eventID); err != nil {
+		apperr.Write(w, r, apperr.BadRequest("Invalid event ID"))
 		return
 	}
 
-	eventID := parts[3]
-	if eventID == "" {
-		http.Error(w, "Event ID is required", http.StatusBadRequest)
+	var req TransferOwnershipRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	err := h.EventService.DeleteEvent(eventID)
+	event, err := h.EventService.TransferOwnership(r.Context(), eventID, req.OrganizerID)
 	if err != nil {
-		http.Error(w, "Failed to delete event", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(event)
 }
@@ -0,0 +1,240 @@
+package events
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryEventRepository keeps events in the process. It has no bookings, so
+// every seat of an event counts as available, and it is meant for tests and
+// trying out the API without a database.
+type MemoryEventRepository struct {
+	mu     sync.Mutex
+	events map[string]Event
+	// users are the IDs UserExists reports as existing.
+	users map[string]bool
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+}
+
+func NewMemoryEventRepository(userIDs ...string) *MemoryEventRepository {
+	users := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		users[id] = true
+	}
+	return &MemoryEventRepository{
+		events: make(map[string]Event),
+		users:  users,
+		Now:    time.Now,
+	}
+}
+
+func (r *MemoryEventRepository) Create(ctx context.Context, event *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.events[event.ID]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	now := r.Now()
+	event.CreatedAt, event.UpdatedAt = now, now
+	r.events[event.ID] = *event
+	return nil
+}
+
+func (r *MemoryEventRepository) GetByID(ctx context.Context, id string) (*Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event, ok := r.events[id]
+	if !ok {
+		return &Event{}, gorm.ErrRecordNotFound
+	}
+	return &event, nil
+}
+
+// GetAll returns the events ordered by date, then ID.
+func (r *MemoryEventRepository) GetAll(ctx context.Context) ([]Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := make([]Event, 0, len(r.events))
+	for _, event := range r.events {
+		events = append(events, event)
+	}
+	slices.SortFunc(events, func(a, b Event) int {
+		return compareEvents(&a, &b, "date")
+	})
+	return events, nil
+}
+
+// Query returns the page of events matching the filter like
+// EventRepositoryImpl.Query. The filter must have been normalized.
+func (r *MemoryEventRepository) Query(ctx context.Context, filter EventFilter) (*EventPage, error) {
+	events, _ := r.GetAll(ctx)
+
+	var matching []Event
+	for _, event := range events {
+		if r.matches(&event, &filter) {
+			matching = append(matching, event)
+		}
+	}
+
+	slices.SortFunc(matching, func(a, b Event) int {
+		c := compareEvents(&a, &b, filter.SortBy)
+		if filter.SortDesc {
+			return -c
+		}
+		return c
+	})
+
+	page := &EventPage{
+		Items:    []Event{},
+		Total:    int64(len(matching)),
+		PageSize: filter.PageSize,
+	}
+
+	start := 0
+	if filter.Cursor != "" {
+		value, id, err := decodeCursor(filter.Cursor, filter.SortBy)
+		if err != nil {
+			return nil, err
+		}
+		// The cursor event itself may be gone, so look for the first event
+		// after its position.
+		after := Event{ID: id}
+		setSortValue(&after, filter.SortBy, value)
+		start = len(matching)
+		for i := range matching {
+			c := compareEvents(&matching[i], &after, filter.SortBy)
+			if filter.SortDesc {
+				c = -c
+			}
+			if c > 0 {
+				start = i
+				break
+			}
+		}
+	} else {
+		page.Page = filter.Page
+		start = min((filter.Page-1)*filter.PageSize, len(matching))
+	}
+
+	end := min(start+filter.PageSize, len(matching))
+	page.Items = append(page.Items, matching[start:end]...)
+	if end < len(matching) && len(page.Items) > 0 {
+		page.NextCursor = encodeCursor(filter.SortBy, &page.Items[len(page.Items)-1])
+	}
+	return page, nil
+}
+
+func (r *MemoryEventRepository) matches(event *Event, filter *EventFilter) bool {
+	switch {
+	case filter.From != nil && event.Date.Before(*filter.From):
+		return false
+	case filter.To != nil && event.Date.After(*filter.To):
+		return false
+	case filter.UpcomingOnly && event.Date.Before(r.Now()):
+		return false
+	case filter.OrganizerID != "" && (event.OrganizerID == nil || *event.OrganizerID != filter.OrganizerID):
+		return false
+	case filter.Location != "" && !containsFold(event.Location, filter.Location):
+		return false
+	case filter.Search != "" && !containsFold(event.Title, filter.Search) && !containsFold(event.Description, filter.Search):
+		return false
+	case filter.AvailableOnly && event.Capacity <= 0:
+		return false
+	}
+	return true
+}
+
+// Update saves the event like EventRepositoryImpl.Update. With no bookings,
+// any capacity is accepted.
+func (r *MemoryEventRepository) Update(ctx context.Context, event *Event) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, ok := r.events[event.ID]
+	if !ok {
+		return 0, gorm.ErrRecordNotFound
+	}
+	event.OrganizerID = previous.OrganizerID
+	event.CreatedAt = previous.CreatedAt
+	event.UpdatedAt = r.Now()
+	r.events[event.ID] = *event
+	return previous.Capacity, nil
+}
+
+func (r *MemoryEventRepository) SetOrganizer(ctx context.Context, id, organizerID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event, ok := r.events[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	event.OrganizerID = &organizerID
+	event.UpdatedAt = r.Now()
+	r.events[id] = event
+	return nil
+}
+
+func (r *MemoryEventRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.events, id)
+	return nil
+}
+
+func (r *MemoryEventRepository) UserExists(ctx context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.users[id], nil
+}
+
+// compareEvents orders events by the sort field, then by ID, like the ORDER
+// BY of EventRepositoryImpl.Query.
+func compareEvents(a, b *Event, sortBy string) int {
+	var c int
+	switch sortBy {
+	case "date":
+		c = a.Date.Compare(b.Date)
+	case "createdAt":
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	case "title":
+		c = strings.Compare(a.Title, b.Title)
+	case "capacity":
+		c = cmp.Compare(a.Capacity, b.Capacity)
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// setSortValue sets the sort field of event to a value from decodeCursor.
+func setSortValue(event *Event, sortBy string, value interface{}) {
+	switch sortBy {
+	case "date":
+		event.Date = value.(time.Time)
+	case "createdAt":
+		event.CreatedAt = value.(time.Time)
+	case "title":
+		event.Title = value.(string)
+	case "capacity":
+		event.Capacity = value.(int)
+	}
+}
+
+// containsFold is the in-memory ILIKE of likePattern.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
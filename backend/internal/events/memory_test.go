@@ -0,0 +1,169 @@
+package events
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestMemoryEventRepositoryQuery(t *testing.T) {
+	ctx := t.Context()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	organizer := "organizer"
+	repository := NewMemoryEventRepository()
+	repository.Now = func() time.Time { return now }
+	for _, event := range []Event{
+		{ID: "a", Title: "Jazz night", Location: "Berlin", Capacity: 50, Date: now.Add(-24 * time.Hour)},
+		{ID: "b", Title: "Rock", Description: "Loud jazz fusion", Location: "Hamburg", Capacity: 0, Date: now.Add(24 * time.Hour)},
+		{ID: "c", Title: "Chess", Location: "berlin", Capacity: 10, Date: now.Add(48 * time.Hour), OrganizerID: &organizer},
+		{ID: "d", Title: "Art", Location: "Munich", Capacity: 30, Date: now.Add(48 * time.Hour)},
+	} {
+		if err := repository.Create(ctx, &event); err != nil {
+			t.Fatalf("Create(%s): %v", event.ID, err)
+		}
+	}
+	from := now
+
+	tests := []struct {
+		name   string
+		filter EventFilter
+		want   []string
+		total  int64
+	}{
+		{"all by date", EventFilter{}, []string{"a", "b", "c", "d"}, 4},
+		{"descending", EventFilter{SortDesc: true}, []string{"d", "c", "b", "a"}, 4},
+		{"by title", EventFilter{SortBy: "title"}, []string{"d", "c", "a", "b"}, 4},
+		{"by capacity", EventFilter{SortBy: "capacity"}, []string{"b", "c", "d", "a"}, 4},
+		{"from", EventFilter{From: &from}, []string{"b", "c", "d"}, 3},
+		{"upcoming", EventFilter{UpcomingOnly: true}, []string{"b", "c", "d"}, 3},
+		{"location ignores case", EventFilter{Location: "BERLIN"}, []string{"a", "c"}, 2},
+		{"search title and description", EventFilter{Search: "jazz"}, []string{"a", "b"}, 2},
+		{"organizer", EventFilter{OrganizerID: organizer}, []string{"c"}, 1},
+		{"available", EventFilter{AvailableOnly: true}, []string{"a", "c", "d"}, 3},
+		{"page", EventFilter{PageSize: 3, Page: 2}, []string{"d"}, 4},
+		{"page past the end", EventFilter{PageSize: 3, Page: 3}, []string{}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Normalize(); err != nil {
+				t.Fatalf("Normalize: %v", err)
+			}
+			page, err := repository.Query(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			if got := eventIDs(page.Items); got != strings.Join(tt.want, ",") {
+				t.Errorf("items = %s, want %s", got, strings.Join(tt.want, ","))
+			}
+			if page.Total != tt.total {
+				t.Errorf("total = %d, want %d", page.Total, tt.total)
+			}
+		})
+	}
+}
+
+func TestMemoryEventRepositoryCursor(t *testing.T) {
+	ctx := t.Context()
+	repository := NewMemoryEventRepository()
+	date := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		// Equal dates leave the ID to break ties.
+		event := Event{ID: id, Title: id, Date: date}
+		if err := repository.Create(ctx, &event); err != nil {
+			t.Fatalf("Create(%s): %v", id, err)
+		}
+	}
+
+	for _, desc := range []bool{false, true} {
+		filter := EventFilter{PageSize: 2, SortDesc: desc}
+		if err := filter.Normalize(); err != nil {
+			t.Fatalf("Normalize: %v", err)
+		}
+
+		var seen []Event
+		for {
+			page, err := repository.Query(ctx, filter)
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			seen = append(seen, page.Items...)
+			if page.NextCursor == "" {
+				break
+			}
+			filter.Cursor = page.NextCursor
+		}
+
+		want := "a,b,c,d,e"
+		if desc {
+			want = "e,d,c,b,a"
+		}
+		if got := eventIDs(seen); got != want {
+			t.Errorf("desc=%v: pages = %s, want %s", desc, got, want)
+		}
+	}
+
+	filter := EventFilter{Cursor: "not a cursor"}
+	if err := filter.Normalize(); err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if _, err := repository.Query(ctx, filter); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("Query with a bad cursor = %v, want ErrInvalidFilter", err)
+	}
+}
+
+func TestMemoryEventRepositoryCRUD(t *testing.T) {
+	ctx := t.Context()
+	repository := NewMemoryEventRepository("user")
+
+	event := &Event{ID: "a", Title: "Before"}
+	if err := repository.Create(ctx, event); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repository.Create(ctx, &Event{ID: "a"}); !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("Create duplicate = %v, want ErrDuplicatedKey", err)
+	}
+
+	event.Title = "After"
+	if _, err := repository.Update(ctx, event); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err := repository.GetByID(ctx, "a")
+	if err != nil || got.Title != "After" {
+		t.Errorf("GetByID = %+v, %v, want the updated event", got, err)
+	}
+
+	if err := repository.SetOrganizer(ctx, "a", "user"); err != nil {
+		t.Fatalf("SetOrganizer: %v", err)
+	}
+	got, _ = repository.GetByID(ctx, "a")
+	if got.OrganizerID == nil || *got.OrganizerID != "user" || got.Title != "After" {
+		t.Errorf("GetByID = %+v, want organizer user and the title kept", got)
+	}
+	if err := repository.SetOrganizer(ctx, "missing", "user"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("SetOrganizer of a missing event = %v, want ErrRecordNotFound", err)
+	}
+
+	if err := repository.Delete(ctx, "a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repository.GetByID(ctx, "a"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetByID after Delete = %v, want ErrRecordNotFound", err)
+	}
+
+	for id, want := range map[string]bool{"user": true, "other": false} {
+		if exists, _ := repository.UserExists(ctx, id); exists != want {
+			t.Errorf("UserExists(%q) = %v, want %v", id, exists, want)
+		}
+	}
+}
+
+func eventIDs(events []Event) string {
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return strings.Join(ids, ",")
+}
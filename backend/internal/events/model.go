@@ -1,11 +1,19 @@
 package events
 
 import (
+	"eventBookingSystem/internal/apperr"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+var (
+	ErrEventNotFound      = apperr.NotFound("event not found")
+	ErrNotEventOrganizer  = apperr.Forbidden("only the event's organizer can do this")
+	ErrOrganizerNotFound  = apperr.BadRequest("organizer not found")
+	ErrCapacityBelowTaken = apperr.Conflict("capacity is below the seats already taken")
+)
+
 type Event struct {
 	ID          string    `gorm:"type:uuid;primaryKey"`
 	Title       string    `gorm:"not null"`
@@ -13,6 +21,7 @@ type Event struct {
 	Date        time.Time `gorm:"not null"`
 	Location    string    `gorm:"not null"`
 	Capacity    int       `gorm:"not null"`
+	OrganizerID *string   `gorm:"type:uuid;index"`
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	DeletedAt   gorm.DeletedAt `gorm:"index"`
@@ -1,15 +1,24 @@
 package events
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type EventRepository interface {
-	Create(event *Event) error
-	GetByID(id string) (*Event, error)
-	GetAll() ([]Event, error)
-	Update(event *Event) error
-	Delete(id string) error
+	Create(ctx context.Context, event *Event) error
+	GetByID(ctx context.Context, id string) (*Event, error)
+	GetAll(ctx context.Context) ([]Event, error)
+	Query(ctx context.Context, filter EventFilter) (*EventPage, error)
+	Update(ctx context.Context, event *Event) (int, error)
+	SetOrganizer(ctx context.Context, id, organizerID string) error
+	Delete(ctx context.Context, id string) error
+	UserExists(ctx context.Context, id string) (bool, error)
 }
 
 type EventRepositoryImpl struct {
@@ -20,26 +29,168 @@ func NewEventRepository(db *gorm.DB) EventRepository {
 	return &EventRepositoryImpl{DB: db}
 }
 
-func (r *EventRepositoryImpl) Create(event *Event) error {
-	return r.DB.Create(event).Error
+func (r *EventRepositoryImpl) Create(ctx context.Context, event *Event) error {
+	return r.DB.WithContext(ctx).Create(event).Error
 }
 
-func (r *EventRepositoryImpl) GetByID(id string) (*Event, error) {
+func (r *EventRepositoryImpl) GetByID(ctx context.Context, id string) (*Event, error) {
 	var event Event
-	err := r.DB.First(&event, "id = ?", id).Error
+	err := r.DB.WithContext(ctx).First(&event, "id = ?", id).Error
 	return &event, err
 }
 
-func (r *EventRepositoryImpl) GetAll() ([]Event, error) {
+func (r *EventRepositoryImpl) GetAll(ctx context.Context) ([]Event, error) {
 	var events []Event
-	err := r.DB.Find(&events).Error
+	err := r.DB.WithContext(ctx).Find(&events).Error
 	return events, err
 }
 
-func (r *EventRepositoryImpl) Update(event *Event) error {
-	return r.DB.Save(event).Error
+// Query returns the page of events matching the filter. The filter must have
+// been normalized.
+func (r *EventRepositoryImpl) Query(ctx context.Context, filter EventFilter) (*EventPage, error) {
+	query := r.DB.WithContext(ctx).Model(&Event{})
+
+	if filter.From != nil {
+		query = query.Where("date >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("date <= ?", *filter.To)
+	}
+	if filter.UpcomingOnly {
+		query = query.Where("date >= ?", time.Now())
+	}
+	if filter.OrganizerID != "" {
+		query = query.Where("organizer_id = ?", filter.OrganizerID)
+	}
+	if filter.Location != "" {
+		query = query.Where("location ILIKE ?", likePattern(filter.Location))
+	}
+	if filter.Search != "" {
+		pattern := likePattern(filter.Search)
+		query = query.Where("(title ILIKE ? OR description ILIKE ?)", pattern, pattern)
+	}
+	if filter.AvailableOnly {
+		// Seats are taken by holds and confirmed bookings, see
+		// bookings.AvailableSeats.
+		query = query.Where(`capacity > COALESCE((
+			SELECT SUM(b.seats) FROM bookings b
+			WHERE b.event_id = events.id AND b.status IN ('held', 'booked') AND b.deleted_at IS NULL
+		), 0)`)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	column := sortColumns[filter.SortBy]
+	direction, comparison := "ASC", ">"
+	if filter.SortDesc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if filter.Cursor != "" {
+		value, id, err := decodeCursor(filter.Cursor, filter.SortBy)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(
+			fmt.Sprintf("((%[1]s %[2]s ?) OR (%[1]s = ? AND id %[2]s ?))", column, comparison),
+			value, value, id,
+		)
+	} else {
+		query = query.Offset((filter.Page - 1) * filter.PageSize)
+	}
+
+	// Fetch one extra row to know whether there is a next page.
+	var events []Event
+	err := query.
+		Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).
+		Limit(filter.PageSize + 1).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	page := &EventPage{
+		Items:    events,
+		Total:    total,
+		PageSize: filter.PageSize,
+	}
+	if filter.Cursor == "" {
+		page.Page = filter.Page
+	}
+	if len(events) > filter.PageSize {
+		page.Items = events[:filter.PageSize]
+		page.NextCursor = encodeCursor(filter.SortBy, &page.Items[filter.PageSize-1])
+	}
+
+	return page, nil
+}
+
+// Update saves the title, description, date, location and capacity of the
+// event and returns the capacity it had before. The event row stays locked
+// like in bookings.LockEvent, so no seats are taken while the capacity is
+// checked against the seats taken. Other columns, such as the organizer, keep
+// their stored values, which are copied into event.
+func (r *EventRepositoryImpl) Update(ctx context.Context, event *Event) (int, error) {
+	var previous Event
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&previous, "id = ?", event.ID).Error
+		if err != nil {
+			return err
+		}
+
+		// Seats are taken by holds and confirmed bookings, see
+		// bookings.AvailableSeats.
+		var taken int
+		err = tx.Raw(`SELECT COALESCE(SUM(seats), 0) FROM bookings
+			WHERE event_id = ? AND status IN ('held', 'booked') AND deleted_at IS NULL`, event.ID).
+			Scan(&taken).Error
+		if err != nil {
+			return err
+		}
+		if event.Capacity < taken {
+			return ErrCapacityBelowTaken
+		}
+
+		event.OrganizerID = previous.OrganizerID
+		event.CreatedAt = previous.CreatedAt
+		return tx.Model(event).
+			Select("title", "description", "date", "location", "capacity", "updated_at").
+			Updates(event).Error
+	})
+	return previous.Capacity, err
+}
+
+// SetOrganizer makes the user the organizer of the event without touching any
+// other column. It returns gorm.ErrRecordNotFound if there is no such event.
+func (r *EventRepositoryImpl) SetOrganizer(ctx context.Context, id, organizerID string) error {
+	result := r.DB.WithContext(ctx).Model(&Event{}).Where("id = ?", id).Update("organizer_id", organizerID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *EventRepositoryImpl) Delete(ctx context.Context, id string) error {
+	return r.DB.WithContext(ctx).Delete(&Event{}, "id = ?", id).Error
+}
+
+// UserExists reports whether a user with the ID exists and has not been
+// deleted.
+func (r *EventRepositoryImpl) UserExists(ctx context.Context, id string) (bool, error) {
+	var count int64
+	err := r.DB.WithContext(ctx).Table("users").Where("id = ? AND deleted_at IS NULL", id).Count(&count).Error
+	return count > 0, err
 }
 
-func (r *EventRepositoryImpl) Delete(id string) error {
-	return r.DB.Delete(&Event{}, "id = ?", id).Error
+// likePattern turns user input into a "contains" pattern for LIKE/ILIKE with
+// the wildcard characters escaped.
+func likePattern(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	return "%" + escaped + "%"
 }
@@ -0,0 +1,55 @@
+package events
+
+import (
+	"eventBookingSystem/internal/request"
+	"strings"
+	"time"
+)
+
+const (
+	maxTitleLength       = 200
+	maxDescriptionLength = 5000
+	maxLocationLength    = 200
+	maxCapacity          = 100000
+)
+
+// EventRequest is the body of creating and updating an event.
+type EventRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Date        string `json:"date"`
+	Location    string `json:"location"`
+	Capacity    int    `json:"capacity"`
+}
+
+func (r *EventRequest) Normalize() {
+	r.Title = strings.TrimSpace(r.Title)
+	r.Description = strings.TrimSpace(r.Description)
+	r.Date = strings.TrimSpace(r.Date)
+	r.Location = strings.TrimSpace(r.Location)
+}
+
+func (r *EventRequest) Validate(v *request.Validator) {
+	v.Required("title", r.Title)
+	v.MaxLength("title", r.Title, maxTitleLength)
+	v.MaxLength("description", r.Description, maxDescriptionLength)
+	v.Required("date", r.Date)
+	v.Future("date", v.Time("date", r.Date))
+	v.Required("location", r.Location)
+	v.MaxLength("location", r.Location, maxLocationLength)
+	v.Range("capacity", r.Capacity, 1, maxCapacity)
+}
+
+// ParsedDate returns the event date of a validated request.
+func (r *EventRequest) ParsedDate() time.Time {
+	date, _ := time.Parse(time.RFC3339, r.Date)
+	return date
+}
+
+type TransferOwnershipRequest struct {
+	OrganizerID string `json:"organizerID"`
+}
+
+func (r *TransferOwnershipRequest) Validate(v *request.Validator) {
+	v.UUID("organizerID", r.OrganizerID)
+}
@@ -0,0 +1,242 @@
+package events
+
+import (
+	"context"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+const (
+	highlightStart = "<mark>"
+	highlightStop  = "</mark>"
+	snippetRadius  = 60
+
+	// headlineStart and headlineStop delimit matches in PostgreSQL headlines
+	// until the text has been escaped. They are removed from the text first,
+	// so users cannot forge them.
+	headlineStart = "\x01"
+	headlineStop  = "\x02"
+)
+
+// SearchResult is an event matched by a keyword search. Snippet is an
+// HTML-escaped excerpt with the matched terms wrapped in <mark> tags, so it
+// can be rendered as HTML.
+type SearchResult struct {
+	Event   Event   `json:"event"`
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type EventSearcher interface {
+	Search(ctx context.Context, query string, limit, offset int) ([]SearchResult, error)
+}
+
+// NewEventSearcher returns the full-text searcher for PostgreSQL databases and
+// the in-process fallback for anything else.
+func NewEventSearcher(db *gorm.DB, eventRepository EventRepository) EventSearcher {
+	if db != nil && db.Dialector.Name() == "postgres" {
+		return &PostgresEventSearcher{DB: db}
+	}
+	return &BasicEventSearcher{EventRepository: eventRepository}
+}
+
+type PostgresEventSearcher struct {
+	DB *gorm.DB
+}
+
+func (s *PostgresEventSearcher) Search(ctx context.Context, query string, limit, offset int) ([]SearchResult, error) {
+	var rows []struct {
+		Event
+		Rank    float64
+		Snippet string
+	}
+
+	headlineOptions := "StartSel=" + headlineStart + ", StopSel=" + headlineStop + ", MaxFragments=2, MaxWords=30, MinWords=10"
+	err := s.DB.WithContext(ctx).Raw(`
+		SELECT events.*,
+			ts_rank(search_vector, q) AS rank,
+			ts_headline('english', translate(coalesce(title, '') || ' ' || coalesce(description, '') || ' ' || coalesce(location, ''), ?, ''), q, ?) AS snippet
+		FROM events, websearch_to_tsquery('english', ?) AS q
+		WHERE search_vector @@ q AND deleted_at IS NULL
+		ORDER BY rank DESC, date ASC, id ASC
+		LIMIT ? OFFSET ?`,
+		headlineStart+headlineStop, headlineOptions, query, limit, offset,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = SearchResult{Event: row.Event, Rank: row.Rank, Snippet: markHeadline(row.Snippet)}
+	}
+	return results, nil
+}
+
+// markHeadline escapes a PostgreSQL headline and turns its delimiters into
+// <mark> tags.
+func markHeadline(headline string) string {
+	return strings.NewReplacer(headlineStart, highlightStart, headlineStop, highlightStop).
+		Replace(html.EscapeString(headline))
+}
+
+// BasicEventSearcher scans all events of an EventRepository and ranks them by
+// weighted term matches. It mirrors the PostgreSQL weights (title over
+// description over location) and is meant for small or in-memory stores.
+type BasicEventSearcher struct {
+	EventRepository EventRepository
+}
+
+func (s *BasicEventSearcher) Search(ctx context.Context, query string, limit, offset int) ([]SearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	events, err := s.EventRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, event := range events {
+		rank := 1.0*termMatches(event.Title, terms) +
+			0.4*termMatches(event.Description, terms) +
+			0.2*termMatches(event.Location, terms)
+		if rank == 0 {
+			continue
+		}
+
+		text := event.Title + " " + event.Description + " " + event.Location
+		results = append(results, SearchResult{
+			Event:   event,
+			Rank:    rank,
+			Snippet: highlight(text, terms),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Event.Date.Before(results[j].Event.Date)
+	})
+
+	if offset >= len(results) {
+		return []SearchResult{}, nil
+	}
+	results = results[offset:]
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func searchTerms(query string) []string {
+	var terms []string
+	for _, field := range strings.FieldsFunc(strings.ToLower(query), isSeparator) {
+		if len(field) > 1 {
+			terms = append(terms, field)
+		}
+	}
+	return terms
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// termMatches counts the words of text that start with one of the terms, a
+// cheap stand-in for stemming.
+func termMatches(text string, terms []string) float64 {
+	var matches float64
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+		for _, term := range terms {
+			if strings.HasPrefix(word, term) {
+				matches++
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// highlight returns an HTML-escaped excerpt of text around the first match
+// with every matching word marked.
+func highlight(text string, terms []string) string {
+	first := max(firstMatch(text, terms), 0)
+
+	start := max(first-snippetRadius, 0)
+	end := min(first+2*snippetRadius, len(text))
+	for start > 0 && !isBoundary(text, start) {
+		start--
+	}
+	for end < len(text) && !isBoundary(text, end) {
+		end++
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("... ")
+	}
+
+	excerpt := text[start:end]
+	wordStart := -1
+	flush := func(i int) {
+		if wordStart < 0 {
+			return
+		}
+		word := excerpt[wordStart:i]
+		if termMatches(word, terms) > 0 {
+			b.WriteString(highlightStart + word + highlightStop)
+		} else {
+			b.WriteString(word)
+		}
+		wordStart = -1
+	}
+	for i, r := range excerpt {
+		if isSeparator(r) {
+			flush(i)
+			b.WriteString(html.EscapeString(string(r)))
+		} else if wordStart < 0 {
+			wordStart = i
+		}
+	}
+	flush(len(excerpt))
+
+	if end < len(text) {
+		b.WriteString(" ...")
+	}
+	return b.String()
+}
+
+// firstMatch returns the byte offset in text of the first word that starts
+// with one of the terms, or -1 if none does. Words are matched one at a time,
+// as lowercasing all of text can change its length and shift the offsets.
+func firstMatch(text string, terms []string) int {
+	wordStart := -1
+	for i, r := range text {
+		if !isSeparator(r) {
+			if wordStart < 0 {
+				wordStart = i
+			}
+			continue
+		}
+		if wordStart >= 0 && termMatches(text[wordStart:i], terms) > 0 {
+			return wordStart
+		}
+		wordStart = -1
+	}
+	if wordStart >= 0 && termMatches(text[wordStart:], terms) > 0 {
+		return wordStart
+	}
+	return -1
+}
+
+func isBoundary(text string, i int) bool {
+	return text[i] == ' '
+}
@@ -0,0 +1,157 @@
+package events
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSearchRepository(t *testing.T, events ...Event) *MemoryEventRepository {
+	t.Helper()
+	ctx := t.Context()
+	repository := NewMemoryEventRepository()
+	for i := range events {
+		if err := repository.Create(ctx, &events[i]); err != nil {
+			t.Fatalf("Create(%s): %v", events[i].ID, err)
+		}
+	}
+	return repository
+}
+
+func TestBasicEventSearcherRanking(t *testing.T) {
+	ctx := t.Context()
+	base := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+	repository := newSearchRepository(t,
+		Event{ID: "title", Title: "Jazz night", Description: "Live music", Location: "Club", Date: base.Add(48 * time.Hour)},
+		Event{ID: "description", Title: "Open air", Description: "Jazz and blues", Location: "Park", Date: base},
+		Event{ID: "location", Title: "Dinner", Description: "Three courses", Location: "Jazz cellar", Date: base},
+		Event{ID: "twice", Title: "Jazz jazz", Description: "", Location: "Hall", Date: base.Add(72 * time.Hour)},
+		Event{ID: "earlier", Title: "Jazz brunch", Description: "Live music", Location: "Club", Date: base},
+		Event{ID: "unrelated", Title: "Chess", Description: "Tournament", Location: "Library", Date: base},
+	)
+	searcher := &BasicEventSearcher{EventRepository: repository}
+
+	tests := []struct {
+		name   string
+		query  string
+		limit  int
+		offset int
+		want   []string
+	}{
+		{"title over description over location", "jazz", 0, 0, []string{"twice", "earlier", "title", "description", "location"}},
+		{"prefix match", "JAZ", 0, 0, []string{"twice", "earlier", "title", "description", "location"}},
+		{"description matches add to title matches", "jazz music", 0, 0, []string{"twice", "earlier", "title", "description", "location"}},
+		{"equal ranks by date", "live", 0, 0, []string{"earlier", "title"}},
+		{"limit", "jazz", 2, 0, []string{"twice", "earlier"}},
+		{"offset", "jazz", 2, 3, []string{"description", "location"}},
+		{"offset past the end", "jazz", 2, 10, []string{}},
+		{"no match", "opera", 0, 0, []string{}},
+		{"single letters are ignored", "a b", 0, 0, []string{}},
+		{"empty query", "  ", 0, 0, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := searcher.Search(ctx, tt.query, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+
+			got := make([]string, len(results))
+			for i, result := range results {
+				got[i] = result.Event.ID
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			for i := 1; i < len(results); i++ {
+				if results[i].Rank > results[i-1].Rank {
+					t.Errorf("result %d ranks %v above %v", i, results[i].Rank, results[i-1].Rank)
+				}
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	long := strings.Repeat("filler ", 30)
+
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{
+			name:  "marks every matching word",
+			text:  "Jazz night with jazzy tunes",
+			terms: []string{"jazz"},
+			want:  "<mark>Jazz</mark> night with <mark>jazzy</mark> tunes",
+		},
+		{
+			name:  "escapes the text around matches",
+			text:  `<script>alert("jazz")</script> & more`,
+			terms: []string{"jazz"},
+			want:  `&lt;script&gt;alert(&#34;<mark>jazz</mark>&#34;)&lt;/script&gt; &amp; more`,
+		},
+		{
+			name:  "cuts long text around the first match",
+			text:  long + "jazz " + long,
+			terms: []string{"jazz"},
+		},
+		{
+			// "İ" grows from two to three bytes when lowercased.
+			name:  "cuts text that changes length when lowercased",
+			text:  strings.Repeat("İİİİ ", 40) + "jazz " + long,
+			terms: []string{"jazz"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := highlight(tt.text, tt.terms)
+			if tt.want != "" && got != tt.want {
+				t.Errorf("highlight = %q, want %q", got, tt.want)
+			}
+			if !strings.Contains(got, "<mark>jazz</mark>") && !strings.Contains(got, "<mark>Jazz</mark>") {
+				t.Errorf("highlight = %q, want the match marked", got)
+			}
+			if len(tt.text) > 3*snippetRadius {
+				if !strings.HasPrefix(got, "... ") || !strings.HasSuffix(got, " ...") {
+					t.Errorf("highlight = %q, want an excerpt with ellipses", got)
+				}
+				if len(got) >= len(tt.text) {
+					t.Errorf("highlight returned %d bytes of %d, want an excerpt", len(got), len(tt.text))
+				}
+			}
+		})
+	}
+}
+
+func TestHighlightEscapesTerms(t *testing.T) {
+	// The searcher treats "script" as a term here; the tags around it must
+	// still come out escaped.
+	got := highlight("<b>script</b>", []string{"script"})
+	want := "&lt;b&gt;<mark>script</mark>&lt;/b&gt;"
+	if got != want {
+		t.Errorf("highlight = %q, want %q", got, want)
+	}
+}
+
+func TestMarkHeadline(t *testing.T) {
+	tests := []struct {
+		name     string
+		headline string
+		want     string
+	}{
+		{"marks matches", "a \x01jazz\x02 night", "a <mark>jazz</mark> night"},
+		{"escapes markup", "<img src=x onerror=alert(1)> \x01jazz\x02", "&lt;img src=x onerror=alert(1)&gt; <mark>jazz</mark>"},
+		{"escapes literal mark tags", "<mark>fake</mark>", "&lt;mark&gt;fake&lt;/mark&gt;"},
+		{"escapes entities", "Tom &amp; Jerry", "Tom &amp;amp; Jerry"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markHeadline(tt.headline); got != tt.want {
+				t.Errorf("markHeadline(%q) = %q, want %q", tt.headline, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,28 +1,44 @@
 package events
 
 import (
+	"context"
+	"errors"
+	"eventBookingSystem/internal/logging"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type EventService interface {
-	CreateEvent(title, description string, date string, location string, capacity int) (*Event, error)
-	GetEventByID(id string) (*Event, error)
-	GetAllEvents() ([]Event, error)
-	UpdateEvent(event *Event) error
-	DeleteEvent(id string) error
+	CreateEvent(ctx context.Context, title, description string, date string, location string, capacity int, organizerID string) (*Event, error)
+	GetEventByID(ctx context.Context, id string) (*Event, error)
+	GetManagedEvent(ctx context.Context, id string, actor Actor) (*Event, error)
+	GetAllEvents(ctx context.Context) ([]Event, error)
+	ListEvents(ctx context.Context, filter EventFilter) (*EventPage, error)
+	SearchEvents(ctx context.Context, query string, limit, offset int) ([]SearchResult, error)
+	UpdateEvent(ctx context.Context, event *Event) error
+	DeleteEvent(ctx context.Context, id string, actor Actor) error
+	TransferOwnership(ctx context.Context, id, organizerID string) (*Event, error)
+}
+
+// SeatReleaseListener is notified after an event gained free seats because its
+// capacity was raised.
+type SeatReleaseListener interface {
+	SeatsReleased(ctx context.Context, eventID string) error
 }
 
 type EventServiceImpl struct {
-	EventRepository EventRepository
+	EventRepository     EventRepository
+	EventSearcher       EventSearcher
+	SeatReleaseListener SeatReleaseListener
 }
 
-func NewEventService(eventRepository EventRepository) EventService {
-	return &EventServiceImpl{EventRepository: eventRepository}
+func NewEventService(eventRepository EventRepository, eventSearcher EventSearcher, listener SeatReleaseListener) EventService {
+	return &EventServiceImpl{EventRepository: eventRepository, EventSearcher: eventSearcher, SeatReleaseListener: listener}
 }
 
-func (s *EventServiceImpl) CreateEvent(title, description string, date string, location string, capacity int) (*Event, error) {
+func (s *EventServiceImpl) CreateEvent(ctx context.Context, title, description string, date string, location string, capacity int, organizerID string) (*Event, error) {
 	parsedDate, err := time.Parse(time.RFC3339, date)
 	if err != nil {
 		return nil, err
@@ -34,9 +50,10 @@ func (s *EventServiceImpl) CreateEvent(title, description string, date string, l
 		Date:        parsedDate,
 		Location:    location,
 		Capacity:    capacity,
+		OrganizerID: &organizerID,
 	}
 
-	err = s.EventRepository.Create(event)
+	err = s.EventRepository.Create(ctx, event)
 	if err != nil {
 		return nil, err
 	}
@@ -44,18 +61,109 @@ func (s *EventServiceImpl) CreateEvent(title, description string, date string, l
 	return event, nil
 }
 
-func (s *EventServiceImpl) GetEventByID(id string) (*Event, error) {
-	return s.EventRepository.GetByID(id)
+func (s *EventServiceImpl) GetEventByID(ctx context.Context, id string) (*Event, error) {
+	event, err := s.EventRepository.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEventNotFound
+	}
+	return event, err
+}
+
+func (s *EventServiceImpl) GetAllEvents(ctx context.Context) ([]Event, error) {
+	return s.EventRepository.GetAll(ctx)
+}
+
+func (s *EventServiceImpl) ListEvents(ctx context.Context, filter EventFilter) (*EventPage, error) {
+	if err := filter.Normalize(); err != nil {
+		return nil, err
+	}
+	return s.EventRepository.Query(ctx, filter)
+}
+
+func (s *EventServiceImpl) SearchEvents(ctx context.Context, query string, limit, offset int) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	return s.EventSearcher.Search(ctx, query, min(limit, MaxPageSize), max(offset, 0))
 }
 
-func (s *EventServiceImpl) GetAllEvents() ([]Event, error) {
-	return s.EventRepository.GetAll()
+// GetManagedEvent returns the event if the actor may manage it.
+func (s *EventServiceImpl) GetManagedEvent(ctx context.Context, id string, actor Actor) (*Event, error) {
+	event, err := s.EventRepository.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !actor.CanManage(event) {
+		return nil, ErrNotEventOrganizer
+	}
+	return event, nil
 }
 
-func (s *EventServiceImpl) UpdateEvent(event *Event) error {
-	return s.EventRepository.Update(event)
+// UpdateEvent saves the event's details. The capacity cannot go below the
+// seats already taken, and raising it lets the waitlist take the new seats.
+func (s *EventServiceImpl) UpdateEvent(ctx context.Context, event *Event) error {
+	previousCapacity, err := s.EventRepository.Update(ctx, event)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrEventNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if event.Capacity > previousCapacity {
+		s.releaseSeats(ctx, event.ID)
+	}
+	return nil
 }
 
-func (s *EventServiceImpl) DeleteEvent(id string) error {
-	return s.EventRepository.Delete(id)
+func (s *EventServiceImpl) DeleteEvent(ctx context.Context, id string, actor Actor) error {
+	if _, err := s.GetManagedEvent(ctx, id, actor); err != nil {
+		return err
+	}
+	return s.EventRepository.Delete(ctx, id)
+}
+
+// TransferOwnership makes another user the organizer of the event.
+func (s *EventServiceImpl) TransferOwnership(ctx context.Context, id, organizerID string) (*Event, error) {
+	event, err := s.EventRepository.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.EventRepository.UserExists(ctx, organizerID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrOrganizerNotFound
+	}
+
+	err = s.EventRepository.SetOrganizer(ctx, id, organizerID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	event.OrganizerID = &organizerID
+	return event, nil
+}
+
+// releaseSeats notifies the listener that seats were freed. The update has
+// already been committed at this point, so failures are only logged.
+func (s *EventServiceImpl) releaseSeats(ctx context.Context, eventID string) {
+	if s.SeatReleaseListener == nil {
+		return
+	}
+	if err := s.SeatReleaseListener.SeatsReleased(ctx, eventID); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Failed to process released seats", "event_id", eventID, "error", err)
+	}
 }
@@ -0,0 +1,230 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// fakeEventRepository keeps events in memory. Only the users it contains
+// exist.
+type fakeEventRepository struct {
+	events map[string]Event
+	users  map[string]bool
+	// taken are the seats taken per event.
+	taken map[string]int
+}
+
+func newFakeEventRepository(events ...Event) *fakeEventRepository {
+	r := &fakeEventRepository{events: make(map[string]Event), users: make(map[string]bool), taken: make(map[string]int)}
+	for _, event := range events {
+		r.events[event.ID] = event
+	}
+	return r
+}
+
+func (r *fakeEventRepository) Create(ctx context.Context, event *Event) error {
+	r.events[event.ID] = *event
+	return nil
+}
+
+func (r *fakeEventRepository) GetByID(ctx context.Context, id string) (*Event, error) {
+	event, ok := r.events[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &event, nil
+}
+
+func (r *fakeEventRepository) GetAll(ctx context.Context) ([]Event, error) {
+	var events []Event
+	for _, event := range r.events {
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (r *fakeEventRepository) Query(ctx context.Context, filter EventFilter) (*EventPage, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *fakeEventRepository) Update(ctx context.Context, event *Event) (int, error) {
+	previous, ok := r.events[event.ID]
+	if !ok {
+		return 0, gorm.ErrRecordNotFound
+	}
+	if event.Capacity < r.taken[event.ID] {
+		return 0, ErrCapacityBelowTaken
+	}
+	event.OrganizerID = previous.OrganizerID
+	r.events[event.ID] = *event
+	return previous.Capacity, nil
+}
+
+func (r *fakeEventRepository) SetOrganizer(ctx context.Context, id, organizerID string) error {
+	event, ok := r.events[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	event.OrganizerID = &organizerID
+	r.events[id] = event
+	return nil
+}
+
+// recordingListener records the events it was told have free seats.
+type recordingListener struct {
+	released []string
+}
+
+func (l *recordingListener) SeatsReleased(ctx context.Context, eventID string) error {
+	l.released = append(l.released, eventID)
+	return nil
+}
+
+func (r *fakeEventRepository) Delete(ctx context.Context, id string) error {
+	delete(r.events, id)
+	return nil
+}
+
+func (r *fakeEventRepository) UserExists(ctx context.Context, id string) (bool, error) {
+	return r.users[id], nil
+}
+
+func organizedBy(id, organizerID string) Event {
+	event := Event{ID: id, Title: id, Capacity: 10}
+	if organizerID != "" {
+		event.OrganizerID = &organizerID
+	}
+	return event
+}
+
+func TestDeleteEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		eventID string
+		actor   Actor
+		wantErr error
+	}{
+		{"organizer", "e", Actor{UserID: "ada"}, nil},
+		{"other user", "e", Actor{UserID: "grace"}, ErrNotEventOrganizer},
+		{"other user as manager", "e", Actor{UserID: "grace", ManageAny: true}, nil},
+		{"event without organizer", "legacy", Actor{UserID: "ada"}, ErrNotEventOrganizer},
+		{"event without organizer as manager", "legacy", Actor{UserID: "ada", ManageAny: true}, nil},
+		{"missing event", "missing", Actor{UserID: "ada", ManageAny: true}, ErrEventNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeEventRepository(organizedBy("e", "ada"), organizedBy("legacy", ""))
+			service := NewEventService(repository, nil, nil)
+
+			if err := service.DeleteEvent(t.Context(), tt.eventID, tt.actor); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteEvent = %v, want %v", err, tt.wantErr)
+			}
+			if _, exists := repository.events[tt.eventID]; exists != (tt.wantErr != nil && tt.eventID != "missing") {
+				t.Errorf("event exists = %v after DeleteEvent = %v", exists, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpdateEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		eventID  string
+		capacity int
+		// taken are the seats taken of the event.
+		taken        int
+		wantErr      error
+		wantReleased bool
+	}{
+		{name: "same capacity", eventID: "e", capacity: 10, taken: 4},
+		{name: "capacity raised", eventID: "e", capacity: 12, taken: 10, wantReleased: true},
+		{name: "capacity lowered to the seats taken", eventID: "e", capacity: 4, taken: 4},
+		{name: "capacity below the seats taken", eventID: "e", capacity: 3, taken: 4, wantErr: ErrCapacityBelowTaken},
+		{name: "missing event", eventID: "missing", capacity: 10, wantErr: ErrEventNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeEventRepository(organizedBy("e", "ada"))
+			repository.taken["e"] = tt.taken
+			listener := &recordingListener{}
+			service := NewEventService(repository, nil, listener)
+
+			// The event was read before its ownership moved to grace.
+			event := organizedBy(tt.eventID, "ada")
+			if err := repository.SetOrganizer(t.Context(), "e", "grace"); err != nil {
+				t.Fatalf("SetOrganizer: %v", err)
+			}
+			event.Title = "Renamed"
+			event.Capacity = tt.capacity
+
+			if err := service.UpdateEvent(t.Context(), &event); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateEvent = %v, want %v", err, tt.wantErr)
+			}
+			if released := len(listener.released) > 0; released != tt.wantReleased {
+				t.Errorf("seats released = %v, want %v", released, tt.wantReleased)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			stored := repository.events["e"]
+			if stored.Title != "Renamed" || stored.Capacity != tt.capacity {
+				t.Errorf("stored %q with capacity %d, want Renamed with %d", stored.Title, stored.Capacity, tt.capacity)
+			}
+			if stored.OrganizerID == nil || *stored.OrganizerID != "grace" || *event.OrganizerID != "grace" {
+				t.Errorf("organizer = %v, want grace kept", stored.OrganizerID)
+			}
+		})
+	}
+}
+
+func TestTransferOwnership(t *testing.T) {
+	tests := []struct {
+		name          string
+		eventID       string
+		organizerID   string
+		wantErr       error
+		wantOrganizer string
+	}{
+		{"to another user", "e", "grace", nil, "grace"},
+		{"event without organizer", "legacy", "grace", nil, "grace"},
+		{"unknown user", "e", "alan", ErrOrganizerNotFound, "ada"},
+		{"missing event", "missing", "grace", ErrEventNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeEventRepository(organizedBy("e", "ada"), organizedBy("legacy", ""))
+			repository.users["ada"] = true
+			repository.users["grace"] = true
+			service := NewEventService(repository, nil, nil)
+
+			event, err := service.TransferOwnership(t.Context(), tt.eventID, tt.organizerID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TransferOwnership = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (event.OrganizerID == nil || *event.OrganizerID != tt.wantOrganizer) {
+				t.Errorf("returned organizer = %v, want %s", event.OrganizerID, tt.wantOrganizer)
+			}
+			if tt.wantOrganizer == "" {
+				return
+			}
+
+			stored := repository.events[tt.eventID]
+			if stored.OrganizerID == nil || *stored.OrganizerID != tt.wantOrganizer {
+				t.Errorf("stored organizer = %v, want %s", stored.OrganizerID, tt.wantOrganizer)
+			}
+			// The new organizer can manage the event, the previous one cannot.
+			if _, err := service.GetManagedEvent(t.Context(), tt.eventID, Actor{UserID: tt.wantOrganizer}); err != nil {
+				t.Errorf("GetManagedEvent as %s = %v, want nil", tt.wantOrganizer, err)
+			}
+			if tt.wantErr == nil && tt.eventID == "e" {
+				if _, err := service.GetManagedEvent(t.Context(), tt.eventID, Actor{UserID: "ada"}); !errors.Is(err, ErrNotEventOrganizer) {
+					t.Errorf("GetManagedEvent as previous organizer = %v, want ErrNotEventOrganizer", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"eventBookingSystem/internal/migrate"
+	"fmt"
+)
+
+// DatabaseCheck pings the connection pool.
+func DatabaseCheck(db *sql.DB) Check {
+	return db.PingContext
+}
+
+// MigrationCheck fails while migrations are pending, which means the schema
+// is older than the code expects.
+func MigrationCheck(migrator *migrate.Migrator) Check {
+	return func(ctx context.Context) error {
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		pending := 0
+		for _, status := range statuses {
+			if status.AppliedAt == nil {
+				pending++
+			}
+		}
+		if pending > 0 {
+			return fmt.Errorf("%d migrations pending", pending)
+		}
+		return nil
+	}
+}
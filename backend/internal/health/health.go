@@ -0,0 +1,125 @@
+// Package health serves the liveness and readiness endpoints.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports whether a dependency of the server is usable.
+type Check func(ctx context.Context) error
+
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Checker runs the registered checks for the readiness endpoint.
+type Checker struct {
+	// Timeout bounds each run of the checks.
+	Timeout time.Duration
+
+	mu           sync.RWMutex
+	checks       map[string]Check
+	shuttingDown atomic.Bool
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{Timeout: timeout, checks: make(map[string]Check)}
+}
+
+// Register adds a check that has to pass for the server to be ready.
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// SetShuttingDown makes the server report not ready from now on, so it is
+// taken out of rotation while it drains.
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Liveness handles GET /healthz. It only tells that the process is serving
+// requests.
+func (c *Checker) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, http.StatusOK, Report{Status: "ok"})
+}
+
+// Readiness handles GET /readyz. It runs every check and answers 503 Service
+// Unavailable if any fails or the server is shutting down.
+func (c *Checker) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+
+	status := http.StatusOK
+	if report.Status != "ready" {
+		status = http.StatusServiceUnavailable
+	}
+	writeReport(w, status, report)
+}
+
+// Run runs all checks concurrently.
+func (c *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	c.mu.RLock()
+	checks := make(map[string]Check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.RUnlock()
+
+	report := Report{Status: "ready", Checks: make(map[string]CheckResult, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := runCheck(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != "ok" {
+				report.Status = "not_ready"
+			}
+		}()
+	}
+	wg.Wait()
+
+	if c.shuttingDown.Load() {
+		report.Status = "shutting_down"
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, check Check) CheckResult {
+	start := time.Now()
+	err := check(ctx)
+	result := CheckResult{Status: "ok", Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = "failing"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
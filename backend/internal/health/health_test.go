@@ -0,0 +1,121 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func passing(ctx context.Context) error { return nil }
+
+func failing(ctx context.Context) error { return errors.New("connection refused") }
+
+// blocking waits until its context is done.
+func blocking(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name         string
+		checks       map[string]Check
+		shuttingDown bool
+		wantStatus   int
+		wantReport   string
+		// wantFailing is the check expected to fail, if any.
+		wantFailing string
+	}{
+		{
+			name:       "all checks pass",
+			checks:     map[string]Check{"database": passing, "migrations": passing},
+			wantStatus: http.StatusOK,
+			wantReport: "ready",
+		},
+		{
+			name:       "no checks",
+			wantStatus: http.StatusOK,
+			wantReport: "ready",
+		},
+		{
+			name:        "check fails",
+			checks:      map[string]Check{"database": failing, "migrations": passing},
+			wantStatus:  http.StatusServiceUnavailable,
+			wantReport:  "not_ready",
+			wantFailing: "database",
+		},
+		{
+			name:        "check times out",
+			checks:      map[string]Check{"database": passing, "migrations": blocking},
+			wantStatus:  http.StatusServiceUnavailable,
+			wantReport:  "not_ready",
+			wantFailing: "migrations",
+		},
+		{
+			name:         "shutting down",
+			checks:       map[string]Check{"database": passing},
+			shuttingDown: true,
+			wantStatus:   http.StatusServiceUnavailable,
+			wantReport:   "shutting_down",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(10 * time.Millisecond)
+			for name, check := range tt.checks {
+				checker.Register(name, check)
+			}
+			if tt.shuttingDown {
+				checker.SetShuttingDown()
+			}
+
+			w := httptest.NewRecorder()
+			checker.Readiness(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+
+			var report Report
+			if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatalf("decode %s: %v", w.Body, err)
+			}
+			if report.Status != tt.wantReport {
+				t.Errorf("report status = %q, want %q", report.Status, tt.wantReport)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Errorf("report has %d checks, want %d", len(report.Checks), len(tt.checks))
+			}
+			for name, result := range report.Checks {
+				if failed := result.Status != "ok"; failed != (name == tt.wantFailing) {
+					t.Errorf("check %s = %+v, want failing %v", name, result, name == tt.wantFailing)
+				}
+				if name == tt.wantFailing && result.Error == "" {
+					t.Errorf("check %s failed without an error", name)
+				}
+			}
+		})
+	}
+}
+
+func TestLiveness(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Register("database", failing)
+	checker.SetShuttingDown()
+
+	w := httptest.NewRecorder()
+	checker.Liveness(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	// Liveness does not depend on the checks, or the process would be
+	// restarted for a database outage.
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger sends gorm's logs to the logger of the query's context, so
+// repositories that run queries with db.WithContext(ctx) log with the request
+// ID. Failed queries are logged at debug level, since the caller handles the
+// error, and queries slower than SlowThreshold at warn level.
+type GormLogger struct {
+	SlowThreshold time.Duration
+}
+
+func NewGormLogger(slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{SlowThreshold: slowThreshold}
+}
+
+// LogMode is a no-op, the level is set on the slog handler.
+func (l *GormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	FromContext(ctx).InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	FromContext(ctx).WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	FromContext(ctx).ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	logger := FromContext(ctx)
+	elapsed := time.Since(begin)
+
+	var level slog.Level
+	var msg string
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, msg = slog.LevelDebug, "Query failed"
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold:
+		level, msg = slog.LevelWarn, "Slow query"
+	default:
+		level, msg = slog.LevelDebug, "Query"
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []any{"sql", sql, "rows", rows, "duration", elapsed}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+	logger.Log(ctx, level, msg, attrs...)
+}
@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// RequestIDHeader carries the request ID. An ID sent by the client or a proxy
+// is kept, otherwise one is generated, and it is echoed in the response.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds IDs taken from clients, which end up in every log
+// line of the request.
+const maxRequestIDLength = 128
+
+// Request holds what the access log reports about a request that is only
+// known further down the handler chain.
+type Request struct {
+	ID     string
+	UserID string
+}
+
+type requestKey struct{}
+
+// NewRequest returns a copy of ctx that tracks the request with the given ID.
+func NewRequest(ctx context.Context, id string) (context.Context, *Request) {
+	req := &Request{ID: id}
+	return context.WithValue(ctx, requestKey{}, req), req
+}
+
+// RequestID returns the ID of the request ctx belongs to, if any.
+func RequestID(ctx context.Context) string {
+	if req, ok := ctx.Value(requestKey{}).(*Request); ok {
+		return req.ID
+	}
+	return ""
+}
+
+// SetUserID records the authenticated user for the access log and adds it to
+// the logger of the returned context.
+func SetUserID(ctx context.Context, userID string) context.Context {
+	if req, ok := ctx.Value(requestKey{}).(*Request); ok {
+		req.UserID = userID
+	}
+	return With(ctx, "user_id", userID)
+}
+
+// RequestIDFromHeader returns the ID sent in the request header if it is
+// usable, otherwise a new random one.
+func RequestIDFromHeader(header string) string {
+	if header != "" && len(header) <= maxRequestIDLength && printable(header) {
+		return header
+	}
+	return NewRequestID()
+}
+
+// NewRequestID returns a random 128-bit ID in hex.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func printable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x21 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+// Package metrics collects Prometheus metrics and serves them on /metrics.
+//
+// Business events are recorded through the functions of this package so
+// callers do not depend on the Prometheus client.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "event_booking"
+
+// Registry holds every metric of the application.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	bookingsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_created_total",
+		Help:      "Bookings created, by their initial status (held or booked).",
+	}, []string{"status"})
+
+	bookingsCancelled = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_cancelled_total",
+		Help:      "Bookings and holds cancelled.",
+	})
+
+	seatsSold = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "seats_sold_total",
+		Help:      "Seats of confirmed bookings, by event.",
+	}, []string{"event_id"})
+
+	failedLogins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "failed_logins_total",
+		Help:      "Rejected login attempts, by step (password, mfa or oidc).",
+	}, []string{"step"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		bookingsCreated,
+		bookingsCancelled,
+		seatsSold,
+		failedLogins,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// RegisterDB exports the connection pool statistics of db.
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// BookingCreated records a new booking or hold.
+func BookingCreated(status string) {
+	bookingsCreated.WithLabelValues(status).Inc()
+}
+
+// BookingCancelled records a cancelled booking or hold.
+func BookingCancelled() {
+	bookingsCancelled.Inc()
+}
+
+// SeatsSold records seats that were confirmed for an event.
+func SeatsSold(eventID string, seats int) {
+	seatsSold.WithLabelValues(eventID).Add(float64(seats))
+}
+
+// LoginFailed records a rejected login at step "password", "mfa" or "oidc".
+func LoginFailed(step string) {
+	failedLogins.WithLabelValues(step).Inc()
+}
+
+func observeRequest(method, route string, status int, seconds float64) {
+	labels := []string{method, route, strconv.Itoa(status)}
+	httpRequests.WithLabelValues(labels...).Inc()
+	httpDuration.WithLabelValues(labels...).Observe(seconds)
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+)
+
+// unmatchedRoute labels requests no route matched, so unknown paths do not
+// create a series each.
+const unmatchedRoute = "unmatched"
+
+// Middleware records the count and latency of every request. It has to wrap
+// the ServeMux with the same *http.Request, since the mux stores the matched
+// route pattern in r.Pattern.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = unmatchedRoute
+		}
+		observeRequest(r.Method, route, recorder.status, time.Since(start).Seconds())
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddlewareRouteLabels(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/events/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("DELETE /api/bookings/{id}", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "conflict", http.StatusConflict)
+	})
+	handler := Middleware(mux)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		// labels are the method, route and status the request is counted under.
+		labels [3]string
+	}{
+		{"matched route", http.MethodGet, "/api/events/7f1c", [3]string{"GET", "GET /api/events/{id}", "200"}},
+		{"other ID counts under the same route", http.MethodGet, "/api/events/a9e2", [3]string{"GET", "GET /api/events/{id}", "200"}},
+		{"error status", http.MethodDelete, "/api/bookings/5d0b", [3]string{"DELETE", "DELETE /api/bookings/{id}", "409"}},
+		{"unknown path", http.MethodGet, "/api/unknown/42", [3]string{"GET", unmatchedRoute, "404"}},
+		{"wrong method", http.MethodPost, "/api/events/7f1c", [3]string{"POST", unmatchedRoute, "405"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := httpRequests.WithLabelValues(tt.labels[:]...)
+			before := testutil.ToFloat64(counter)
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("requests counted under %v = %v, want 1", tt.labels, got)
+			}
+		})
+	}
+
+	// Paths must not leak into the labels, or every ID would create a series.
+	if got := testutil.CollectAndCount(httpRequests); got != 4 {
+		t.Errorf("request series = %d, want 4", got)
+	}
+}
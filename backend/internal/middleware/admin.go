@@ -1,24 +1,36 @@
 package middleware
 
 import (
-	"eventBookingSystem/internal/auth/roles"
+	"context"
+	"eventBookingSystem/internal/apperr"
 	"net/http"
 )
 
+// PermissionChecker reports whether a user has been granted a permission
+// through any of their roles.
+type PermissionChecker interface {
+	UserHasPermission(ctx context.Context, userID, permission string) (bool, error)
+}
+
 // RequirePermission creates a middleware that checks for a specific permission
-func RequirePermission(permission string) func(http.Handler) http.Handler {
+func RequirePermission(checker PermissionChecker, permission string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Get the user role from the context
-			role, ok := r.Context().Value(UserRoleKey).(string)
+			// Get the user ID from the context
+			userID, ok := r.Context().Value(UserIDKey).(string)
 			if !ok {
-				http.Error(w, "Unauthorized: Missing role", http.StatusUnauthorized)
+				apperr.Write(w, r, apperr.Unauthenticated("Missing user"))
 				return
 			}
 
-			// Check if the role has the required permission
-			if !roles.HasPermission(role, permission) {
-				http.Error(w, "Forbidden: Insufficient permissions", http.StatusForbidden)
+			// Check if the user's roles grant the required permission
+			allowed, err := checker.UserHasPermission(r.Context(), userID, permission)
+			if err != nil {
+				apperr.Write(w, r, err)
+				return
+			}
+			if !allowed {
+				apperr.Write(w, r, apperr.Forbidden("Insufficient permissions"))
 				return
 			}
 
@@ -26,21 +38,3 @@ func RequirePermission(permission string) func(http.Handler) http.Handler {
 		})
 	}
 }
-
-// AdminMiddleware checks if the user has admin role
-func AdminMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		role, ok := r.Context().Value(UserRoleKey).(string)
-		if !ok {
-			http.Error(w, "Unauthorized: Missing role", http.StatusUnauthorized)
-			return
-		}
-
-		if role != roles.RoleAdmin {
-			http.Error(w, "Forbidden: Admin access required", http.StatusForbidden)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeChecker grants the permissions and verified emails it contains.
+type fakeChecker struct {
+	granted  map[string]bool
+	verified map[string]bool
+	err      error
+}
+
+func (c fakeChecker) UserHasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	return c.granted[userID+" "+permission], c.err
+}
+
+func (c fakeChecker) IsEmailVerified(ctx context.Context, userID string) (bool, error) {
+	return c.verified[userID], c.err
+}
+
+// serveAs runs handler for a request authenticated as userID, or an
+// unauthenticated request if userID is empty.
+func serveAs(handler http.Handler, userID string) int {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if userID != "" {
+		r = r.WithContext(context.WithValue(r.Context(), UserIDKey, userID))
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+func TestRequirePermission(t *testing.T) {
+	checker := fakeChecker{granted: map[string]bool{"ada events:create": true}}
+
+	tests := []struct {
+		name       string
+		checker    fakeChecker
+		userID     string
+		wantStatus int
+	}{
+		{"granted", checker, "ada", http.StatusOK},
+		{"not granted", checker, "grace", http.StatusForbidden},
+		{"unauthenticated", checker, "", http.StatusUnauthorized},
+		{"lookup fails", fakeChecker{err: errors.New("connection refused")}, "ada", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serveAs(RequirePermission(tt.checker, "events:create")(okHandler), tt.userID); got != tt.wantStatus {
+				t.Errorf("status = %d, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+}
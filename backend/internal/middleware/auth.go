@@ -2,78 +2,81 @@ package middleware
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"eventBookingSystem/internal/apperr"
+	"eventBookingSystem/internal/auth/token"
+	"eventBookingSystem/internal/logging"
 	"net/http"
-	"os"
 	"strings"
-
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type contextKey string
 
 const (
-	UserIDKey   contextKey = "userID"
-	UserRoleKey contextKey = "userRole"
+	UserIDKey    contextKey = "userID"
+	SessionIDKey contextKey = "sessionID"
 )
 
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
-			return
-		}
-
-		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
-
-		userID, role, err := verifyJWT(tokenString)
-		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		ctx := context.WithValue(r.Context(), UserIDKey, userID)
-		ctx = context.WithValue(ctx, UserRoleKey, role)
-		r = r.WithContext(ctx)
-
-		next.ServeHTTP(w, r)
-	})
+// SessionValidator reports whether the session an access token was issued
+// for is still active.
+type SessionValidator interface {
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
 }
 
-func verifyJWT(tokenString string) (string, string, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Fatal("JWT_SECRET is not set in .env file")
-		return "", "", fmt.Errorf("JWT_SECRET is not set")
-	}
-
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("invalid signing method")
-		}
-		return []byte(jwtSecret), nil
-	})
-
-	if err != nil {
-		return "", "", err
-	}
+// TokenVerifier verifies the signature and claims of access tokens.
+type TokenVerifier interface {
+	Verify(tokenString, purpose string) (*token.Claims, error)
+}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok || !token.Valid {
-		return "", "", fmt.Errorf("invalid token claims")
-	}
+// AuthMiddleware creates a middleware that verifies the bearer token and
+// rejects tokens whose session has been revoked.
+func AuthMiddleware(tokens TokenVerifier, sessions SessionValidator) func(http.Handler) http.Handler {
+	return authenticate(tokens, sessions, false)
+}
 
-	userID, ok := claims["userID"].(string)
-	if !ok {
-		return "", "", fmt.Errorf("invalid user ID in token")
-	}
+// MFAEnrollmentAuthMiddleware is like AuthMiddleware but also accepts tokens
+// limited to two-factor enrollment. Use it only for the enrollment routes.
+func MFAEnrollmentAuthMiddleware(tokens TokenVerifier, sessions SessionValidator) func(http.Handler) http.Handler {
+	return authenticate(tokens, sessions, true)
+}
 
-	role, ok := claims["role"].(string)
-	if !ok {
-		return "", "", fmt.Errorf("invalid role in token")
+func authenticate(tokens TokenVerifier, sessions SessionValidator, allowEnrollment bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				apperr.Write(w, r, apperr.Unauthenticated("Missing Authorization header"))
+				return
+			}
+
+			tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
+
+			claims, err := tokens.Verify(tokenString, token.PurposeAccess)
+			if err != nil {
+				apperr.Write(w, r, apperr.Unauthenticated("Invalid token"))
+				return
+			}
+
+			if claims.Scope == token.ScopeMFAEnrollment && !allowEnrollment {
+				apperr.Write(w, r, apperr.Forbidden("Two-factor enrollment required"))
+				return
+			}
+
+			active, err := sessions.IsSessionActive(r.Context(), claims.SessionID)
+			if err != nil {
+				apperr.Write(w, r, err)
+				return
+			}
+			if !active {
+				apperr.Write(w, r, apperr.Unauthenticated("Session has been revoked"))
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID())
+			ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
+			ctx = logging.SetUserID(ctx, claims.UserID())
+			r = r.WithContext(ctx)
+
+			next.ServeHTTP(w, r)
+		})
 	}
-
-	return userID, role, nil
 }
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"eventBookingSystem/internal/auth/token"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeVerifier accepts the tokens it knows the claims of.
+type fakeVerifier map[string]*token.Claims
+
+func (v fakeVerifier) Verify(tokenString, purpose string) (*token.Claims, error) {
+	claims, ok := v[tokenString]
+	if !ok || purpose != token.PurposeAccess {
+		return nil, token.ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// fakeSessions reports the sessions it contains as active.
+type fakeSessions struct {
+	active map[string]bool
+	err    error
+}
+
+func (s fakeSessions) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return s.active[sessionID], s.err
+}
+
+func accessClaims(userID, sessionID, scope string) *token.Claims {
+	claims := &token.Claims{Purpose: token.PurposeAccess, SessionID: sessionID, Scope: scope}
+	claims.Subject = userID
+	return claims
+}
+
+// contextHandler writes the user and session it finds in the request context.
+var contextHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDKey).(string)
+	sessionID, _ := r.Context().Value(SessionIDKey).(string)
+	w.Write([]byte(userID + "/" + sessionID))
+})
+
+func TestAuthMiddleware(t *testing.T) {
+	tokens := fakeVerifier{
+		"access":    accessClaims("ada", "s1", ""),
+		"revoked":   accessClaims("ada", "s2", ""),
+		"enrolling": accessClaims("grace", "s3", token.ScopeMFAEnrollment),
+	}
+	sessions := fakeSessions{active: map[string]bool{"s1": true, "s3": true}}
+
+	tests := []struct {
+		name            string
+		header          string
+		sessions        SessionValidator
+		allowEnrollment bool
+		wantStatus      int
+		wantBody        string
+	}{
+		{name: "valid token", header: "Bearer access", wantStatus: http.StatusOK, wantBody: "ada/s1"},
+		{name: "missing header", header: "", wantStatus: http.StatusUnauthorized},
+		{name: "unknown token", header: "Bearer forged", wantStatus: http.StatusUnauthorized},
+		{name: "revoked session", header: "Bearer revoked", wantStatus: http.StatusUnauthorized},
+		{name: "enrollment token", header: "Bearer enrolling", wantStatus: http.StatusForbidden},
+		{name: "enrollment token on enrollment routes", header: "Bearer enrolling", allowEnrollment: true, wantStatus: http.StatusOK, wantBody: "grace/s3"},
+		{
+			name:       "session lookup fails",
+			header:     "Bearer access",
+			sessions:   fakeSessions{err: errors.New("connection refused")},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.sessions == nil {
+				tt.sessions = sessions
+			}
+			middleware := AuthMiddleware(tokens, tt.sessions)
+			if tt.allowEnrollment {
+				middleware = MFAEnrollmentAuthMiddleware(tokens, tt.sessions)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			middleware(contextHandler).ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -1,22 +1,76 @@
 package middleware
 
 import (
-	"log"
+	"eventBookingSystem/internal/logging"
+	"log/slog"
 	"net/http"
 	"time"
 )
 
+// LoggingMiddleware tags every request with an ID, taken from the
+// X-Request-ID header or generated, and echoes it in the response. Handlers
+// get a logger carrying the ID through logging.FromContext, and one access log
+// line is written per request once it has been served.
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Log the request
-		startTime := time.Now()
-		log.Printf("Request: %s %s %s", r.Method, r.URL.Path, r.RemoteAddr)
+		start := time.Now()
 
-		// Call the next handler
-		next.ServeHTTP(w, r)
+		id := logging.RequestIDFromHeader(r.Header.Get(logging.RequestIDHeader))
+		w.Header().Set(logging.RequestIDHeader, id)
 
-		// Log the response time
-		duration := time.Since(startTime)
-		log.Printf("Response: %s %s %s %s", r.Method, r.URL.Path, r.RemoteAddr, duration)
+		ctx, req := logging.NewRequest(r.Context(), id)
+		ctx = logging.With(ctx, "request_id", id)
+		// The ServeMux records the matched route on this request.
+		r = r.WithContext(ctx)
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", r.Pattern,
+			"status", recorder.status,
+			"bytes", recorder.bytes,
+			"duration", time.Since(start),
+			"remote_addr", r.RemoteAddr,
+		}
+		if req.UserID != "" {
+			attrs = append(attrs, "user_id", req.UserID)
+		}
+
+		level := slog.LevelInfo
+		if recorder.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logging.FromContext(ctx).Log(ctx, level, "Request served", attrs...)
 	})
 }
+
+// responseRecorder captures the status code and body size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
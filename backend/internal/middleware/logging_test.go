@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"eventBookingSystem/internal/logging"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggingMiddlewareRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		// wantKept reports whether the ID from the header is used.
+		wantKept bool
+	}{
+		{name: "from header", header: "req-7f3a", wantKept: true},
+		{name: "generated", header: ""},
+		{name: "unprintable header", header: "req 7f3a\n"},
+		{name: "header too long", header: strings.Repeat("a", 129)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			previous := slog.Default()
+			slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+			t.Cleanup(func() { slog.SetDefault(previous) })
+
+			var seen string
+			handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = logging.RequestID(r.Context())
+				ctx := logging.SetUserID(r.Context(), "ada")
+				logging.FromContext(ctx).InfoContext(ctx, "Handled")
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/events", nil)
+			if tt.header != "" {
+				r.Header.Set(logging.RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			id := w.Header().Get(logging.RequestIDHeader)
+			if tt.wantKept && id != tt.header {
+				t.Errorf("request ID = %q, want %q", id, tt.header)
+			}
+			if !tt.wantKept && (len(id) != 32 || id == tt.header) {
+				t.Errorf("request ID = %q, want a generated one", id)
+			}
+			if seen != id {
+				t.Errorf("handler saw request ID %q, response has %q", seen, id)
+			}
+
+			// Both the handler's line and the access log carry the ID and
+			// the user.
+			lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("logged %d lines, want 2:\n%s", len(lines), logs.String())
+			}
+			for _, line := range lines {
+				var record map[string]any
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("decode %s: %v", line, err)
+				}
+				if record["request_id"] != id || record["user_id"] != "ada" {
+					t.Errorf("%s: request_id = %v, user_id = %v, want %s and ada", record["msg"], record["request_id"], record["user_id"], id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"eventBookingSystem/internal/apperr"
+	"net/http"
+)
+
+// EmailVerificationChecker reports whether a user has confirmed their email
+// address.
+type EmailVerificationChecker interface {
+	IsEmailVerified(ctx context.Context, userID string) (bool, error)
+}
+
+// RequireVerifiedEmail creates a middleware that rejects requests from users
+// whose email address is not verified yet.
+func RequireVerifiedEmail(checker EmailVerificationChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := r.Context().Value(UserIDKey).(string)
+			if !ok {
+				apperr.Write(w, r, apperr.Unauthenticated("Missing user"))
+				return
+			}
+
+			verified, err := checker.IsEmailVerified(r.Context(), userID)
+			if err != nil {
+				apperr.Write(w, r, err)
+				return
+			}
+			if !verified {
+				apperr.Write(w, r, apperr.Forbidden("Email address is not verified"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
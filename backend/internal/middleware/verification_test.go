@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	checker := fakeChecker{verified: map[string]bool{"ada": true}}
+
+	tests := []struct {
+		name       string
+		checker    fakeChecker
+		userID     string
+		wantStatus int
+	}{
+		{"verified", checker, "ada", http.StatusOK},
+		{"not verified", checker, "grace", http.StatusForbidden},
+		{"unauthenticated", checker, "", http.StatusUnauthorized},
+		{"lookup fails", fakeChecker{err: errors.New("connection refused")}, "ada", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serveAs(RequireVerifiedEmail(tt.checker)(okHandler), tt.userID); got != tt.wantStatus {
+				t.Errorf("status = %d, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+}
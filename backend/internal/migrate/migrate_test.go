@@ -0,0 +1,400 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"eventBookingSystem/migrations"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// fakeDatabase is just enough of PostgreSQL for the Migrator. It tracks the
+// rows of schema_migrations and the advisory lock, and records every other
+// statement. Statements containing FAIL fail.
+type fakeDatabase struct {
+	mu       sync.Mutex
+	exists   bool
+	applied  map[int64]time.Time
+	executed []string
+	locked   bool
+}
+
+func newFakeDatabase(applied ...int64) *fakeDatabase {
+	db := &fakeDatabase{applied: make(map[int64]time.Time)}
+	for _, version := range applied {
+		db.exists = true
+		db.applied[version] = time.Unix(1700000000, 0)
+	}
+	return db
+}
+
+func (db *fakeDatabase) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeConn{db: db}, nil
+}
+
+func (db *fakeDatabase) Driver() driver.Driver {
+	return nil
+}
+
+func (db *fakeDatabase) appliedVersions() []int64 {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	versions := make([]int64, 0, len(db.applied))
+	for version := range db.applied {
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+	return versions
+}
+
+type fakeConn struct {
+	db *fakeDatabase
+	// rollback restores the state from before the open transaction.
+	rollback func()
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	applied := maps.Clone(c.db.applied)
+	executed := len(c.db.executed)
+	c.rollback = func() {
+		c.db.applied = applied
+		c.db.executed = c.db.executed[:executed]
+	}
+	return fakeTx{c}, nil
+}
+
+type fakeTx struct{ conn *fakeConn }
+
+func (tx fakeTx) Commit() error {
+	tx.conn.rollback = nil
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.conn.db.mu.Lock()
+	defer tx.conn.db.mu.Unlock()
+	tx.conn.rollback()
+	tx.conn.rollback = nil
+	return nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	db := c.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "SELECT pg_advisory_lock"):
+		if db.locked {
+			return nil, errors.New("lock is already held")
+		}
+		db.locked = true
+	case strings.HasPrefix(query, "SELECT pg_advisory_unlock"):
+		db.locked = false
+	case strings.Contains(query, "CREATE TABLE IF NOT EXISTS schema_migrations"):
+		db.exists = true
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations"):
+		db.applied[args[0].Value.(int64)] = args[2].Value.(time.Time)
+	case strings.HasPrefix(query, "DELETE FROM schema_migrations"):
+		delete(db.applied, args[0].Value.(int64))
+	case strings.Contains(query, "FAIL"):
+		return nil, errors.New("syntax error")
+	default:
+		db.executed = append(db.executed, query)
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	db := c.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "SELECT to_regclass"):
+		return &fakeRows{columns: []string{"exists"}, values: [][]driver.Value{{db.exists}}}, nil
+	case strings.HasPrefix(query, "SELECT version, applied_at FROM schema_migrations"):
+		if !db.exists {
+			return nil, errors.New(`relation "schema_migrations" does not exist`)
+		}
+		rows := &fakeRows{columns: []string{"version", "applied_at"}}
+		for version, appliedAt := range db.applied {
+			rows.values = append(rows.values, []driver.Value{version, appliedAt})
+		}
+		return rows, nil
+	}
+	return nil, errors.New("unexpected query: " + query)
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func testMigrations(failing ...int64) []Migration {
+	migrations := []Migration{
+		{Version: 1, Name: "users", Up: "up 1", Down: "down 1"},
+		{Version: 2, Name: "events", Up: "up 2", Down: "down 2"},
+		{Version: 3, Name: "bookings", Up: "up 3", Down: "down 3"},
+	}
+	for i := range migrations {
+		if slices.Contains(failing, migrations[i].Version) {
+			migrations[i].Up = "FAIL"
+			migrations[i].Down = "FAIL"
+		}
+	}
+	return migrations
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		want    []Migration
+		wantErr bool
+	}{
+		{
+			name: "sorted by version",
+			fsys: fstest.MapFS{
+				"10_later.up.sql":   {Data: []byte("up 10")},
+				"10_later.down.sql": {Data: []byte("down 10")},
+				"2_first.up.sql":    {Data: []byte("up 2")},
+				"2_first.down.sql":  {Data: []byte("down 2")},
+				"migrations.go":     {Data: []byte("package migrations")},
+				"README.md":         {Data: []byte("not a migration")},
+			},
+			want: []Migration{
+				{Version: 2, Name: "first", Up: "up 2", Down: "down 2"},
+				{Version: 10, Name: "later", Up: "up 10", Down: "down 10"},
+			},
+		},
+		{
+			name: "missing down file",
+			fsys: fstest.MapFS{
+				"1_users.up.sql": {Data: []byte("up 1")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "two names for a version",
+			fsys: fstest.MapFS{
+				"1_users.up.sql":      {Data: []byte("up 1")},
+				"1_accounts.down.sql": {Data: []byte("down 1")},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.fsys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadEmbeddedMigrations(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, migration := range loaded {
+		if migration.Version != int64(i+1) {
+			t.Errorf("migration %d_%s, want version %d: versions must not have gaps", migration.Version, migration.Name, i+1)
+		}
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	tests := []struct {
+		name    string
+		applied []int64
+		failing []int64
+		run     func(ctx context.Context, m *Migrator) error
+		wantErr error
+		// wantExecuted are the migration statements run, in order.
+		wantExecuted []string
+		wantApplied  []int64
+	}{
+		{
+			name:         "up from an empty database",
+			run:          func(ctx context.Context, m *Migrator) error { return m.Up(ctx) },
+			wantExecuted: []string{"up 1", "up 2", "up 3"},
+			wantApplied:  []int64{1, 2, 3},
+		},
+		{
+			name:         "up applies only pending migrations",
+			applied:      []int64{1},
+			run:          func(ctx context.Context, m *Migrator) error { return m.Up(ctx) },
+			wantExecuted: []string{"up 2", "up 3"},
+			wantApplied:  []int64{1, 2, 3},
+		},
+		{
+			name:         "up when up to date",
+			applied:      []int64{1, 2, 3},
+			run:          func(ctx context.Context, m *Migrator) error { return m.Up(ctx) },
+			wantExecuted: nil,
+			wantApplied:  []int64{1, 2, 3},
+		},
+		{
+			name:         "down reverts the latest first",
+			applied:      []int64{1, 2, 3},
+			run:          func(ctx context.Context, m *Migrator) error { return m.Down(ctx, 2) },
+			wantExecuted: []string{"down 3", "down 2"},
+			wantApplied:  []int64{1},
+		},
+		{
+			name:         "down skips pending migrations",
+			applied:      []int64{1, 2},
+			run:          func(ctx context.Context, m *Migrator) error { return m.Down(ctx, 1) },
+			wantExecuted: []string{"down 2"},
+			wantApplied:  []int64{1},
+		},
+		{
+			name:         "to an older version",
+			applied:      []int64{1, 2, 3},
+			run:          func(ctx context.Context, m *Migrator) error { return m.To(ctx, 1) },
+			wantExecuted: []string{"down 3", "down 2"},
+			wantApplied:  []int64{1},
+		},
+		{
+			name:         "to version 0 reverts everything",
+			applied:      []int64{1, 2, 3},
+			run:          func(ctx context.Context, m *Migrator) error { return m.To(ctx, 0) },
+			wantExecuted: []string{"down 3", "down 2", "down 1"},
+			wantApplied:  []int64{},
+		},
+		{
+			name:         "to fills a gap",
+			applied:      []int64{1, 3},
+			run:          func(ctx context.Context, m *Migrator) error { return m.To(ctx, 3) },
+			wantExecuted: []string{"up 2"},
+			wantApplied:  []int64{1, 2, 3},
+		},
+		{
+			name:         "to an unknown version",
+			applied:      []int64{1},
+			run:          func(ctx context.Context, m *Migrator) error { return m.To(ctx, 7) },
+			wantErr:      ErrUnknownVersion,
+			wantExecuted: nil,
+			wantApplied:  []int64{1},
+		},
+		{
+			name:         "failing migration stops and is not recorded",
+			failing:      []int64{2},
+			run:          func(ctx context.Context, m *Migrator) error { return m.Up(ctx) },
+			wantErr:      errAny,
+			wantExecuted: []string{"up 1"},
+			wantApplied:  []int64{1},
+		},
+		{
+			name:         "failing revert stays applied",
+			applied:      []int64{1, 2, 3},
+			failing:      []int64{2},
+			run:          func(ctx context.Context, m *Migrator) error { return m.Down(ctx, 3) },
+			wantErr:      errAny,
+			wantExecuted: []string{"down 3"},
+			wantApplied:  []int64{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDatabase(tt.applied...)
+			conn := sql.OpenDB(db)
+			defer conn.Close()
+			migrator := NewMigrator(conn, testMigrations(tt.failing...))
+
+			err := tt.run(t.Context(), migrator)
+			switch {
+			case tt.wantErr == errAny && err == nil:
+				t.Fatal("migrating succeeded, want an error")
+			case tt.wantErr != errAny && !errors.Is(err, tt.wantErr):
+				t.Fatalf("migrating = %v, want %v", err, tt.wantErr)
+			}
+
+			if !slices.Equal(db.executed, tt.wantExecuted) {
+				t.Errorf("executed %q, want %q", db.executed, tt.wantExecuted)
+			}
+			if applied := db.appliedVersions(); !slices.Equal(applied, tt.wantApplied) {
+				t.Errorf("applied %v, want %v", applied, tt.wantApplied)
+			}
+			if db.locked {
+				t.Error("migration lock is still held")
+			}
+		})
+	}
+}
+
+// errAny stands for any error in TestMigrator.
+var errAny = errors.New("any error")
+
+func TestMigratorStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		db          *fakeDatabase
+		wantApplied []bool
+	}{
+		{"before the first migration", newFakeDatabase(), []bool{false, false, false}},
+		{"partly migrated", newFakeDatabase(1, 2), []bool{true, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := sql.OpenDB(tt.db)
+			defer conn.Close()
+
+			statuses, err := NewMigrator(conn, testMigrations()).Status(t.Context())
+			if err != nil {
+				t.Fatalf("Status: %v", err)
+			}
+
+			applied := make([]bool, len(statuses))
+			for i, status := range statuses {
+				applied[i] = status.AppliedAt != nil
+			}
+			if !slices.Equal(applied, tt.wantApplied) {
+				t.Errorf("applied %v, want %v", applied, tt.wantApplied)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package notify
+
+import (
+	"log/slog"
+)
+
+// LogNotifier writes messages to a log instead of delivering them. It is
+// meant for local development, where the log can be read to follow links
+// that would otherwise be emailed.
+type LogNotifier struct {
+	Logger *slog.Logger
+}
+
+func NewLogNotifier(logger *slog.Logger) *LogNotifier {
+	return &LogNotifier{Logger: logger}
+}
+
+func (n *LogNotifier) Send(message Message) error {
+	n.Logger.Info("Notification", "to", message.To, "subject", message.Subject, "body", message.Body)
+	return nil
+}
@@ -0,0 +1,13 @@
+package notify
+
+// Message is a notification addressed to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to users, e.g. by email.
+type Notifier interface {
+	Send(message Message) error
+}
@@ -0,0 +1,126 @@
+// Package ratelimit throttles clients with token buckets and locks accounts
+// out after repeated failed logins.
+//
+// State lives in a Store: MemoryStore keeps it in the process, PostgresStore
+// shares it between replicas.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows bursts of up to Burst requests and refills the bucket at Burst
+// requests per Period. The zero Limit is unlimited.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// Unlimited reports whether the limit lets every request through.
+func (l Limit) Unlimited() bool {
+	return l.Burst <= 0 || l.Period <= 0
+}
+
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "off"
+	}
+
+	period := l.Period.String()
+	switch l.Period {
+	case time.Second:
+		period = "s"
+	case time.Minute:
+		period = "m"
+	case time.Hour:
+		period = "h"
+	}
+	return fmt.Sprintf("%d/%s", l.Burst, period)
+}
+
+// MarshalText formats the limit like ParseLimit accepts it, so limits can be
+// written in config files.
+func (l Limit) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+func (l *Limit) UnmarshalText(text []byte) error {
+	parsed, err := ParseLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+// ParseLimit parses limits such as "10/m", "100/s", "5/h" or "20/15m".
+// "off" disables the limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" {
+		return Limit{}, nil
+	}
+
+	count, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit %q, want <count>/<period>", s)
+	}
+
+	burst, err := strconv.Atoi(count)
+	if err != nil || burst <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q, count must be a positive integer", s)
+	}
+
+	var duration time.Duration
+	switch period {
+	case "s":
+		duration = time.Second
+	case "m":
+		duration = time.Minute
+	case "h":
+		duration = time.Hour
+	default:
+		duration, err = time.ParseDuration(period)
+		if err != nil || duration <= 0 {
+			return Limit{}, fmt.Errorf("invalid limit %q, period must be s, m, h or a positive duration", s)
+		}
+	}
+
+	return Limit{Burst: burst, Period: duration}, nil
+}
+
+// bucket is the state of a token bucket.
+type bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// take refills the bucket up to now and takes a token. It returns 0 if a
+// token was taken, otherwise how long until the next one is available.
+func (b *bucket) take(limit Limit, now time.Time) time.Duration {
+	rate := float64(limit.Burst) / limit.Period.Seconds()
+
+	elapsed := now.Sub(b.UpdatedAt).Seconds()
+	if elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Burst), b.Tokens+elapsed*rate)
+		b.UpdatedAt = now
+	}
+
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return 0
+	}
+	return time.Duration((1 - b.Tokens) / rate * float64(time.Second))
+}
+
+// fullAt returns when the bucket will be full again, after which its state
+// can be dropped.
+func (b *bucket) fullAt(limit Limit) time.Time {
+	rate := float64(limit.Burst) / limit.Period.Seconds()
+	missing := float64(limit.Burst) - b.Tokens
+	return b.UpdatedAt.Add(time.Duration(missing / rate * float64(time.Second)))
+}
@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{in: "10/m", want: Limit{Burst: 10, Period: time.Minute}},
+		{in: "100/s", want: Limit{Burst: 100, Period: time.Second}},
+		{in: " 5/h ", want: Limit{Burst: 5, Period: time.Hour}},
+		{in: "20/15m", want: Limit{Burst: 20, Period: 15 * time.Minute}},
+		{in: "off", want: Limit{}},
+		{in: "10", wantErr: true},
+		{in: "0/m", wantErr: true},
+		{in: "-1/m", wantErr: true},
+		{in: "ten/m", wantErr: true},
+		{in: "10/fortnight", wantErr: true},
+		{in: "10/-1m", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLimit(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitText(t *testing.T) {
+	for _, in := range []string{"10/m", "100/s", "5/h", "20/15m0s", "off"} {
+		var limit Limit
+		if err := limit.UnmarshalText([]byte(in)); err != nil {
+			t.Fatalf("UnmarshalText(%q): %v", in, err)
+		}
+		text, _ := limit.MarshalText()
+		if string(text) != in {
+			t.Errorf("MarshalText after UnmarshalText(%q) = %q", in, text)
+		}
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	limit := Limit{Burst: 3, Period: 3 * time.Second}
+	start := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name string
+		// offsets are the times of the requests after start.
+		offsets []time.Duration
+		// want is the wait returned for each request.
+		want []time.Duration
+	}{
+		{
+			name:    "burst then wait for a refill",
+			offsets: []time.Duration{0, 0, 0, 0},
+			want:    []time.Duration{0, 0, 0, time.Second},
+		},
+		{
+			name:    "refills one token per second",
+			offsets: []time.Duration{0, 0, 0, time.Second, time.Second},
+			want:    []time.Duration{0, 0, 0, 0, time.Second},
+		},
+		{
+			name:    "waits for the remaining part of a token",
+			offsets: []time.Duration{0, 0, 0, 250 * time.Millisecond},
+			want:    []time.Duration{0, 0, 0, 750 * time.Millisecond},
+		},
+		{
+			name:    "does not refill beyond the burst",
+			offsets: []time.Duration{0, time.Hour, time.Hour, time.Hour, time.Hour},
+			want:    []time.Duration{0, 0, 0, 0, time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			for i, offset := range tt.offsets {
+				wait, err := store.Take(t.Context(), "key", limit, start.Add(offset))
+				if err != nil {
+					t.Fatalf("Take: %v", err)
+				}
+				if wait != tt.want[i] {
+					t.Errorf("request %d at +%s waits %s, want %s", i, offset, wait, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreKeysAreSeparate(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Burst: 1, Period: time.Minute}
+	now := time.Now()
+
+	if wait, _ := store.Take(t.Context(), "a", limit, now); wait != 0 {
+		t.Fatalf("first take of a waits %s", wait)
+	}
+	if wait, _ := store.Take(t.Context(), "b", limit, now); wait != 0 {
+		t.Errorf("b waits %s after a emptied its bucket", wait)
+	}
+}
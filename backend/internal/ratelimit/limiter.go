@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"eventBookingSystem/internal/apperr"
+	"eventBookingSystem/internal/logging"
+	"eventBookingSystem/internal/middleware"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Error rejects a request that exceeded a rate limit or hit a lockout.
+type Error struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s, try again in %s", e.Message, e.RetryAfter.Round(time.Second))
+}
+
+func (e *Error) ErrorCode() apperr.Code {
+	return apperr.CodeTooManyRequests
+}
+
+func (e *Error) RetryDelay() time.Duration {
+	return e.RetryAfter
+}
+
+// KeyFunc returns the key a request is limited by, or false if the rule does
+// not apply to the request.
+type KeyFunc func(r *http.Request) (string, bool)
+
+// Rule limits the requests that share a key. The Name keeps the buckets of
+// rules with the same key apart.
+type Rule struct {
+	Name  string
+	Key   KeyFunc
+	Limit Limit
+}
+
+// Limiter enforces rules with token buckets kept in Store.
+type Limiter struct {
+	Store Store
+	Now   func() time.Time
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{Store: store, Now: time.Now}
+}
+
+// Middleware creates a middleware that rejects requests exceeding any of the
+// rules with 429 Too Many Requests and a Retry-After header. If the store
+// fails, requests are let through and the error is logged.
+func (l *Limiter) Middleware(rules ...Rule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, rule := range rules {
+				if rule.Limit.Unlimited() {
+					continue
+				}
+				key, ok := rule.Key(r)
+				if !ok {
+					continue
+				}
+
+				wait, err := l.Store.Take(r.Context(), rule.Name+":"+key, rule.Limit, l.Now())
+				if err != nil {
+					logging.FromContext(r.Context()).ErrorContext(r.Context(), "Rate limiter failed", "rule", rule.Name, "error", err)
+					continue
+				}
+				if wait > 0 {
+					logging.FromContext(r.Context()).WarnContext(r.Context(), "Rate limit exceeded", "rule", rule.Name)
+					apperr.Write(w, r, &Error{Message: "Too many requests", RetryAfter: wait})
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ByIP keys requests by client IP. IPv6 clients are keyed by their /64
+// network, since a single client usually controls all of it. With
+// trustProxy the client IP is taken from the last X-Forwarded-For entry,
+// which must then be set by a proxy in front of the server.
+func ByIP(trustProxy bool) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		addr := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			addr = host
+		}
+		if trustProxy {
+			if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+				entries := strings.Split(forwarded[len(forwarded)-1], ",")
+				addr = strings.TrimSpace(entries[len(entries)-1])
+			}
+		}
+
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return addr, addr != ""
+		}
+		if ip.To4() == nil {
+			ip = ip.Mask(net.CIDRMask(64, 128))
+		}
+		return ip.String(), true
+	}
+}
+
+// ByUser keys requests by the authenticated user. It must run after the
+// authentication middleware.
+func ByUser(r *http.Request) (string, bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	return userID, ok && userID != ""
+}
+
+// maxPeekBytes bounds how much of a body ByJSONField reads, matching the
+// limit request.Decode enforces later.
+const maxPeekBytes = 1 << 20
+
+// ByJSONField keys requests by a string field of their JSON body, compared
+// case-insensitively, such as the email of a login. The body is restored for
+// the handler.
+func ByJSONField(field string) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		if r.Body == nil {
+			return "", false
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxPeekBytes))
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		if err != nil {
+			return "", false
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return "", false
+		}
+		var value string
+		if err := json.Unmarshal(fields[field], &value); err != nil {
+			return "", false
+		}
+
+		value = strings.ToLower(strings.TrimSpace(value))
+		return value, value != ""
+	}
+}
@@ -0,0 +1,159 @@
+package ratelimit
+
+import (
+	"context"
+	"eventBookingSystem/internal/middleware"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimiterMiddleware(t *testing.T) {
+	perIP := Rule{Name: "ip", Key: ByIP(false), Limit: Limit{Burst: 2, Period: time.Minute}}
+	perEmail := Rule{Name: "email", Key: ByJSONField("email"), Limit: Limit{Burst: 1, Period: time.Minute}}
+
+	type request struct {
+		remoteAddr string
+		body       string
+	}
+	tests := []struct {
+		name     string
+		store    Store
+		rules    []Rule
+		requests []request
+		// want is the status of each request.
+		want []int
+	}{
+		{
+			name:     "rejects requests over the limit",
+			rules:    []Rule{perIP},
+			requests: []request{{"192.0.2.1:1000", ""}, {"192.0.2.1:1001", ""}, {"192.0.2.1:1002", ""}},
+			want:     []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:     "limits clients separately",
+			rules:    []Rule{perIP},
+			requests: []request{{"192.0.2.1:1000", ""}, {"192.0.2.1:1000", ""}, {"192.0.2.2:1000", ""}},
+			want:     []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+		{
+			name:     "limits an IPv6 /64 together",
+			rules:    []Rule{perIP},
+			requests: []request{{"[2001:db8::1]:1000", ""}, {"[2001:db8::2]:1000", ""}, {"[2001:db8::3]:1000", ""}},
+			want:     []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:  "applies every rule",
+			rules: []Rule{perIP, perEmail},
+			requests: []request{
+				{"192.0.2.1:1000", `{"email":"ada@example.com"}`},
+				{"192.0.2.2:1000", `{"email":" ADA@example.com "}`},
+				{"192.0.2.3:1000", `{"email":"grace@example.com"}`},
+			},
+			want: []int{http.StatusOK, http.StatusTooManyRequests, http.StatusOK},
+		},
+		{
+			name:     "skips rules without a key",
+			rules:    []Rule{perEmail},
+			requests: []request{{"192.0.2.1:1000", `{}`}, {"192.0.2.1:1000", `not json`}},
+			want:     []int{http.StatusOK, http.StatusOK},
+		},
+		{
+			name:     "skips unlimited rules",
+			rules:    []Rule{{Name: "off", Key: ByIP(false)}},
+			requests: []request{{"192.0.2.1:1000", ""}, {"192.0.2.1:1000", ""}},
+			want:     []int{http.StatusOK, http.StatusOK},
+		},
+		{
+			name:     "lets requests through when the store fails",
+			store:    failingStore{},
+			rules:    []Rule{perIP},
+			requests: []request{{"192.0.2.1:1000", ""}, {"192.0.2.1:1000", ""}, {"192.0.2.1:1000", ""}},
+			want:     []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.store
+			if store == nil {
+				store = NewMemoryStore()
+			}
+			limiter := NewLimiter(store)
+			now := time.Now()
+			limiter.Now = func() time.Time { return now }
+
+			handler := limiter.Middleware(tt.rules...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// The handler still sees the body the limiter peeked at.
+				body, _ := io.ReadAll(r.Body)
+				w.Write(body)
+			}))
+
+			for i, req := range tt.requests {
+				r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(req.body))
+				r.RemoteAddr = req.remoteAddr
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+
+				if w.Code != tt.want[i] {
+					t.Fatalf("request %d: status %d, want %d", i, w.Code, tt.want[i])
+				}
+				switch w.Code {
+				case http.StatusOK:
+					if w.Body.String() != req.body {
+						t.Errorf("request %d: handler read body %q, want %q", i, w.Body.String(), req.body)
+					}
+				case http.StatusTooManyRequests:
+					if w.Header().Get("Retry-After") == "" {
+						t.Errorf("request %d: no Retry-After header", i)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestByIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{name: "IPv4", remoteAddr: "192.0.2.1:1234", want: "192.0.2.1"},
+		{name: "IPv6 masked to /64", remoteAddr: "[2001:db8:1:2:3:4:5:6]:1234", want: "2001:db8:1:2::"},
+		{name: "ignores X-Forwarded-For by default", remoteAddr: "192.0.2.1:1234", forwarded: []string{"198.51.100.1"}, want: "192.0.2.1"},
+		{name: "last X-Forwarded-For entry behind a proxy", trustProxy: true, remoteAddr: "10.0.0.1:1234", forwarded: []string{"203.0.113.9, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "last X-Forwarded-For header behind a proxy", trustProxy: true, remoteAddr: "10.0.0.1:1234", forwarded: []string{"203.0.113.9", "198.51.100.1"}, want: "198.51.100.1"},
+		{name: "remote address without X-Forwarded-For", trustProxy: true, remoteAddr: "10.0.0.1:1234", want: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			got, ok := ByIP(tt.trustProxy)(r)
+			if !ok || got != tt.want {
+				t.Errorf("ByIP = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestByUser(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := ByUser(r); ok {
+		t.Error("ByUser applies to an anonymous request")
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, "ada"))
+	if got, ok := ByUser(r); !ok || got != "ada" {
+		t.Errorf("ByUser = %q, %v, want ada", got, ok)
+	}
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"eventBookingSystem/internal/logging"
+	"time"
+)
+
+// Lockout locks a key, such as an account, after Threshold consecutive
+// failures. The first lock lasts BaseDelay and every further failure doubles
+// it, up to MaxDelay. Failures are forgotten after a success or once Window
+// has passed without another one.
+//
+// If the store fails, the error is logged and the key is treated as not
+// locked, so an outage of the store does not lock everybody out.
+type Lockout struct {
+	Store     Store
+	Threshold int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Window    time.Duration
+	Now       func() time.Time
+}
+
+func NewLockout(store Store, threshold int, baseDelay, maxDelay, window time.Duration) *Lockout {
+	return &Lockout{
+		Store:     store,
+		Threshold: threshold,
+		BaseDelay: baseDelay,
+		MaxDelay:  maxDelay,
+		Window:    max(window, maxDelay),
+		Now:       time.Now,
+	}
+}
+
+// Check returns an *Error while key is locked.
+func (l *Lockout) Check(ctx context.Context, key string) error {
+	if l.Threshold <= 0 {
+		return nil
+	}
+
+	now := l.Now()
+	failures, err := l.Store.Failures(ctx, "lockout:"+key, now)
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Failed to check lockout", "error", err)
+		return nil
+	}
+
+	if failures.Count < l.Threshold {
+		return nil
+	}
+	if wait := failures.Last.Add(l.delay(failures.Count)).Sub(now); wait > 0 {
+		return &Error{Message: "Too many failed attempts", RetryAfter: wait}
+	}
+	return nil
+}
+
+// Fail records a failed attempt for key.
+func (l *Lockout) Fail(ctx context.Context, key string) {
+	if l.Threshold <= 0 {
+		return
+	}
+
+	failures, err := l.Store.Fail(ctx, "lockout:"+key, l.Now(), l.Window)
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Failed to record failed attempt", "error", err)
+		return
+	}
+	if failures.Count >= l.Threshold {
+		logging.FromContext(ctx).WarnContext(ctx, "Locked out after repeated failures",
+			"failures", failures.Count, "duration", l.delay(failures.Count))
+	}
+}
+
+// Reset forgets the failures of key after a successful attempt.
+func (l *Lockout) Reset(ctx context.Context, key string) {
+	if l.Threshold <= 0 {
+		return
+	}
+
+	if err := l.Store.Reset(ctx, "lockout:"+key); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Failed to reset lockout", "error", err)
+	}
+}
+
+// delay returns how long a key is locked after count failures.
+func (l *Lockout) delay(count int) time.Duration {
+	delay := l.BaseDelay
+	for i := l.Threshold; i < count && delay < l.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, l.MaxDelay)
+}
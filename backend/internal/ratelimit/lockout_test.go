@@ -0,0 +1,187 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// clock is a settable time source for Lockout.Now and Limiter.Now.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func (c *clock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// failingStore fails every operation, like an unreachable database.
+type failingStore struct{}
+
+var errStore = errors.New("store unavailable")
+
+func (failingStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (time.Duration, error) {
+	return 0, errStore
+}
+
+func (failingStore) Fail(ctx context.Context, key string, now time.Time, window time.Duration) (Failures, error) {
+	return Failures{}, errStore
+}
+
+func (failingStore) Failures(ctx context.Context, key string, now time.Time) (Failures, error) {
+	return Failures{}, errStore
+}
+
+func (failingStore) Reset(ctx context.Context, key string) error {
+	return errStore
+}
+
+func newTestLockout(threshold int) (*Lockout, *clock) {
+	c := &clock{now: time.Unix(1700000000, 0)}
+	lockout := NewLockout(NewMemoryStore(), threshold, time.Second, 8*time.Second, time.Minute)
+	lockout.Now = c.Now
+	return lockout, c
+}
+
+func TestLockoutDelay(t *testing.T) {
+	lockout, _ := newTestLockout(3)
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 8 * time.Second},
+		{7, 8 * time.Second},
+		{100, 8 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := lockout.delay(tt.failures); got != tt.want {
+			t.Errorf("delay(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLockout(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		// steps run against the lockout before the final check.
+		steps    func(ctx context.Context, lockout *Lockout, c *clock)
+		wantWait time.Duration
+	}{
+		{
+			name:      "below the threshold",
+			threshold: 3,
+			steps: func(ctx context.Context, lockout *Lockout, c *clock) {
+				lockout.Fail(ctx, "ada")
+				lockout.Fail(ctx, "ada")
+			},
+		},
+		{
+			name:      "locked at the threshold",
+			threshold: 3,
+			steps: func(ctx context.Context, lockout *Lockout, c *clock) {
+				for range 3 {
+					lockout.Fail(ctx, "ada")
+				}
+			},
+			wantWait: time.Second,
+		},
+		{
+			name:      "lock runs out",
+			threshold: 3,
+			steps: func(ctx context.Context, lockout *Lockout, c *clock) {
+				for range 3 {
+					lockout.Fail(ctx, "ada")
+				}
+				c.advance(time.Second)
+			},
+		},
+		{
+			name:      "failure after the lock doubles it",
+			threshold: 3,
+			steps: func(ctx context.Context, lockout *Lockout, c *clock) {
+				for range 3 {
+					lockout.Fail(ctx, "ada")
+				}
+				c.advance(time.Second)
+				lockout.Fail(ctx, "ada")
+			},
+			wantWait: 2 * time.Second,
+		},
+		{
+			name:      "success resets the failures",
+			threshold: 3,
+			steps: func(ctx context.Context, lockout *Lockout, c *clock) {
+				lockout.Fail(ctx, "ada")
+				lockout.Fail(ctx, "ada")
+				lockout.Reset(ctx, "ada")
+				lockout.Fail(ctx, "ada")
+			},
+		},
+		{
+			name:      "failures are forgotten after the window",
+			threshold: 3,
+			steps: func(ctx context.Context, lockout *Lockout, c *clock) {
+				lockout.Fail(ctx, "ada")
+				lockout.Fail(ctx, "ada")
+				c.advance(time.Minute)
+				lockout.Fail(ctx, "ada")
+			},
+		},
+		{
+			name:      "other keys are not locked",
+			threshold: 3,
+			steps: func(ctx context.Context, lockout *Lockout, c *clock) {
+				for range 3 {
+					lockout.Fail(ctx, "grace")
+				}
+			},
+		},
+		{
+			name:      "threshold 0 disables the lockout",
+			threshold: 0,
+			steps: func(ctx context.Context, lockout *Lockout, c *clock) {
+				for range 100 {
+					lockout.Fail(ctx, "ada")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+			lockout, c := newTestLockout(tt.threshold)
+			tt.steps(ctx, lockout, c)
+
+			err := lockout.Check(ctx, "ada")
+			if tt.wantWait == 0 {
+				if err != nil {
+					t.Fatalf("Check = %v, want no lock", err)
+				}
+				return
+			}
+
+			var lockErr *Error
+			if !errors.As(err, &lockErr) {
+				t.Fatalf("Check = %v, want *Error", err)
+			}
+			if lockErr.RetryAfter != tt.wantWait {
+				t.Errorf("RetryAfter = %s, want %s", lockErr.RetryAfter, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestLockoutStoreFailure(t *testing.T) {
+	ctx := t.Context()
+	lockout := NewLockout(failingStore{}, 1, time.Second, time.Minute, time.Minute)
+
+	lockout.Fail(ctx, "ada")
+	lockout.Reset(ctx, "ada")
+	if err := lockout.Check(ctx, "ada"); err != nil {
+		t.Errorf("Check with a failing store = %v, want no lock", err)
+	}
+}
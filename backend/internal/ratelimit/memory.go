@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps limiter state in the process. Each replica limits on its
+// own, so the effective limit is multiplied by the number of replicas.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	failures  map[string]*memoryFailures
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	expiresAt time.Time
+}
+
+type memoryFailures struct {
+	Failures
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:  make(map[string]*memoryBucket),
+		failures: make(map[string]*memoryFailures),
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{bucket: bucket{Tokens: float64(limit.Burst), UpdatedAt: now}}
+		s.buckets[key] = b
+	}
+
+	wait := b.take(limit, now)
+	b.expiresAt = b.fullAt(limit)
+	return wait, nil
+}
+
+func (s *MemoryStore) Fail(ctx context.Context, key string, now time.Time, window time.Duration) (Failures, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	f, ok := s.failures[key]
+	if !ok || !now.Before(f.expiresAt) {
+		f = &memoryFailures{}
+		s.failures[key] = f
+	}
+
+	f.Count++
+	f.Last = now
+	f.expiresAt = now.Add(window)
+	return f.Failures, nil
+}
+
+func (s *MemoryStore) Failures(ctx context.Context, key string, now time.Time) (Failures, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.failures[key]
+	if !ok || !now.Before(f.expiresAt) {
+		return Failures{}, nil
+	}
+	return f.Failures, nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, key)
+	return nil
+}
+
+// sweep drops full buckets and forgotten failures so the maps do not grow
+// with every client ever seen. The caller must hold s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !now.Before(b.expiresAt) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, f := range s.failures {
+		if !now.Before(f.expiresAt) {
+			delete(s.failures, key)
+		}
+	}
+}
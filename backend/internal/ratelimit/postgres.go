@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostgresStore keeps limiter state in the rate_limit_buckets and
+// rate_limit_failures tables, so all replicas share the same limits.
+type PostgresStore struct {
+	DB *gorm.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{DB: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (time.Duration, error) {
+	s.sweep(ctx, now)
+
+	var wait time.Duration
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The no-op update locks an existing row for the transaction.
+		var b bucket
+		err := tx.Raw(`
+			INSERT INTO rate_limit_buckets (key, tokens, updated_at, expires_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key
+			RETURNING tokens, updated_at`,
+			key, float64(limit.Burst), now, now,
+		).Scan(&b).Error
+		if err != nil {
+			return err
+		}
+
+		wait = b.take(limit, now)
+		return tx.Exec(
+			"UPDATE rate_limit_buckets SET tokens = ?, updated_at = ?, expires_at = ? WHERE key = ?",
+			b.Tokens, b.UpdatedAt, b.fullAt(limit), key,
+		).Error
+	})
+	return wait, err
+}
+
+func (s *PostgresStore) Fail(ctx context.Context, key string, now time.Time, window time.Duration) (Failures, error) {
+	var f Failures
+	err := s.DB.WithContext(ctx).Raw(`
+		INSERT INTO rate_limit_failures (key, count, last_failure_at, expires_at)
+		VALUES (?, 1, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE
+				WHEN rate_limit_failures.expires_at <= EXCLUDED.last_failure_at THEN 1
+				ELSE rate_limit_failures.count + 1
+			END,
+			last_failure_at = EXCLUDED.last_failure_at,
+			expires_at = EXCLUDED.expires_at
+		RETURNING count, last_failure_at AS last`,
+		key, now, now.Add(window),
+	).Scan(&f).Error
+	return f, err
+}
+
+func (s *PostgresStore) Failures(ctx context.Context, key string, now time.Time) (Failures, error) {
+	var f Failures
+	err := s.DB.WithContext(ctx).Raw(
+		"SELECT count, last_failure_at AS last FROM rate_limit_failures WHERE key = ? AND expires_at > ?",
+		key, now,
+	).Scan(&f).Error
+	return f, err
+}
+
+func (s *PostgresStore) Reset(ctx context.Context, key string) error {
+	return s.DB.WithContext(ctx).Exec("DELETE FROM rate_limit_failures WHERE key = ?", key).Error
+}
+
+// sweep deletes full buckets and forgotten failures, at most once per
+// sweepInterval per replica. Failing to sweep only leaves stale rows behind.
+func (s *PostgresStore) sweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	db := s.DB.WithContext(ctx)
+	db.Exec("DELETE FROM rate_limit_buckets WHERE expires_at <= ?", now)
+	db.Exec("DELETE FROM rate_limit_failures WHERE expires_at <= ?", now)
+}
@@ -0,0 +1,35 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Store keeps the state of token buckets and failure counters. It must be
+// safe for concurrent use.
+type Store interface {
+	// Take takes a token from the bucket key, which holds limit.Burst tokens
+	// when first used. It returns 0 if a token was taken, otherwise how long
+	// until the next one is available.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (time.Duration, error)
+
+	// Fail records a failure for key and returns the failures so far. Earlier
+	// failures are forgotten once window has passed without another one.
+	Fail(ctx context.Context, key string, now time.Time, window time.Duration) (Failures, error)
+
+	// Failures returns the failures recorded for key that are not forgotten
+	// yet.
+	Failures(ctx context.Context, key string, now time.Time) (Failures, error)
+
+	// Reset forgets the failures of key.
+	Reset(ctx context.Context, key string) error
+}
+
+// Failures counts consecutive failures for a key.
+type Failures struct {
+	Count int
+	Last  time.Time
+}
+
+// sweepInterval is how often stores drop state that has run out.
+const sweepInterval = time.Minute
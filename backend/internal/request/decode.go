@@ -0,0 +1,106 @@
+// Package request decodes and validates JSON request bodies.
+//
+// Request bodies are DTOs that describe their own rules by implementing
+// Validatable:
+//
+//	func (r *CreateUserRequest) Validate(v *request.Validator) {
+//		v.Required("username", r.Username)
+//		v.MaxLength("username", r.Username, 50)
+//	}
+//
+// Decode runs the rules after decoding and reports every broken rule in a
+// single validation error.
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"eventBookingSystem/internal/apperr"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// MaxBodyBytes is the largest request body Decode accepts.
+const MaxBodyBytes = 1 << 20
+
+// Normalizer is implemented by request bodies that clean up their input,
+// such as trimming whitespace, before they are validated.
+type Normalizer interface {
+	Normalize()
+}
+
+// Validatable is implemented by request bodies with validation rules.
+type Validatable interface {
+	Validate(v *Validator)
+}
+
+// Decode reads the JSON body of r into dst, rejecting unknown fields,
+// trailing data and bodies over MaxBodyBytes. The decoded value is then
+// normalized and validated if it implements Normalizer or Validatable.
+func Decode(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return decodeError(err)
+		}
+		return apperr.BadRequest("Request body must contain a single JSON object")
+	}
+
+	if normalizer, ok := dst.(Normalizer); ok {
+		normalizer.Normalize()
+	}
+
+	if validatable, ok := dst.(Validatable); ok {
+		var v Validator
+		validatable.Validate(&v)
+		return v.Err()
+	}
+
+	return nil
+}
+
+func decodeError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return apperr.BadRequest("Request body is required")
+	case errors.As(err, &maxBytesErr):
+		return apperr.New(apperr.CodePayloadTooLarge, fmt.Sprintf("Request body must not exceed %d bytes", maxBytesErr.Limit))
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return apperr.Field(typeErr.Field, "Must be "+describeType(typeErr.Type))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return apperr.Field(field, "Unknown field")
+	default:
+		return apperr.BadRequest("Invalid request body")
+	}
+}
+
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,126 @@
+package request
+
+import (
+	"errors"
+	"eventBookingSystem/internal/apperr"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testRequest struct {
+	Name   string   `json:"name"`
+	Email  string   `json:"email"`
+	Seats  int      `json:"seats"`
+	Date   string   `json:"date"`
+	Emails []string `json:"emails"`
+}
+
+func (r *testRequest) Normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+}
+
+func (r *testRequest) Validate(v *Validator) {
+	v.Required("name", r.Name)
+	v.Length("name", r.Name, 3, 10)
+	v.Email("email", r.Email)
+	v.Range("seats", r.Seats, 1, 10)
+	v.Future("date", v.Time("date", r.Date))
+	v.Each("emails", r.Emails, v.Email)
+}
+
+func TestDecode(t *testing.T) {
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name       string
+		body       string
+		want       testRequest
+		wantCode   apperr.Code
+		wantFields map[string]string
+	}{
+		{
+			name: "valid",
+			body: `{"name": " Ada ", "email": "ada@example.com", "seats": 2, "date": "` + future + `"}`,
+			want: testRequest{Name: "Ada", Email: "ada@example.com", Seats: 2, Date: future},
+		},
+		{
+			name:     "empty body",
+			body:     "",
+			wantCode: apperr.CodeBadRequest,
+		},
+		{
+			name:     "malformed JSON",
+			body:     `{"name": `,
+			wantCode: apperr.CodeBadRequest,
+		},
+		{
+			name:     "two objects",
+			body:     `{"name": "Ada", "seats": 1} {}`,
+			wantCode: apperr.CodeBadRequest,
+		},
+		{
+			name:       "unknown field",
+			body:       `{"name": "Ada", "seats": 1, "admin": true}`,
+			wantCode:   apperr.CodeValidation,
+			wantFields: map[string]string{"admin": "Unknown field"},
+		},
+		{
+			name:       "wrong type",
+			body:       `{"name": "Ada", "seats": "two"}`,
+			wantCode:   apperr.CodeValidation,
+			wantFields: map[string]string{"seats": "Must be an integer"},
+		},
+		{
+			name:     "too large",
+			body:     `{"name": "` + strings.Repeat("a", MaxBodyBytes) + `"}`,
+			wantCode: apperr.CodePayloadTooLarge,
+		},
+		{
+			name:     "every broken rule at once",
+			body:     `{"name": "  ", "email": "Ada <ada@example.com>", "seats": 0, "date": "2001-01-01T00:00:00Z", "emails": ["ok@example.com", "nope"]}`,
+			wantCode: apperr.CodeValidation,
+			wantFields: map[string]string{
+				"name":      "Is required",
+				"email":     "Must be a valid email address",
+				"seats":     "Must be between 1 and 10",
+				"date":      "Must be in the future",
+				"emails[1]": "Must be a valid email address",
+			},
+		},
+		{
+			name:       "first failed rule of a field wins",
+			body:       `{"name": "Ad", "seats": 1, "date": "tomorrow"}`,
+			wantCode:   apperr.CodeValidation,
+			wantFields: map[string]string{"name": "Must be at least 3 characters", "date": "Must be an RFC3339 timestamp"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			var got testRequest
+			err := Decode(httptest.NewRecorder(), r, &got)
+
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("Decode: %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Decode = %+v, want %+v", got, tt.want)
+				}
+				return
+			}
+
+			var appErr *apperr.Error
+			if !errors.As(err, &appErr) || appErr.Code != tt.wantCode {
+				t.Fatalf("Decode = %v, want code %s", err, tt.wantCode)
+			}
+			if tt.wantFields != nil && !reflect.DeepEqual(appErr.Fields, tt.wantFields) {
+				t.Errorf("fields = %v, want %v", appErr.Fields, tt.wantFields)
+			}
+		})
+	}
+}
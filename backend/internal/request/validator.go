@@ -0,0 +1,111 @@
+package request
+
+import (
+	"eventBookingSystem/internal/apperr"
+	"fmt"
+	"net/mail"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Validator collects the field errors of a request. Only the first error of
+// each field is kept, so rules for a field should go from basic to specific.
+type Validator struct {
+	errors map[string]string
+}
+
+// Check records message for field unless ok holds.
+func (v *Validator) Check(ok bool, field, message string) {
+	if ok || v.HasError(field) {
+		return
+	}
+	if v.errors == nil {
+		v.errors = make(map[string]string)
+	}
+	v.errors[field] = message
+}
+
+// HasError reports whether a rule for field has already failed.
+func (v *Validator) HasError(field string) bool {
+	_, ok := v.errors[field]
+	return ok
+}
+
+// Err returns a validation error listing every failed field, or nil.
+func (v *Validator) Err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return apperr.Validation(v.errors)
+}
+
+func (v *Validator) Required(field, value string) {
+	v.Check(value != "", field, "Is required")
+}
+
+// Length checks that value has between min and max characters. Empty values
+// are left to Required.
+func (v *Validator) Length(field, value string, min, max int) {
+	if value == "" {
+		return
+	}
+	length := utf8.RuneCountInString(value)
+	v.Check(length >= min, field, fmt.Sprintf("Must be at least %d characters", min))
+	v.Check(length <= max, field, fmt.Sprintf("Must be at most %d characters", max))
+}
+
+func (v *Validator) MaxLength(field, value string, max int) {
+	v.Check(utf8.RuneCountInString(value) <= max, field, fmt.Sprintf("Must be at most %d characters", max))
+}
+
+func (v *Validator) Email(field, value string) {
+	if value == "" {
+		return
+	}
+	address, err := mail.ParseAddress(value)
+	v.Check(err == nil && address.Address == value, field, "Must be a valid email address")
+}
+
+func (v *Validator) UUID(field, value string) {
+	_, err := uuid.Parse(value)
+	v.Check(err == nil, field, "Must be a valid ID")
+}
+
+func (v *Validator) Positive(field string, value int) {
+	v.Check(value > 0, field, "Must be a positive integer")
+}
+
+// Range checks that value is between min and max inclusive.
+func (v *Validator) Range(field string, value, min, max int) {
+	v.Check(value >= min && value <= max, field, fmt.Sprintf("Must be between %d and %d", min, max))
+}
+
+// Time checks that value is an RFC3339 timestamp and returns it. The zero
+// time is returned for empty or invalid values.
+func (v *Validator) Time(field, value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	v.Check(err == nil, field, "Must be an RFC3339 timestamp")
+	return parsed
+}
+
+// Future checks that t lies after now. Zero times are skipped, they already
+// failed Required or Time.
+func (v *Validator) Future(field string, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	v.Check(t.After(time.Now()), field, "Must be in the future")
+}
+
+// Each runs check for every element of values, reporting errors under
+// field[i].
+func (v *Validator) Each(field string, values []string, check func(field, value string)) {
+	for i, value := range values {
+		check(fmt.Sprintf("%s[%d]", field, i), value)
+	}
+}
@@ -0,0 +1,116 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"eventBookingSystem/internal/notify"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EmailVerificationService interface {
+	SendVerification(ctx context.Context, user *User) error
+	ResendVerification(ctx context.Context, userID string) error
+	Verify(ctx context.Context, token string) error
+	IsEmailVerified(ctx context.Context, userID string) (bool, error)
+}
+
+type EmailVerificationServiceImpl struct {
+	EmailVerificationRepository EmailVerificationRepository
+	UserRepository              UserRepository
+	Notifier                    notify.Notifier
+	TokenTTL                    time.Duration
+	ResendInterval              time.Duration
+	AppURL                      string
+}
+
+func NewEmailVerificationService(emailVerificationRepository EmailVerificationRepository, userRepository UserRepository, notifier notify.Notifier, tokenTTL, resendInterval time.Duration, appURL string) EmailVerificationService {
+	return &EmailVerificationServiceImpl{
+		EmailVerificationRepository: emailVerificationRepository,
+		UserRepository:              userRepository,
+		Notifier:                    notifier,
+		TokenTTL:                    tokenTTL,
+		ResendInterval:              resendInterval,
+		AppURL:                      appURL,
+	}
+}
+
+// SendVerification sends the user a link that confirms their email address.
+func (s *EmailVerificationServiceImpl) SendVerification(ctx context.Context, user *User) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.EmailVerificationRepository.Create(ctx, &EmailVerificationToken{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.TokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", s.AppURL, url.QueryEscape(token))
+	return s.Notifier.Send(notify.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to confirm your email address. It expires in %s.\n\n%s",
+			user.Username, s.TokenTTL, link),
+	})
+}
+
+// ResendVerification sends a new verification link, at most once per
+// ResendInterval.
+func (s *EmailVerificationServiceImpl) ResendVerification(ctx context.Context, userID string) error {
+	user, err := s.UserRepository.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return ErrAlreadyVerified
+	}
+
+	lastSent, err := s.EmailVerificationRepository.LatestCreatedAt(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if lastSent != nil {
+		if wait := time.Until(lastSent.Add(s.ResendInterval)); wait > 0 {
+			return &ResendThrottledError{RetryAfter: wait}
+		}
+	}
+
+	return s.SendVerification(ctx, user)
+}
+
+func (s *EmailVerificationServiceImpl) Verify(ctx context.Context, token string) error {
+	verification, err := s.EmailVerificationRepository.GetByHash(ctx, hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidVerification
+	}
+	if err != nil {
+		return err
+	}
+
+	if verification.UsedAt != nil || !verification.ExpiresAt.After(time.Now()) {
+		return ErrInvalidVerification
+	}
+
+	return s.EmailVerificationRepository.Complete(ctx, verification)
+}
+
+// IsEmailVerified satisfies middleware.EmailVerificationChecker.
+func (s *EmailVerificationServiceImpl) IsEmailVerified(ctx context.Context, userID string) (bool, error) {
+	user, err := s.UserRepository.GetByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.EmailVerifiedAt != nil, nil
+}
@@ -0,0 +1,77 @@
+package users
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type EmailVerificationRepository interface {
+	Create(ctx context.Context, token *EmailVerificationToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*EmailVerificationToken, error)
+	LatestCreatedAt(ctx context.Context, userID string) (*time.Time, error)
+	Complete(ctx context.Context, token *EmailVerificationToken) error
+}
+
+type EmailVerificationRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewEmailVerificationRepository(db *gorm.DB) EmailVerificationRepository {
+	return &EmailVerificationRepositoryImpl{DB: db}
+}
+
+// Create stores a new verification token and invalidates any earlier token
+// of the same user that has not been used yet.
+func (r *EmailVerificationRepositoryImpl) Create(ctx context.Context, token *EmailVerificationToken) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&EmailVerificationToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("expires_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+func (r *EmailVerificationRepositoryImpl) GetByHash(ctx context.Context, tokenHash string) (*EmailVerificationToken, error) {
+	var token EmailVerificationToken
+	err := r.DB.WithContext(ctx).First(&token, "token_hash = ?", tokenHash).Error
+	return &token, err
+}
+
+// LatestCreatedAt returns when the user's most recent verification token was
+// created, or nil if none was ever sent.
+func (r *EmailVerificationRepositoryImpl) LatestCreatedAt(ctx context.Context, userID string) (*time.Time, error) {
+	var tokens []EmailVerificationToken
+	err := r.DB.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Limit(1).Find(&tokens).Error
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+	return &tokens[0].CreatedAt, nil
+}
+
+// Complete consumes the token and marks the user's email as verified. The
+// token is consumed only if it is still unused and unexpired, otherwise
+// ErrInvalidVerification is returned and nothing changes.
+func (r *EmailVerificationRepositoryImpl) Complete(ctx context.Context, token *EmailVerificationToken) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&EmailVerificationToken{}).
+			Where("id = ? AND used_at IS NULL AND expires_at > ?", token.ID, now).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidVerification
+		}
+		token.UsedAt = &now
+
+		return tx.Model(&User{}).
+			Where("id = ? AND email_verified_at IS NULL", token.UserID).
+			Update("email_verified_at", now).Error
+	})
+}
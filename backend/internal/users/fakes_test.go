@@ -0,0 +1,451 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"eventBookingSystem/internal/auth/token"
+	"eventBookingSystem/internal/notify"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeDB is the state behind the in-memory repositories of the tests. It
+// mirrors what the gorm repositories do, including their error values.
+type fakeDB struct {
+	mu            sync.Mutex
+	users         map[string]*User
+	sessions      map[string]*Session
+	refreshTokens map[string]*RefreshToken
+	resetTokens   map[string]*PasswordResetToken
+	recoveryCodes []RecoveryCode
+	roles         map[string][]string
+	identities    []UserIdentity
+	loginStates   map[string]OIDCLoginState
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{
+		users:         make(map[string]*User),
+		sessions:      make(map[string]*Session),
+		refreshTokens: make(map[string]*RefreshToken),
+		resetTokens:   make(map[string]*PasswordResetToken),
+		roles:         make(map[string][]string),
+		loginStates:   make(map[string]OIDCLoginState),
+	}
+}
+
+// addUser stores a copy of user with the role.
+func (db *fakeDB) addUser(user User, role string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.users[user.ID] = &user
+	db.roles[user.ID] = append(db.roles[user.ID], role)
+}
+
+func (db *fakeDB) user(id string) User {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return *db.users[id]
+}
+
+func (db *fakeDB) createUser(user *User, role string) error {
+	for _, existing := range db.users {
+		switch {
+		case existing.Username == user.Username:
+			return ErrUsernameTaken
+		case existing.Email == user.Email:
+			return ErrEmailTaken
+		}
+	}
+	stored := *user
+	db.users[user.ID] = &stored
+	if role != "" {
+		db.roles[user.ID] = append(db.roles[user.ID], role)
+	}
+	return nil
+}
+
+type fakeUserRepository struct{ db *fakeDB }
+
+func (r fakeUserRepository) Create(ctx context.Context, user *User) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	return r.db.createUser(user, "")
+}
+
+func (r fakeUserRepository) CreateWithRole(ctx context.Context, user *User, roleName string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	return r.db.createUser(user, roleName)
+}
+
+func (r fakeUserRepository) GetByID(ctx context.Context, id string) (*User, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	user, ok := r.db.users[id]
+	if !ok {
+		return &User{}, ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r fakeUserRepository) find(match func(*User) bool) (*User, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	for _, user := range r.db.users {
+		if match(user) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return &User{}, gorm.ErrRecordNotFound
+}
+
+func (r fakeUserRepository) GetByUsername(ctx context.Context, username string) (*User, error) {
+	return r.find(func(user *User) bool { return user.Username == username })
+}
+
+func (r fakeUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	return r.find(func(user *User) bool { return user.Email == email })
+}
+
+func (r fakeUserRepository) Update(ctx context.Context, user *User) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	stored := *user
+	r.db.users[user.ID] = &stored
+	return nil
+}
+
+func (r fakeUserRepository) Delete(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	delete(r.db.users, id)
+	return nil
+}
+
+func (r fakeUserRepository) GetAll(ctx context.Context) ([]User, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	var users []User
+	for _, user := range r.db.users {
+		users = append(users, *user)
+	}
+	return users, nil
+}
+
+type fakeOIDCRepository struct{ db *fakeDB }
+
+func (r fakeOIDCRepository) CreateLoginState(ctx context.Context, state *OIDCLoginState) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	r.db.loginStates[state.StateHash] = *state
+	return nil
+}
+
+func (r fakeOIDCRepository) ConsumeLoginState(ctx context.Context, stateHash string) (*OIDCLoginState, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	state, ok := r.db.loginStates[stateHash]
+	delete(r.db.loginStates, stateHash)
+	if !ok || !state.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidOIDCState
+	}
+	return &state, nil
+}
+
+func (r fakeOIDCRepository) GetIdentity(ctx context.Context, issuer, subject string) (*UserIdentity, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	for _, identity := range r.db.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return &UserIdentity{}, gorm.ErrRecordNotFound
+}
+
+func (r fakeOIDCRepository) Link(ctx context.Context, identity *UserIdentity, claimAccount bool) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	r.db.identities = append(r.db.identities, *identity)
+	if !claimAccount {
+		return nil
+	}
+
+	now := time.Now()
+	user := r.db.users[identity.UserID]
+	user.EmailVerifiedAt = &now
+	user.PasswordHash = ""
+	for _, session := range r.db.sessions {
+		if session.UserID == identity.UserID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r fakeOIDCRepository) Provision(ctx context.Context, user *User, identity *UserIdentity) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	if err := r.db.createUser(user, user.Role); err != nil {
+		return err
+	}
+	r.db.identities = append(r.db.identities, *identity)
+	return nil
+}
+
+// fakeRoles answers UserHasRole from the roles of the fakeDB.
+type fakeRoles struct{ db *fakeDB }
+
+func (r fakeRoles) UserHasRole(ctx context.Context, userID, roleName string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	return slices.Contains(r.db.roles[userID], roleName), nil
+}
+
+type fakeSessionRepository struct{ db *fakeDB }
+
+func (r fakeSessionRepository) Create(ctx context.Context, session *Session, token *RefreshToken) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	stored := *session
+	r.db.sessions[session.ID] = &stored
+	storedToken := *token
+	r.db.refreshTokens[token.TokenHash] = &storedToken
+	return nil
+}
+
+func (r fakeSessionRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	stored, ok := r.db.refreshTokens[tokenHash]
+	if !ok {
+		return &RefreshToken{}, gorm.ErrRecordNotFound
+	}
+	token := *stored
+	token.Session = *r.db.sessions[token.SessionID]
+	return &token, nil
+}
+
+func (r fakeSessionRepository) Rotate(ctx context.Context, used *RefreshToken, next *RefreshToken) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	stored := r.db.refreshTokens[used.TokenHash]
+	if stored.UsedAt != nil {
+		return ErrRefreshTokenReused
+	}
+	now := time.Now()
+	stored.UsedAt = &now
+	used.UsedAt = &now
+	storedNext := *next
+	r.db.refreshTokens[next.TokenHash] = &storedNext
+	return nil
+}
+
+func (r fakeSessionRepository) IsActive(ctx context.Context, sessionID string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	session, ok := r.db.sessions[sessionID]
+	return ok && session.RevokedAt == nil && r.db.users[session.UserID] != nil, nil
+}
+
+func (r fakeSessionRepository) Revoke(ctx context.Context, sessionID string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	if session, ok := r.db.sessions[sessionID]; ok && session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+	}
+	return nil
+}
+
+func (r fakeSessionRepository) RevokeByUserID(ctx context.Context, userID string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	now := time.Now()
+	for _, session := range r.db.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+type fakeMFARepository struct{ db *fakeDB }
+
+func (r fakeMFARepository) SetPendingSecret(ctx context.Context, userID, secret string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	r.db.users[userID].TOTPSecret = secret
+	return nil
+}
+
+func (r fakeMFARepository) Enable(ctx context.Context, userID string, codes []RecoveryCode) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	now := time.Now()
+	r.db.users[userID].TOTPEnabledAt = &now
+	r.db.replaceRecoveryCodes(userID, codes)
+	return nil
+}
+
+func (r fakeMFARepository) ReplaceRecoveryCodes(ctx context.Context, userID string, codes []RecoveryCode) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	r.db.replaceRecoveryCodes(userID, codes)
+	return nil
+}
+
+func (r fakeMFARepository) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	user := r.db.users[userID]
+	if user.TOTPLastStep >= step {
+		return false, nil
+	}
+	user.TOTPLastStep = step
+	return true, nil
+}
+
+func (r fakeMFARepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	for i := range r.db.recoveryCodes {
+		code := &r.db.recoveryCodes[i]
+		if code.UserID == userID && code.CodeHash == codeHash && code.UsedAt == nil {
+			now := time.Now()
+			code.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r fakeMFARepository) Disable(ctx context.Context, userID string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	user := r.db.users[userID]
+	user.TOTPSecret, user.TOTPEnabledAt, user.TOTPLastStep = "", nil, 0
+	r.db.replaceRecoveryCodes(userID, nil)
+	return nil
+}
+
+func (db *fakeDB) replaceRecoveryCodes(userID string, codes []RecoveryCode) {
+	db.recoveryCodes = slices.DeleteFunc(db.recoveryCodes, func(code RecoveryCode) bool {
+		return code.UserID == userID
+	})
+	db.recoveryCodes = append(db.recoveryCodes, codes...)
+}
+
+type fakePasswordResetRepository struct{ db *fakeDB }
+
+func (r fakePasswordResetRepository) Create(ctx context.Context, token *PasswordResetToken) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	stored := *token
+	r.db.resetTokens[token.TokenHash] = &stored
+	return nil
+}
+
+func (r fakePasswordResetRepository) GetByHash(ctx context.Context, tokenHash string) (*PasswordResetToken, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	stored, ok := r.db.resetTokens[tokenHash]
+	if !ok {
+		return &PasswordResetToken{}, gorm.ErrRecordNotFound
+	}
+	token := *stored
+	return &token, nil
+}
+
+func (r fakePasswordResetRepository) Complete(ctx context.Context, token *PasswordResetToken, passwordHash string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	now := time.Now()
+	stored := r.db.resetTokens[token.TokenHash]
+	if stored.UsedAt != nil || !stored.ExpiresAt.After(now) {
+		return ErrInvalidResetToken
+	}
+	stored.UsedAt = &now
+	token.UsedAt = &now
+
+	r.db.users[token.UserID].PasswordHash = passwordHash
+	for _, session := range r.db.sessions {
+		if session.UserID == token.UserID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+var errLockedOut = errors.New("locked out")
+
+// fakeLockout locks a key after threshold failures until it is reset.
+type fakeLockout struct {
+	mu        sync.Mutex
+	threshold int
+	failures  map[string]int
+}
+
+func newFakeLockout(threshold int) *fakeLockout {
+	return &fakeLockout{threshold: threshold, failures: make(map[string]int)}
+}
+
+func (l *fakeLockout) Check(ctx context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.failures[key] >= l.threshold {
+		return errLockedOut
+	}
+	return nil
+}
+
+func (l *fakeLockout) Fail(ctx context.Context, key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.failures[key]++
+}
+
+func (l *fakeLockout) Reset(ctx context.Context, key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, key)
+}
+
+// recordingNotifier keeps the messages it was asked to send.
+type recordingNotifier struct {
+	mu       sync.Mutex
+	messages []notify.Message
+}
+
+func (n *recordingNotifier) Send(message notify.Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.messages = append(n.messages, message)
+	return nil
+}
+
+func (n *recordingNotifier) sent() []notify.Message {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return slices.Clone(n.messages)
+}
+
+func newTestTokens(t *testing.T) *token.Manager {
+	t.Helper()
+	manager, err := token.NewManager(token.Options{
+		Issuer:   "test",
+		Audience: "test-api",
+		TTL:      time.Minute,
+	}, token.HMACKey("test", []byte("0123456789abcdef0123456789abcdef")))
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return manager
+}
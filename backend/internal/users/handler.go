@@ -1,297 +1,402 @@
 package users
 
 import (
+	"context"
 	"encoding/json"
+	"eventBookingSystem/internal/apperr"
+	"eventBookingSystem/internal/logging"
 	"eventBookingSystem/internal/middleware"
-	"fmt"
-	"log"
+	"eventBookingSystem/internal/request"
 	"net/http"
-	"net/mail"
-	"os"
-	"strings"
-	"time"
-
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type UserHandler struct {
-	UserService UserService
+	UserService              UserService
+	SessionService           SessionService
+	PasswordResetService     PasswordResetService
+	EmailVerificationService EmailVerificationService
+	MFAService               MFAService
+	// OIDCService is nil when single sign-on is not configured.
+	OIDCService OIDCService
 }
 
-func NewUserHandler(userService UserService) *UserHandler {
-	return &UserHandler{UserService: userService}
+func NewUserHandler(userService UserService, sessionService SessionService, passwordResetService PasswordResetService, emailVerificationService EmailVerificationService, mfaService MFAService, oidcService OIDCService) *UserHandler {
+	return &UserHandler{
+		UserService:              userService,
+		SessionService:           sessionService,
+		PasswordResetService:     passwordResetService,
+		EmailVerificationService: emailVerificationService,
+		MFAService:               mfaService,
+		OIDCService:              oidcService,
+	}
 }
 
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	var req CreateUserRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	var req struct {
-		Username string `json:"username"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	user, err := h.UserService.CreateUser(r.Context(), req.Username, req.Email, req.Password, "user")
+	if err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	// Input validation
-	if strings.TrimSpace(req.Username) == "" {
-		http.Error(w, "Username is required", http.StatusBadRequest)
+	h.sendVerification(r.Context(), user)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	if strings.TrimSpace(req.Email) == "" {
-		http.Error(w, "Email is required", http.StatusBadRequest)
+	user, err := h.UserService.Login(r.Context(), req.Email, req.Password)
+	if err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	if _, err := mail.ParseAddress(req.Email); err != nil {
-		http.Error(w, "Invalid email format", http.StatusBadRequest)
+	h.finishLogin(w, r, user)
+}
+
+// OIDCStart begins a single sign-on login. The client sends the browser to
+// the returned authorization URL and keeps the state to check it on return.
+func (h *UserHandler) OIDCStart(w http.ResponseWriter, r *http.Request) {
+	login, err := h.OIDCService.StartLogin(r.Context())
+	if err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	if len(req.Password) < 8 {
-		http.Error(w, "Password must be at least 8 characters long", http.StatusBadRequest)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(login)
+}
+
+// OIDCCallback completes a single sign-on login with the state and code the
+// provider redirected back with. It answers like Login.
+func (h *UserHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	var req OIDCCallbackRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	user, err := h.UserService.CreateUser(req.Username, req.Email, req.Password, "user")
+	user, err := h.OIDCService.CompleteLogin(r.Context(), req.State, req.Code)
 	if err != nil {
-		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
+	h.finishLogin(w, r, user)
 }
 
-func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// finishLogin starts a session for a user who has proven their identity.
+func (h *UserHandler) finishLogin(w http.ResponseWriter, r *http.Request, user *User) {
+	// With two-factor authentication the first factor only earns a
+	// short-lived token that has to be exchanged at /api/users/login/mfa.
+	if user.TOTPEnabledAt != nil {
+		mfaToken, err := h.MFAService.StartLogin(r.Context(), user)
+		if err != nil {
+			apperr.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"mfaRequired": true,
+			"mfaToken":    mfaToken,
+		})
 		return
 	}
 
-	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+	tokens, err := h.SessionService.StartSession(r.Context(), user)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// LoginMFA completes a two-factor login with a TOTP or recovery code.
+func (h *UserHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req MFALoginRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	user, err := h.UserService.Login(req.Email, req.Password)
+	user, err := h.MFAService.CompleteLogin(r.Context(), req.MFAToken, req.Code)
 	if err != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		apperr.Write(w, r, err)
 		return
 	}
 
-	// Generate JWT token
-	token, err := generateJWT(user.ID, user.Role == "admin")
+	tokens, err := h.SessionService.StartSession(r.Context(), user)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"token": token})
+	json.NewEncoder(w).Encode(tokens)
 }
 
-func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
-	// Get the user ID from the request context
+// BeginMFAEnrollment generates a TOTP secret for the caller. The response
+// holds the otpauth URI to show as a QR code.
+func (h *UserHandler) BeginMFAEnrollment(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserIDKey).(string)
 
-	// Get the user from the database
-	user, err := h.UserService.GetUserByID(userID)
+	enrollment, err := h.MFAService.BeginEnrollment(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
+	json.NewEncoder(w).Encode(enrollment)
 }
 
-func generateJWT(userID string, isAdmin bool) (string, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Fatal("JWT_SECRET is not set in .env file")
-		return "", fmt.Errorf("JWT_SECRET is not set")
-	}
+// ConfirmMFAEnrollment enables two-factor authentication for the caller and
+// returns their recovery codes.
+func (h *UserHandler) ConfirmMFAEnrollment(w http.ResponseWriter, r *http.Request) {
+	h.handleMFACode(w, r, func(userID, code string) (interface{}, error) {
+		codes, err := h.MFAService.ConfirmEnrollment(r.Context(), userID, code)
+		return map[string][]string{"recoveryCodes": codes}, err
+	})
+}
 
-	role := "user"
-	if isAdmin {
-		role = "admin"
-	}
+// RegenerateRecoveryCodes replaces the caller's recovery codes.
+func (h *UserHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	h.handleMFACode(w, r, func(userID, code string) (interface{}, error) {
+		codes, err := h.MFAService.RegenerateRecoveryCodes(r.Context(), userID, code)
+		return map[string][]string{"recoveryCodes": codes}, err
+	})
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userID": userID,
-		"exp":    time.Now().Add(time.Hour * 24).Unix(),
-		"role":   role,
+func (h *UserHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	h.handleMFACode(w, r, func(userID, code string) (interface{}, error) {
+		return nil, h.MFAService.Disable(r.Context(), userID, code)
 	})
+}
+
+// handleMFACode decodes a {"code": "..."} body and runs action for the
+// caller. A nil result is answered with 204 No Content.
+func (h *UserHandler) handleMFACode(w http.ResponseWriter, r *http.Request, action func(userID, code string) (interface{}, error)) {
+	var req MFACodeRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
 
-	// Sign the token with the secret key
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	result, err := action(userID, req.Code)
 	if err != nil {
-		return "", err
+		apperr.Write(w, r, err)
+		return
 	}
 
-	return tokenString, nil
+	if result == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
 }
 
-func (h *UserHandler) CreateAdmin(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	var req struct {
-		Username string `json:"username"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
+	tokens, err := h.SessionService.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Logout revokes the session the caller's access token belongs to.
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.Context().Value(middleware.SessionIDKey).(string)
+
+	if err := h.SessionService.Revoke(r.Context(), sessionID); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	// Input validation
-	if strings.TrimSpace(req.Username) == "" {
-		http.Error(w, "Username is required", http.StatusBadRequest)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll revokes every session of the caller, logging them out on all
+// devices.
+func (h *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	if err := h.SessionService.RevokeAll(r.Context(), userID); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	if strings.TrimSpace(req.Email) == "" {
-		http.Error(w, "Email is required", http.StatusBadRequest)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RequestPasswordReset sends a reset link to the given email. It responds the
+// same way whether or not an account exists for the email.
+func (h *UserHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req PasswordResetRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	if _, err := mail.ParseAddress(req.Email); err != nil {
-		http.Error(w, "Invalid email format", http.StatusBadRequest)
+	if err := h.PasswordResetService.RequestReset(r.Context(), req.Email); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	if len(req.Password) < 8 {
-		http.Error(w, "Password must be at least 8 characters long", http.StatusBadRequest)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	user, err := h.UserService.CreateUser(req.Username, req.Email, req.Password, "admin")
-	if err != nil {
-		http.Error(w, "Failed to create admin user", http.StatusInternalServerError)
+	if err := h.PasswordResetService.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *UserHandler) Setup(w http.ResponseWriter, r *http.Request) {
-	// Only allow POST method
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	// Check if system is already initialized
-	users, err := h.UserService.GetAllUsers()
+	if err := h.EmailVerificationService.Verify(r.Context(), req.Token); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendVerification sends the caller a new verification link.
+func (h *UserHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	err := h.EmailVerificationService.ResendVerification(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "Failed to check system initialization status", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
-	if len(users) > 0 {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success":     false,
-			"message":     "System is already initialized",
-			"initialized": true,
-		})
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	// Get the user ID from the request context
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Get the user from the database
+	user, err := h.UserService.GetUserByID(r.Context(), userID)
+	if err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	var req struct {
-		Username string `json:"username"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// sendVerification emails a new account its verification link. The account
+// has already been created, so a failure is only logged and the user can ask
+// for the link again.
+func (h *UserHandler) sendVerification(ctx context.Context, user *User) {
+	if err := h.EmailVerificationService.SendVerification(ctx, user); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Failed to send verification email", "user_id", user.ID, "error", err)
+	}
+}
+
+func (h *UserHandler) CreateAdmin(w http.ResponseWriter, r *http.Request) {
+	var req CreateUserRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Invalid request format",
-			"details": err.Error(),
-		})
+	user, err := h.UserService.CreateUser(r.Context(), req.Username, req.Email, req.Password, "admin")
+	if err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	// Enhanced validation
-	validationErrors := make(map[string]string)
+	h.sendVerification(r.Context(), user)
 
-	if username := strings.TrimSpace(req.Username); username == "" {
-		validationErrors["username"] = "Username is required"
-	} else if len(username) < 3 {
-		validationErrors["username"] = "Username must be at least 3 characters"
-	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
 
-	if email := strings.TrimSpace(req.Email); email == "" {
-		validationErrors["email"] = "Email is required"
-	} else if _, err := mail.ParseAddress(email); err != nil {
-		validationErrors["email"] = "Invalid email format"
+func (h *UserHandler) Setup(w http.ResponseWriter, r *http.Request) {
+	// Check if system is already initialized
+	users, err := h.UserService.GetAllUsers(r.Context())
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
 	}
 
-	if password := req.Password; password == "" {
-		validationErrors["password"] = "Password is required"
-	} else if len(password) < 8 {
-		validationErrors["password"] = "Password must be at least 8 characters"
+	if len(users) > 0 {
+		apperr.Write(w, r, ErrAlreadyInitialized)
+		return
 	}
 
-	if len(validationErrors) > 0 {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"errors":  validationErrors,
-		})
+	var req CreateUserRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
 	// Create the initial admin user
 	user, err := h.UserService.CreateUser(
+		r.Context(),
 		req.Username,
 		req.Email,
 		req.Password,
 		"admin", // First user is always admin
 	)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Failed to create admin user",
-			"details": err.Error(),
-		})
+		apperr.Write(w, r, err)
 		return
 	}
 
-	// Generate token for the new admin
-	token, err := generateJWT(user.ID, true)
+	h.sendVerification(r.Context(), user)
+
+	// Generate tokens for the new admin
+	tokens, err := h.SessionService.StartSession(r.Context(), user)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Failed to generate authentication token",
-			"details": err.Error(),
-		})
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -307,6 +412,10 @@ func (h *UserHandler) Setup(w http.ResponseWriter, r *http.Request) {
 			"email":    user.Email,
 			"role":     user.Role,
 		},
-		"token": token,
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresIn":    tokens.ExpiresIn,
+
+		"mfaEnrollmentRequired": tokens.MFAEnrollmentRequired,
 	})
 }
@@ -0,0 +1,282 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"eventBookingSystem/internal/auth/roles"
+	"eventBookingSystem/internal/auth/token"
+	"eventBookingSystem/internal/auth/totp"
+	"eventBookingSystem/internal/logging"
+	"eventBookingSystem/internal/metrics"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const recoveryCodeCount = 10
+
+type MFAService interface {
+	BeginEnrollment(ctx context.Context, userID string) (*MFAEnrollment, error)
+	ConfirmEnrollment(ctx context.Context, userID, code string) ([]string, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error)
+	Disable(ctx context.Context, userID, code string) error
+	StartLogin(ctx context.Context, user *User) (string, error)
+	CompleteLogin(ctx context.Context, mfaToken, code string) (*User, error)
+}
+
+type MFAServiceImpl struct {
+	MFARepository   MFARepository
+	UserRepository  UserRepository
+	Tokens          TokenIssuer
+	Roles           RoleChecker
+	LoginLockout    LoginLockout
+	Issuer          string
+	PendingTTL      time.Duration
+	RequireAdminMFA bool
+}
+
+func NewMFAService(mfaRepository MFARepository, userRepository UserRepository, tokens TokenIssuer, roles RoleChecker, loginLockout LoginLockout, issuer string, pendingTTL time.Duration, requireAdminMFA bool) MFAService {
+	return &MFAServiceImpl{
+		MFARepository:   mfaRepository,
+		UserRepository:  userRepository,
+		Tokens:          tokens,
+		Roles:           roles,
+		LoginLockout:    loginLockout,
+		Issuer:          issuer,
+		PendingTTL:      pendingTTL,
+		RequireAdminMFA: requireAdminMFA,
+	}
+}
+
+// BeginEnrollment generates a new TOTP secret for the user. It only takes
+// effect once confirmed with ConfirmEnrollment.
+func (s *MFAServiceImpl) BeginEnrollment(ctx context.Context, userID string) (*MFAEnrollment, error) {
+	user, err := s.UserRepository.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.MFARepository.SetPendingSecret(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &MFAEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrollment enables two-factor authentication once the user proves
+// their authenticator produces valid codes. It returns the recovery codes,
+// which are not retrievable later.
+func (s *MFAServiceImpl) ConfirmEnrollment(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := s.UserRepository.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolling
+	}
+
+	if err := s.verifyTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes, records, err := s.newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.MFARepository.Enable(ctx, userID, records); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces all of the user's recovery codes.
+func (s *MFAServiceImpl) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := s.enabledUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verify(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes, records, err := s.newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.MFARepository.ReplaceRecoveryCodes(ctx, userID, records); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *MFAServiceImpl) Disable(ctx context.Context, userID, code string) error {
+	user, err := s.enabledUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	mfaRequired, err := requiresMFA(ctx, s.Roles, s.RequireAdminMFA, userID)
+	if err != nil {
+		return err
+	}
+	if mfaRequired {
+		return ErrMFARequired
+	}
+
+	if err := s.verify(ctx, user, code); err != nil {
+		return err
+	}
+
+	return s.MFARepository.Disable(ctx, userID)
+}
+
+// requiresMFA reports whether the user must use two-factor authentication,
+// which with requireAdminMFA applies to everyone holding the admin role.
+func requiresMFA(ctx context.Context, checker RoleChecker, requireAdminMFA bool, userID string) (bool, error) {
+	if !requireAdminMFA {
+		return false, nil
+	}
+	return checker.UserHasRole(ctx, userID, roles.RoleAdmin)
+}
+
+// StartLogin returns a short-lived token for a user who entered the correct
+// password but still has to provide a second factor.
+func (s *MFAServiceImpl) StartLogin(ctx context.Context, user *User) (string, error) {
+	mfaToken, _, err := s.Tokens.Issue(token.Claims{
+		Purpose:          token.PurposeMFA,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: user.ID},
+	}, s.PendingTTL)
+	return mfaToken, err
+}
+
+// CompleteLogin checks the second factor for a token from StartLogin and
+// returns the user to start a session for. code is either a TOTP code or a
+// recovery code. Repeated wrong codes lock the user out of this step, however
+// many tokens they come with.
+func (s *MFAServiceImpl) CompleteLogin(ctx context.Context, mfaToken, code string) (*User, error) {
+	claims, err := s.Tokens.Verify(mfaToken, token.PurposeMFA)
+	if err != nil {
+		metrics.LoginFailed("mfa")
+		return nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.enabledUser(ctx, claims.UserID())
+	if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrMFANotEnabled) {
+		metrics.LoginFailed("mfa")
+		return nil, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lockoutKey := "mfa:" + user.ID
+	if err := s.LoginLockout.Check(ctx, lockoutKey); err != nil {
+		return nil, err
+	}
+
+	if err := s.verify(ctx, user, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			metrics.LoginFailed("mfa")
+			s.LoginLockout.Fail(ctx, lockoutKey)
+		}
+		return nil, err
+	}
+
+	s.LoginLockout.Reset(ctx, lockoutKey)
+	return user, nil
+}
+
+func (s *MFAServiceImpl) enabledUser(ctx context.Context, userID string) (*User, error) {
+	user, err := s.UserRepository.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabledAt == nil {
+		return nil, ErrMFANotEnabled
+	}
+	return user, nil
+}
+
+// verify accepts either a TOTP code or an unused recovery code.
+func (s *MFAServiceImpl) verify(ctx context.Context, user *User, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		return s.verifyTOTP(ctx, user, code)
+	}
+
+	used, err := s.MFARepository.UseRecoveryCode(ctx, user.ID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+
+	logging.FromContext(ctx).InfoContext(ctx, "Recovery code used", "user_id", user.ID)
+	return nil
+}
+
+func (s *MFAServiceImpl) verifyTOTP(ctx context.Context, user *User, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	fresh, err := s.MFARepository.UseStep(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *MFAServiceImpl) newRecoveryCodes(userID string) ([]string, []RecoveryCode, error) {
+	codes := make([]string, recoveryCodeCount)
+	records := make([]RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(raw))
+		codes[i] = code[:4] + "-" + code[4:]
+		records[i] = RecoveryCode{
+			ID:       uuid.New().String(),
+			UserID:   userID,
+			CodeHash: hashToken(code),
+		}
+	}
+	return codes, records, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,101 @@
+package users
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MFARepository interface {
+	SetPendingSecret(ctx context.Context, userID, secret string) error
+	Enable(ctx context.Context, userID string, codes []RecoveryCode) error
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codes []RecoveryCode) error
+	UseStep(ctx context.Context, userID string, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+	Disable(ctx context.Context, userID string) error
+}
+
+type MFARepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &MFARepositoryImpl{DB: db}
+}
+
+// SetPendingSecret stores a secret that is not enabled until the user
+// confirms it with a valid code.
+func (r *MFARepositoryImpl) SetPendingSecret(ctx context.Context, userID, secret string) error {
+	return r.DB.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND totp_enabled_at IS NULL", userID).
+		Updates(map[string]interface{}{
+			"totp_secret":    secret,
+			"totp_last_step": 0,
+		}).Error
+}
+
+// Enable turns on two-factor authentication with the pending secret and
+// stores the user's recovery codes.
+func (r *MFARepositoryImpl) Enable(ctx context.Context, userID string, codes []RecoveryCode) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).
+			Where("id = ? AND totp_enabled_at IS NULL AND totp_secret <> ''", userID).
+			Update("totp_enabled_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMFANotEnrolling
+		}
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+}
+
+func (r *MFARepositoryImpl) ReplaceRecoveryCodes(ctx context.Context, userID string, codes []RecoveryCode) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+}
+
+// UseStep records that the TOTP code of the given time step was used. It
+// returns false if a code of that step or a later one was used before, so a
+// code cannot be replayed.
+func (r *MFARepositoryImpl) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	result := r.DB.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UseRecoveryCode consumes the matching unused recovery code, reporting
+// whether there was one.
+func (r *MFARepositoryImpl) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	result := r.DB.WithContext(ctx).Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *MFARepositoryImpl) Disable(ctx context.Context, userID string) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&User{}).
+			Where("id = ?", userID).
+			Updates(map[string]interface{}{
+				"totp_secret":     "",
+				"totp_enabled_at": nil,
+				"totp_last_step":  0,
+			}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID string, codes []RecoveryCode) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&codes).Error
+}
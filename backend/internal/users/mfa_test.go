@@ -0,0 +1,223 @@
+package users
+
+import (
+	"errors"
+	"eventBookingSystem/internal/auth/roles"
+	"eventBookingSystem/internal/auth/token"
+	"eventBookingSystem/internal/auth/totp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const mfaLockoutThreshold = 3
+
+type mfaTest struct {
+	db       *fakeDB
+	tokens   *token.Manager
+	lockout  *fakeLockout
+	service  *MFAServiceImpl
+	user     *User
+	recovery []string
+}
+
+// newMFATest enrolls a user with role in two-factor authentication.
+func newMFATest(t *testing.T, role string) *mfaTest {
+	t.Helper()
+	ctx := t.Context()
+
+	db := newFakeDB()
+	db.addUser(User{ID: "ada", Username: "ada", Email: "ada@example.com"}, role)
+	tokens := newTestTokens(t)
+	lockout := newFakeLockout(mfaLockoutThreshold)
+	service := NewMFAService(fakeMFARepository{db}, fakeUserRepository{db}, tokens, fakeRoles{db}, lockout, "Test", time.Minute, true).(*MFAServiceImpl)
+
+	enrollment, err := service.BeginEnrollment(ctx, "ada")
+	if err != nil {
+		t.Fatalf("BeginEnrollment: %v", err)
+	}
+	// Confirm with the previous period's code, so that the current one is
+	// still unused for the test.
+	code, _ := totp.Code(enrollment.Secret, time.Now().Add(-totp.Period))
+	recovery, err := service.ConfirmEnrollment(ctx, "ada", code)
+	if err != nil {
+		t.Fatalf("ConfirmEnrollment: %v", err)
+	}
+
+	user := db.user("ada")
+	return &mfaTest{db: db, tokens: tokens, lockout: lockout, service: service, user: &user, recovery: recovery}
+}
+
+func (m *mfaTest) currentCode(t *testing.T) string {
+	t.Helper()
+	code, err := totp.Code(m.user.TOTPSecret, time.Now())
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+	return code
+}
+
+func (m *mfaTest) startLogin(t *testing.T) string {
+	t.Helper()
+	mfaToken, err := m.service.StartLogin(t.Context(), m.user)
+	if err != nil {
+		t.Fatalf("StartLogin: %v", err)
+	}
+	return mfaToken
+}
+
+func TestMFAEnrollment(t *testing.T) {
+	m := newMFATest(t, roles.RoleUser)
+
+	if m.user.TOTPEnabledAt == nil {
+		t.Fatal("two-factor authentication is not enabled after confirming")
+	}
+	if len(m.recovery) != recoveryCodeCount {
+		t.Errorf("got %d recovery codes, want %d", len(m.recovery), recoveryCodeCount)
+	}
+	if _, err := m.service.BeginEnrollment(t.Context(), "ada"); !errors.Is(err, ErrMFAAlreadyEnabled) {
+		t.Errorf("BeginEnrollment when enabled = %v, want ErrMFAAlreadyEnabled", err)
+	}
+}
+
+func TestMFACompleteLogin(t *testing.T) {
+	tests := []struct {
+		name string
+		// code returns the code to log in with.
+		code    func(t *testing.T, m *mfaTest) string
+		wantErr error
+	}{
+		{
+			name: "current TOTP code",
+			code: func(t *testing.T, m *mfaTest) string { return m.currentCode(t) },
+		},
+		{
+			name: "recovery code",
+			code: func(t *testing.T, m *mfaTest) string { return m.recovery[0] },
+		},
+		{
+			name: "recovery code without dash in upper case",
+			code: func(t *testing.T, m *mfaTest) string {
+				return " " + strings.ToUpper(strings.Replace(m.recovery[1], "-", "", 1)) + " "
+			},
+		},
+		{
+			name: "code used for enrollment",
+			code: func(t *testing.T, m *mfaTest) string {
+				c, _ := totp.Code(m.user.TOTPSecret, time.Now().Add(-totp.Period))
+				return c
+			},
+			wantErr: ErrInvalidMFACode,
+		},
+		{
+			name:    "wrong code",
+			code:    func(t *testing.T, m *mfaTest) string { return "000000" },
+			wantErr: ErrInvalidMFACode,
+		},
+		{
+			name:    "unknown recovery code",
+			code:    func(t *testing.T, m *mfaTest) string { return "aaaa-bbbb" },
+			wantErr: ErrInvalidMFACode,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMFATest(t, roles.RoleUser)
+			code := tt.code(t, m)
+
+			user, err := m.service.CompleteLogin(t.Context(), m.startLogin(t), code)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CompleteLogin = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if user.ID != "ada" {
+				t.Errorf("logged in as %q, want ada", user.ID)
+			}
+
+			// Neither kind of code works twice.
+			if _, err := m.service.CompleteLogin(t.Context(), m.startLogin(t), code); !errors.Is(err, ErrInvalidMFACode) {
+				t.Errorf("second CompleteLogin = %v, want ErrInvalidMFACode", err)
+			}
+		})
+	}
+}
+
+func TestMFACompleteLoginRejectsOtherTokens(t *testing.T) {
+	m := newMFATest(t, roles.RoleUser)
+
+	accessToken, _, err := m.tokens.Issue(token.Claims{
+		Purpose:          token.PurposeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "ada"},
+	}, 0)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	for name, mfaToken := range map[string]string{
+		"access token": accessToken,
+		"garbage":      "not a token",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := m.service.CompleteLogin(t.Context(), mfaToken, m.currentCode(t)); !errors.Is(err, ErrInvalidMFAToken) {
+				t.Errorf("CompleteLogin = %v, want ErrInvalidMFAToken", err)
+			}
+		})
+	}
+}
+
+func TestMFACompleteLoginLockout(t *testing.T) {
+	m := newMFATest(t, roles.RoleUser)
+	ctx := t.Context()
+
+	// Every attempt comes with a fresh token, as an attacker knowing the
+	// password could get one for each guess.
+	for range mfaLockoutThreshold {
+		if _, err := m.service.CompleteLogin(ctx, m.startLogin(t), "000000"); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("CompleteLogin with a wrong code = %v, want ErrInvalidMFACode", err)
+		}
+	}
+
+	if _, err := m.service.CompleteLogin(ctx, m.startLogin(t), m.currentCode(t)); !errors.Is(err, errLockedOut) {
+		t.Fatalf("CompleteLogin after %d failures = %v, want the lockout error", mfaLockoutThreshold, err)
+	}
+
+	m.lockout.Reset(ctx, "mfa:ada")
+	if _, err := m.service.CompleteLogin(ctx, m.startLogin(t), m.currentCode(t)); err != nil {
+		t.Fatalf("CompleteLogin after the lockout: %v", err)
+	}
+	if failures := m.lockout.failures["mfa:ada"]; failures != 0 {
+		t.Errorf("%d failures left after a successful login, want 0", failures)
+	}
+}
+
+func TestMFADisable(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    string
+		code    func(t *testing.T, m *mfaTest) string
+		wantErr error
+	}{
+		{"user with a valid code", roles.RoleUser, func(t *testing.T, m *mfaTest) string { return m.currentCode(t) }, nil},
+		{"user with a wrong code", roles.RoleUser, func(t *testing.T, m *mfaTest) string { return "000000" }, ErrInvalidMFACode},
+		{"admin when required", roles.RoleAdmin, func(t *testing.T, m *mfaTest) string { return m.currentCode(t) }, ErrMFARequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMFATest(t, tt.role)
+
+			err := m.service.Disable(t.Context(), "ada", tt.code(t, m))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Disable = %v, want %v", err, tt.wantErr)
+			}
+
+			enabled := m.db.user("ada").TOTPEnabledAt != nil
+			if enabled != (tt.wantErr != nil) {
+				t.Errorf("enabled = %v after Disable returned %v", enabled, err)
+			}
+		})
+	}
+}
@@ -1,18 +1,167 @@
 package users
 
 import (
+	"eventBookingSystem/internal/apperr"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+var (
+	ErrInvalidCredentials  = apperr.Unauthenticated("invalid email or password")
+	ErrUserNotFound        = apperr.NotFound("user not found")
+	ErrUsernameTaken       = &apperr.Error{Code: apperr.CodeConflict, Message: "username is already taken", Fields: map[string]string{"username": "Username is already taken"}}
+	ErrEmailTaken          = &apperr.Error{Code: apperr.CodeConflict, Message: "email address is already registered", Fields: map[string]string{"email": "Email address is already registered"}}
+	ErrAlreadyInitialized  = apperr.Conflict("system is already initialized")
+	ErrInvalidRefreshToken = apperr.Unauthenticated("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = apperr.Unauthenticated("refresh token has already been used")
+	ErrInvalidResetToken   = apperr.BadRequest("password reset token is invalid or expired")
+	ErrInvalidVerification = apperr.BadRequest("verification token is invalid or expired")
+	ErrAlreadyVerified     = apperr.Conflict("email address is already verified")
+	ErrMFAAlreadyEnabled   = apperr.Conflict("two-factor authentication is already enabled")
+	ErrMFANotEnabled       = apperr.Conflict("two-factor authentication is not enabled")
+	ErrMFANotEnrolling     = apperr.Conflict("two-factor enrollment has not been started")
+	ErrMFARequired         = apperr.Forbidden("two-factor authentication is required for this account")
+	ErrInvalidMFACode      = apperr.Unauthenticated("authentication code is invalid")
+	ErrInvalidMFAToken     = apperr.Unauthenticated("two-factor login token is invalid or expired")
+	ErrInvalidOIDCState    = apperr.BadRequest("single sign-on login is invalid or expired")
+	ErrOIDCLoginFailed     = apperr.Unauthenticated("single sign-on login failed")
+	ErrOIDCEmailUnverified = apperr.Forbidden("the identity provider has not verified the email address")
+	ErrOIDCNoAccount       = apperr.Forbidden("no account exists for this email address")
+)
+
 type User struct {
-	ID           string `gorm:"type:uuid;primaryKey"`
-	Username     string `gorm:"uniqueIndex;not null"`
-	Email        string `gorm:"uniqueIndex;not null"`
-	PasswordHash string `gorm:"not null"`
-	Role         string `gorm:"type:varchar(10);default:'user'"`
+	ID              string `gorm:"type:uuid;primaryKey"`
+	Username        string `gorm:"uniqueIndex;not null"`
+	Email           string `gorm:"uniqueIndex;not null"`
+	PasswordHash    string `gorm:"not null"`
+	Role            string `gorm:"type:varchar(10);default:'user'"`
+	EmailVerifiedAt *time.Time
+	TOTPSecret      string `gorm:"type:varchar(64)" json:"-"`
+	TOTPEnabledAt   *time.Time
+	TOTPLastStep    int64 `json:"-"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
+}
+
+// Session is a login on one device. Every refresh token issued for the login
+// belongs to the same session, so revoking the session invalidates the whole
+// token family together with the access tokens issued for it.
+type Session struct {
+	ID        string     `gorm:"type:uuid;primaryKey"`
+	UserID    string     `gorm:"type:uuid;not null;index"`
+	RevokedAt *time.Time `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RefreshToken is a single-use token that can be exchanged for a new access
+// token. Only the SHA-256 hash of the token is stored.
+type RefreshToken struct {
+	ID        string    `gorm:"type:uuid;primaryKey"`
+	SessionID string    `gorm:"type:uuid;not null;index"`
+	TokenHash string    `gorm:"type:char(64);uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+	Session   Session
+}
+
+// PasswordResetToken lets a user who forgot their password set a new one. It
+// can be used once and only the SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	ID        string    `gorm:"type:uuid;primaryKey"`
+	UserID    string    `gorm:"type:uuid;not null;index"`
+	TokenHash string    `gorm:"type:char(64);uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// EmailVerificationToken confirms that a user owns their email address. It
+// can be used once and only the SHA-256 hash of the token is stored.
+type EmailVerificationToken struct {
+	ID        string    `gorm:"type:uuid;primaryKey"`
+	UserID    string    `gorm:"type:uuid;not null;index"`
+	TokenHash string    `gorm:"type:char(64);uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// RecoveryCode is a single-use code that can stand in for a TOTP code when
+// the user has lost their authenticator. Only the SHA-256 hash is stored.
+type RecoveryCode struct {
+	ID        string `gorm:"type:uuid;primaryKey"`
+	UserID    string `gorm:"type:uuid;not null;index"`
+	CodeHash  string `gorm:"type:char(64);not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// UserIdentity links a user to their account at an OpenID Connect provider,
+// identified by the provider's issuer and the subject it assigns.
+type UserIdentity struct {
+	ID        string `gorm:"type:uuid;primaryKey"`
+	UserID    string `gorm:"type:uuid;not null;index"`
+	Issuer    string `gorm:"not null;uniqueIndex:idx_user_identities_issuer_subject"`
+	Subject   string `gorm:"not null;uniqueIndex:idx_user_identities_issuer_subject"`
+	Email     string
+	CreatedAt time.Time
+}
+
+// OIDCLoginState is a single sign-on login that has been started but not
+// completed. It keeps the nonce and PKCE code verifier for the callback and
+// can be used once; only the SHA-256 hash of the state is stored.
+type OIDCLoginState struct {
+	ID           string    `gorm:"type:uuid;primaryKey"`
+	StateHash    string    `gorm:"type:char(64);uniqueIndex;not null"`
+	Nonce        string    `gorm:"not null"`
+	CodeVerifier string    `gorm:"not null"`
+	ExpiresAt    time.Time `gorm:"not null"`
 	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	DeletedAt    gorm.DeletedAt `gorm:"index"`
+}
+
+// OIDCLogin is returned when a single sign-on login is started. The client
+// keeps State to compare it with the one the provider redirects back with.
+type OIDCLogin struct {
+	AuthorizationURL string `json:"authorizationURL"`
+	State            string `json:"state"`
+}
+
+// MFAEnrollment is what an authenticator app needs to be set up.
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauthURI"`
+}
+
+// TokenPair is returned to the client after logging in or refreshing.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"`
+	// MFAEnrollmentRequired is set when the account must enroll in
+	// two-factor authentication before it can do anything else. The access
+	// token is then only accepted by the enrollment endpoints.
+	MFAEnrollmentRequired bool `json:"mfaEnrollmentRequired,omitempty"`
+}
+
+// ResendThrottledError is returned when a verification email is requested
+// again too soon after the previous one.
+type ResendThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ResendThrottledError) Error() string {
+	return fmt.Sprintf("verification email was sent recently, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+func (e *ResendThrottledError) ErrorCode() apperr.Code {
+	return apperr.CodeTooManyRequests
+}
+
+func (e *ResendThrottledError) RetryDelay() time.Duration {
+	return e.RetryAfter
 }
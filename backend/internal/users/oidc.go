@@ -0,0 +1,246 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"eventBookingSystem/internal/auth/oidc"
+	"eventBookingSystem/internal/logging"
+	"eventBookingSystem/internal/metrics"
+	"eventBookingSystem/internal/notify"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// usernameAttempts is how often a provisioned user's username is retried
+// with a random suffix when it is taken.
+const usernameAttempts = 5
+
+// OIDCProvider is the OpenID Connect provider users log in with, see
+// oidc.Client.
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*oidc.Identity, error)
+}
+
+type OIDCService interface {
+	StartLogin(ctx context.Context) (*OIDCLogin, error)
+	CompleteLogin(ctx context.Context, state, code string) (*User, error)
+}
+
+type OIDCServiceImpl struct {
+	OIDCRepository OIDCRepository
+	UserRepository UserRepository
+	Provider       OIDCProvider
+	Notifier       notify.Notifier
+	StateTTL       time.Duration
+	AutoProvision  bool
+}
+
+func NewOIDCService(oidcRepository OIDCRepository, userRepository UserRepository, provider OIDCProvider, notifier notify.Notifier, stateTTL time.Duration, autoProvision bool) OIDCService {
+	return &OIDCServiceImpl{
+		OIDCRepository: oidcRepository,
+		UserRepository: userRepository,
+		Provider:       provider,
+		Notifier:       notifier,
+		StateTTL:       stateTTL,
+		AutoProvision:  autoProvision,
+	}
+}
+
+// StartLogin begins a single sign-on login and returns the provider URL to
+// send the browser to.
+func (s *OIDCServiceImpl) StartLogin(ctx context.Context) (*OIDCLogin, error) {
+	state, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	codeVerifier, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := s.Provider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.OIDCRepository.CreateLoginState(ctx, &OIDCLoginState{
+		ID:           uuid.New().String(),
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(s.StateTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCLogin{AuthorizationURL: authURL, State: state}, nil
+}
+
+// CompleteLogin finishes the login the provider redirected back from and
+// returns the local user, who is looked up by the identity, else by the
+// verified email address, else created.
+func (s *OIDCServiceImpl) CompleteLogin(ctx context.Context, state, code string) (*User, error) {
+	login, err := s.OIDCRepository.ConsumeLoginState(ctx, hashToken(state))
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.Provider.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		metrics.LoginFailed("oidc")
+		logging.FromContext(ctx).WarnContext(ctx, "Single sign-on login failed", "error", err)
+		return nil, ErrOIDCLoginFailed
+	}
+
+	user, err := s.userFor(ctx, identity)
+	if err != nil {
+		if errors.Is(err, ErrOIDCEmailUnverified) || errors.Is(err, ErrOIDCNoAccount) {
+			metrics.LoginFailed("oidc")
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *OIDCServiceImpl) userFor(ctx context.Context, identity *oidc.Identity) (*User, error) {
+	linked, err := s.OIDCRepository.GetIdentity(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		user, err := s.UserRepository.GetByID(ctx, linked.UserID)
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrOIDCNoAccount
+		}
+		return user, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Anyone can put any address into an unverified email claim, so
+	// accounts are only matched or created by addresses the provider
+	// vouches for.
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrOIDCEmailUnverified
+	}
+
+	link := &UserIdentity{
+		ID:      uuid.New().String(),
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+		Email:   identity.Email,
+	}
+
+	user, err := s.UserRepository.GetByEmail(ctx, identity.Email)
+	if err == nil {
+		return s.link(ctx, user, link)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if !s.AutoProvision {
+		return nil, ErrOIDCNoAccount
+	}
+	return s.provision(ctx, identity, link)
+}
+
+// link adds the identity to the user with the same email address. If that
+// address was never verified, the account may have been registered by
+// someone else in advance, so it is claimed for the identity instead and the
+// owner of the address is told that the password no longer works.
+func (s *OIDCServiceImpl) link(ctx context.Context, user *User, link *UserIdentity) (*User, error) {
+	claimAccount := user.EmailVerifiedAt == nil
+	link.UserID = user.ID
+	if err := s.OIDCRepository.Link(ctx, link, claimAccount); err != nil {
+		return nil, err
+	}
+
+	logger := logging.FromContext(ctx)
+	if claimAccount {
+		logger.WarnContext(ctx, "Claimed unverified account for single sign-on identity, password removed", "user_id", user.ID, "issuer", link.Issuer)
+		err := s.Notifier.Send(notify.Message{
+			To:      user.Email,
+			Subject: "Your account now uses single sign-on",
+			Body: fmt.Sprintf("Hi %s,\n\nYou signed in with single sign-on using this email address, which had not been verified for your account yet. "+
+				"To keep whoever registered the account from using it, its password has been removed and it has been signed out everywhere.\n\n"+
+				"Keep signing in with single sign-on, or set a new password with \"Forgot password\" to also sign in with a password.",
+				user.Username),
+		})
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to send account claim notice", "user_id", user.ID, "error", err)
+		}
+		return s.UserRepository.GetByID(ctx, user.ID)
+	}
+	logger.InfoContext(ctx, "Linked single sign-on identity", "user_id", user.ID, "issuer", link.Issuer)
+	return user, nil
+}
+
+// provision creates a user for the identity. It has no password and its
+// email address counts as verified.
+func (s *OIDCServiceImpl) provision(ctx context.Context, identity *oidc.Identity, link *UserIdentity) (*User, error) {
+	username := provisionedUsername(identity)
+	now := time.Now()
+
+	for attempt := 0; ; attempt++ {
+		user := &User{
+			ID:              uuid.New().String(),
+			Username:        username,
+			Email:           identity.Email,
+			Role:            "user",
+			EmailVerifiedAt: &now,
+		}
+		link.UserID = user.ID
+
+		err := s.OIDCRepository.Provision(ctx, user, link)
+		if errors.Is(err, ErrUsernameTaken) && attempt < usernameAttempts {
+			if username, err = withRandomSuffix(provisionedUsername(identity)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		logging.FromContext(ctx).InfoContext(ctx, "Provisioned user for single sign-on identity", "user_id", user.ID, "issuer", link.Issuer)
+		return user, nil
+	}
+}
+
+// provisionedUsername prefers the username the provider suggests and falls
+// back to the local part of the email address.
+func provisionedUsername(identity *oidc.Identity) string {
+	username := strings.TrimSpace(identity.PreferredUsername)
+	if username == "" || strings.Contains(username, "@") {
+		username, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	// Leave room for the suffix of withRandomSuffix.
+	runes := []rune(username)
+	if len(runes) > maxUsernameLength-5 {
+		runes = runes[:maxUsernameLength-5]
+	}
+	for len(runes) < minUsernameLength {
+		runes = append(runes, '_')
+	}
+	return string(runes)
+}
+
+func withRandomSuffix(username string) (string, error) {
+	suffix := make([]byte, 2)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return username + "-" + hex.EncodeToString(suffix), nil
+}
@@ -0,0 +1,101 @@
+package users
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OIDCRepository interface {
+	CreateLoginState(ctx context.Context, state *OIDCLoginState) error
+	ConsumeLoginState(ctx context.Context, stateHash string) (*OIDCLoginState, error)
+	GetIdentity(ctx context.Context, issuer, subject string) (*UserIdentity, error)
+	Link(ctx context.Context, identity *UserIdentity, claimAccount bool) error
+	Provision(ctx context.Context, user *User, identity *UserIdentity) error
+}
+
+type OIDCRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewOIDCRepository(db *gorm.DB) OIDCRepository {
+	return &OIDCRepositoryImpl{DB: db}
+}
+
+// CreateLoginState stores a started login and deletes the expired ones of
+// logins that were never completed.
+func (r *OIDCRepositoryImpl) CreateLoginState(ctx context.Context, state *OIDCLoginState) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at <= ?", time.Now()).Delete(&OIDCLoginState{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(state).Error
+	})
+}
+
+// ConsumeLoginState deletes and returns the unexpired login with the given
+// state hash, so a state is only accepted once. Otherwise it returns
+// ErrInvalidOIDCState.
+func (r *OIDCRepositoryImpl) ConsumeLoginState(ctx context.Context, stateHash string) (*OIDCLoginState, error) {
+	var states []OIDCLoginState
+	result := r.DB.WithContext(ctx).Clauses(clause.Returning{}).
+		Where("state_hash = ? AND expires_at > ?", stateHash, time.Now()).
+		Delete(&states)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(states) == 0 {
+		return nil, ErrInvalidOIDCState
+	}
+	return &states[0], nil
+}
+
+func (r *OIDCRepositoryImpl) GetIdentity(ctx context.Context, issuer, subject string) (*UserIdentity, error) {
+	var identity UserIdentity
+	err := r.DB.WithContext(ctx).First(&identity, "issuer = ? AND subject = ?", issuer, subject).Error
+	return &identity, err
+}
+
+// Link adds the identity to an existing user. With claimAccount, the user's
+// email address had not been verified, so whoever registered it is not known
+// to own it: the email is marked verified, the password is removed and every
+// session of the user is revoked, leaving the account to the owner of the
+// identity.
+func (r *OIDCRepositoryImpl) Link(ctx context.Context, identity *UserIdentity, claimAccount bool) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(identity).Error; err != nil {
+			return err
+		}
+		if !claimAccount {
+			return nil
+		}
+
+		now := time.Now()
+		err := tx.Model(&User{}).
+			Where("id = ?", identity.UserID).
+			Updates(map[string]interface{}{
+				"email_verified_at": now,
+				"password_hash":     "",
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", identity.UserID).
+			Update("revoked_at", now).Error
+	})
+}
+
+// Provision creates a user with the role in user.Role, together with their
+// identity.
+func (r *OIDCRepositoryImpl) Provision(ctx context.Context, user *User, identity *UserIdentity) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := createUserWithRole(tx, user, user.Role); err != nil {
+			return err
+		}
+		return tx.Create(identity).Error
+	})
+}
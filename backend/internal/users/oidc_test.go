@@ -0,0 +1,279 @@
+package users
+
+import (
+	"errors"
+	"eventBookingSystem/internal/auth/oidc"
+	"eventBookingSystem/internal/auth/oidc/oidctest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestOIDCService(t *testing.T, provider *oidctest.Provider, db *fakeDB, autoProvision bool) (*OIDCServiceImpl, *recordingNotifier) {
+	t.Helper()
+	notifier := &recordingNotifier{}
+	client := oidc.NewClient(oidc.Options{
+		IssuerURL:    provider.URL,
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  "https://app.example.com/sso/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+	})
+	service := NewOIDCService(fakeOIDCRepository{db}, fakeUserRepository{db}, client, notifier, time.Minute, autoProvision)
+	return service.(*OIDCServiceImpl), notifier
+}
+
+// ssoLogin runs a login through the provider and returns the local user.
+func ssoLogin(t *testing.T, service *OIDCServiceImpl, provider *oidctest.Provider, login oidctest.Login) (*User, error) {
+	t.Helper()
+	ctx := t.Context()
+	started, err := service.StartLogin(ctx)
+	if err != nil {
+		t.Fatalf("StartLogin: %v", err)
+	}
+	code, state := provider.Authorize(t, started.AuthorizationURL, login)
+	if state != started.State {
+		t.Fatalf("provider returned state %q, want %q", state, started.State)
+	}
+	return service.CompleteLogin(ctx, state, code)
+}
+
+func TestOIDCCompleteLogin(t *testing.T) {
+	provider := oidctest.NewProvider(t)
+	verifiedAt := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name          string
+		existing      []User
+		autoProvision bool
+		login         oidctest.Login
+		wantErr       error
+		// check inspects the logged in user, the stored state and the
+		// notices sent.
+		check func(t *testing.T, user *User, db *fakeDB, notifier *recordingNotifier)
+	}{
+		{
+			name:     "links the user with the verified email",
+			existing: []User{{ID: "ada", Username: "ada", Email: "ada@example.com", PasswordHash: "hash", EmailVerifiedAt: &verifiedAt}},
+			login:    oidctest.Login{Subject: "sub-ada", Email: "ada@example.com", EmailVerified: true},
+			check: func(t *testing.T, user *User, db *fakeDB, notifier *recordingNotifier) {
+				if user.ID != "ada" {
+					t.Errorf("logged in as %q, want ada", user.ID)
+				}
+				if db.user("ada").PasswordHash != "hash" {
+					t.Error("linking a verified account removed its password")
+				}
+				if len(db.identities) != 1 || db.identities[0].UserID != "ada" || db.identities[0].Subject != "sub-ada" {
+					t.Errorf("identities = %+v, want sub-ada linked to ada", db.identities)
+				}
+				if sent := notifier.sent(); len(sent) != 0 {
+					t.Errorf("sent %d notices, want none", len(sent))
+				}
+			},
+		},
+		{
+			name:     "claims an account whose email was never verified",
+			existing: []User{{ID: "squatter", Username: "squatter", Email: "ada@example.com", PasswordHash: "hash"}},
+			login:    oidctest.Login{Subject: "sub-ada", Email: "ada@example.com", EmailVerified: true},
+			check: func(t *testing.T, user *User, db *fakeDB, notifier *recordingNotifier) {
+				if user.ID != "squatter" {
+					t.Errorf("logged in as %q, want the existing account", user.ID)
+				}
+				if user.PasswordHash != "" || user.EmailVerifiedAt == nil {
+					t.Errorf("claimed user has password %q and verified %v, want no password and verified", user.PasswordHash, user.EmailVerifiedAt)
+				}
+				sent := notifier.sent()
+				if len(sent) != 1 || sent[0].To != "ada@example.com" {
+					t.Errorf("sent %+v, want one notice to ada@example.com", sent)
+				}
+			},
+		},
+		{
+			name:          "provisions a new user",
+			autoProvision: true,
+			login:         oidctest.Login{Subject: "sub-grace", Email: "grace@example.com", EmailVerified: true, PreferredUsername: "grace"},
+			check: func(t *testing.T, user *User, db *fakeDB, notifier *recordingNotifier) {
+				if user.Username != "grace" || user.Email != "grace@example.com" {
+					t.Errorf("provisioned %q <%s>, want grace <grace@example.com>", user.Username, user.Email)
+				}
+				if user.PasswordHash != "" || user.EmailVerifiedAt == nil {
+					t.Error("provisioned user should have no password and a verified email")
+				}
+				if roles := db.roles[user.ID]; len(roles) != 1 || roles[0] != "user" {
+					t.Errorf("provisioned user has roles %v, want [user]", roles)
+				}
+			},
+		},
+		{
+			name:          "provisions with a suffix when the username is taken",
+			existing:      []User{{ID: "other", Username: "grace", Email: "other@example.com", EmailVerifiedAt: &verifiedAt}},
+			autoProvision: true,
+			login:         oidctest.Login{Subject: "sub-grace", Email: "grace@example.com", EmailVerified: true, PreferredUsername: "grace"},
+			check: func(t *testing.T, user *User, db *fakeDB, notifier *recordingNotifier) {
+				if !strings.HasPrefix(user.Username, "grace-") {
+					t.Errorf("provisioned username %q, want grace with a suffix", user.Username)
+				}
+			},
+		},
+		{
+			name:          "falls back to the local part of the email",
+			autoProvision: true,
+			login:         oidctest.Login{Subject: "sub-x", Email: "x@example.com", EmailVerified: true},
+			check: func(t *testing.T, user *User, db *fakeDB, notifier *recordingNotifier) {
+				if user.Username != "x__" {
+					t.Errorf("provisioned username %q, want x padded to the minimum length", user.Username)
+				}
+			},
+		},
+		{
+			name:          "rejects an unverified email",
+			existing:      []User{{ID: "ada", Username: "ada", Email: "ada@example.com", PasswordHash: "hash", EmailVerifiedAt: &verifiedAt}},
+			autoProvision: true,
+			login:         oidctest.Login{Subject: "sub-mallory", Email: "ada@example.com", EmailVerified: false},
+			wantErr:       ErrOIDCEmailUnverified,
+			check: func(t *testing.T, user *User, db *fakeDB, notifier *recordingNotifier) {
+				if len(db.identities) != 0 {
+					t.Errorf("identities = %+v, want none", db.identities)
+				}
+			},
+		},
+		{
+			name:    "rejects a login without an email",
+			login:   oidctest.Login{Subject: "sub-anon", EmailVerified: true},
+			wantErr: ErrOIDCEmailUnverified,
+		},
+		{
+			name:    "rejects unknown users without auto provisioning",
+			login:   oidctest.Login{Subject: "sub-grace", Email: "grace@example.com", EmailVerified: true},
+			wantErr: ErrOIDCNoAccount,
+			check: func(t *testing.T, user *User, db *fakeDB, notifier *recordingNotifier) {
+				if len(db.users) != 0 {
+					t.Errorf("created %d users, want none", len(db.users))
+				}
+			},
+		},
+		{
+			name:     "rejects an ID token with another nonce",
+			existing: []User{{ID: "ada", Username: "ada", Email: "ada@example.com", EmailVerifiedAt: &verifiedAt}},
+			login:    oidctest.Login{Subject: "sub-ada", Email: "ada@example.com", EmailVerified: true, Nonce: "replayed"},
+			wantErr:  ErrOIDCLoginFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			for _, user := range tt.existing {
+				db.addUser(user, "user")
+			}
+			service, notifier := newTestOIDCService(t, provider, db, tt.autoProvision)
+
+			user, err := ssoLogin(t, service, provider, tt.login)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CompleteLogin error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, user, db, notifier)
+			}
+		})
+	}
+}
+
+func TestOIDCLinkedIdentity(t *testing.T) {
+	provider := oidctest.NewProvider(t)
+	db := newFakeDB()
+	service, _ := newTestOIDCService(t, provider, db, true)
+
+	first, err := ssoLogin(t, service, provider, oidctest.Login{Subject: "sub", Email: "old@example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+
+	// The identity is found by its subject, whatever its email is now.
+	second, err := ssoLogin(t, service, provider, oidctest.Login{Subject: "sub", Email: "new@example.com"})
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if second.ID != first.ID {
+		t.Errorf("second login as %q, want %q", second.ID, first.ID)
+	}
+}
+
+func TestOIDCLoginState(t *testing.T) {
+	provider := oidctest.NewProvider(t)
+	login := oidctest.Login{Subject: "sub", Email: "ada@example.com", EmailVerified: true}
+
+	tests := []struct {
+		name string
+		// complete finishes the login started with the state and code.
+		complete func(t *testing.T, service *OIDCServiceImpl, db *fakeDB, state, code string) error
+	}{
+		{
+			name: "reused state",
+			complete: func(t *testing.T, service *OIDCServiceImpl, db *fakeDB, state, code string) error {
+				if _, err := service.CompleteLogin(t.Context(), state, code); err != nil {
+					t.Fatalf("first CompleteLogin: %v", err)
+				}
+				_, err := service.CompleteLogin(t.Context(), state, code)
+				return err
+			},
+		},
+		{
+			name: "expired state",
+			complete: func(t *testing.T, service *OIDCServiceImpl, db *fakeDB, state, code string) error {
+				db.mu.Lock()
+				for hash, stored := range db.loginStates {
+					stored.ExpiresAt = time.Now().Add(-time.Second)
+					db.loginStates[hash] = stored
+				}
+				db.mu.Unlock()
+				_, err := service.CompleteLogin(t.Context(), state, code)
+				return err
+			},
+		},
+		{
+			name: "unknown state",
+			complete: func(t *testing.T, service *OIDCServiceImpl, db *fakeDB, state, code string) error {
+				_, err := service.CompleteLogin(t.Context(), state+"x", code)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			service, _ := newTestOIDCService(t, provider, db, true)
+
+			started, err := service.StartLogin(t.Context())
+			if err != nil {
+				t.Fatalf("StartLogin: %v", err)
+			}
+			code, state := provider.Authorize(t, started.AuthorizationURL, login)
+
+			if err := tt.complete(t, service, db, state, code); !errors.Is(err, ErrInvalidOIDCState) {
+				t.Errorf("CompleteLogin error = %v, want ErrInvalidOIDCState", err)
+			}
+		})
+	}
+}
+
+func TestOIDCStartLoginStoresHashedState(t *testing.T) {
+	provider := oidctest.NewProvider(t)
+	db := newFakeDB()
+	service, _ := newTestOIDCService(t, provider, db, true)
+
+	started, err := service.StartLogin(t.Context())
+	if err != nil {
+		t.Fatalf("StartLogin: %v", err)
+	}
+
+	stored, ok := db.loginStates[hashToken(started.State)]
+	if !ok {
+		t.Fatal("login state is not stored under the hash of the state")
+	}
+	if strings.Contains(started.AuthorizationURL, stored.CodeVerifier) {
+		t.Error("authorization URL contains the code verifier")
+	}
+	if !strings.Contains(started.AuthorizationURL, "nonce="+stored.Nonce) {
+		t.Error("authorization URL does not carry the stored nonce")
+	}
+}
@@ -0,0 +1,95 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"eventBookingSystem/internal/notify"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PasswordResetService interface {
+	RequestReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+type PasswordResetServiceImpl struct {
+	PasswordResetRepository PasswordResetRepository
+	UserRepository          UserRepository
+	Notifier                notify.Notifier
+	TokenTTL                time.Duration
+	AppURL                  string
+}
+
+func NewPasswordResetService(passwordResetRepository PasswordResetRepository, userRepository UserRepository, notifier notify.Notifier, tokenTTL time.Duration, appURL string) PasswordResetService {
+	return &PasswordResetServiceImpl{
+		PasswordResetRepository: passwordResetRepository,
+		UserRepository:          userRepository,
+		Notifier:                notifier,
+		TokenTTL:                tokenTTL,
+		AppURL:                  appURL,
+	}
+}
+
+// RequestReset sends a reset link to the user with the given email. Unknown
+// emails are ignored so callers cannot tell which addresses have an account.
+func (s *PasswordResetServiceImpl) RequestReset(ctx context.Context, email string) error {
+	user, err := s.UserRepository.GetByEmail(ctx, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.PasswordResetRepository.Create(ctx, &PasswordResetToken{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.TokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.AppURL, url.QueryEscape(token))
+	return s.Notifier.Send(notify.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s\n\n"+
+			"If you did not ask to reset your password, you can ignore this message.",
+			user.Username, s.TokenTTL, link),
+	})
+}
+
+// ResetPassword sets a new password using a token from RequestReset. All of
+// the user's sessions are revoked, so they have to log in again everywhere.
+func (s *PasswordResetServiceImpl) ResetPassword(ctx context.Context, token, password string) error {
+	resetToken, err := s.PasswordResetRepository.GetByHash(ctx, hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	if resetToken.UsedAt != nil || !resetToken.ExpiresAt.After(time.Now()) {
+		return ErrInvalidResetToken
+	}
+
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return s.PasswordResetRepository.Complete(ctx, resetToken, hashedPassword)
+}
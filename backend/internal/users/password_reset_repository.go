@@ -0,0 +1,72 @@
+package users
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, token *PasswordResetToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*PasswordResetToken, error)
+	Complete(ctx context.Context, token *PasswordResetToken, passwordHash string) error
+}
+
+type PasswordResetRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &PasswordResetRepositoryImpl{DB: db}
+}
+
+// Create stores a new reset token and invalidates any earlier token of the
+// same user that has not been used yet.
+func (r *PasswordResetRepositoryImpl) Create(ctx context.Context, token *PasswordResetToken) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("expires_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+func (r *PasswordResetRepositoryImpl) GetByHash(ctx context.Context, tokenHash string) (*PasswordResetToken, error) {
+	var token PasswordResetToken
+	err := r.DB.WithContext(ctx).First(&token, "token_hash = ?", tokenHash).Error
+	return &token, err
+}
+
+// Complete consumes the token, stores the new password hash and revokes every
+// session of the user. The token is consumed only if it is still unused and
+// unexpired, otherwise ErrInvalidResetToken is returned and nothing changes.
+func (r *PasswordResetRepositoryImpl) Complete(ctx context.Context, token *PasswordResetToken, passwordHash string) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL AND expires_at > ?", token.ID, now).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidResetToken
+		}
+		token.UsedAt = &now
+
+		err := tx.Model(&User{}).
+			Where("id = ?", token.UserID).
+			Update("password_hash", passwordHash).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", token.UserID).
+			Update("revoked_at", now).Error
+	})
+}
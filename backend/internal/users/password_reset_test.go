@@ -0,0 +1,122 @@
+package users
+
+import (
+	"errors"
+	"eventBookingSystem/internal/auth/roles"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// resetToken extracts the token from the link of a reset message.
+func resetToken(t *testing.T, body string) string {
+	t.Helper()
+	_, rest, ok := strings.Cut(body, "?token=")
+	if !ok {
+		t.Fatalf("no reset link in %q", body)
+	}
+	token, err := url.QueryUnescape(strings.Fields(rest)[0])
+	if err != nil {
+		t.Fatalf("unescape token: %v", err)
+	}
+	return token
+}
+
+func TestRequestReset(t *testing.T) {
+	db := newFakeDB()
+	db.addUser(User{ID: "ada", Username: "ada", Email: "ada@example.com"}, roles.RoleUser)
+	notifier := &recordingNotifier{}
+	service := NewPasswordResetService(fakePasswordResetRepository{db}, fakeUserRepository{db}, notifier, time.Hour, "https://app.example.com")
+
+	if err := service.RequestReset(t.Context(), "nobody@example.com"); err != nil {
+		t.Fatalf("RequestReset of an unknown email: %v", err)
+	}
+	if sent := notifier.sent(); len(sent) != 0 {
+		t.Fatalf("sent %d messages for an unknown email, want none", len(sent))
+	}
+
+	if err := service.RequestReset(t.Context(), "ada@example.com"); err != nil {
+		t.Fatalf("RequestReset: %v", err)
+	}
+	sent := notifier.sent()
+	if len(sent) != 1 || sent[0].To != "ada@example.com" || !strings.Contains(sent[0].Body, "https://app.example.com/reset-password?token=") {
+		t.Fatalf("sent %+v, want one reset link to ada", sent)
+	}
+	if _, ok := db.resetTokens[hashToken(resetToken(t, sent[0].Body))]; !ok {
+		t.Error("the mailed token is not stored by its hash")
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare changes the stored token and returns the token to present.
+		prepare func(t *testing.T, service PasswordResetService, db *fakeDB, token string) string
+		wantErr error
+	}{
+		{
+			name:    "valid token",
+			prepare: func(t *testing.T, service PasswordResetService, db *fakeDB, token string) string { return token },
+		},
+		{
+			name: "token used before",
+			prepare: func(t *testing.T, service PasswordResetService, db *fakeDB, token string) string {
+				if err := service.ResetPassword(t.Context(), token, "first new password"); err != nil {
+					t.Fatalf("first ResetPassword: %v", err)
+				}
+				return token
+			},
+			wantErr: ErrInvalidResetToken,
+		},
+		{
+			name: "expired token",
+			prepare: func(t *testing.T, service PasswordResetService, db *fakeDB, token string) string {
+				db.resetTokens[hashToken(token)].ExpiresAt = time.Now().Add(-time.Second)
+				return token
+			},
+			wantErr: ErrInvalidResetToken,
+		},
+		{
+			name:    "unknown token",
+			prepare: func(t *testing.T, service PasswordResetService, db *fakeDB, token string) string { return token + "x" },
+			wantErr: ErrInvalidResetToken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			db.addUser(User{ID: "ada", Username: "ada", Email: "ada@example.com", PasswordHash: "old"}, roles.RoleUser)
+			db.sessions["s"] = &Session{ID: "s", UserID: "ada"}
+			notifier := &recordingNotifier{}
+			service := NewPasswordResetService(fakePasswordResetRepository{db}, fakeUserRepository{db}, notifier, time.Hour, "https://app.example.com")
+
+			if err := service.RequestReset(t.Context(), "ada@example.com"); err != nil {
+				t.Fatalf("RequestReset: %v", err)
+			}
+			token := tt.prepare(t, service, db, resetToken(t, notifier.sent()[0].Body))
+			hashBefore := db.user("ada").PasswordHash
+
+			err := service.ResetPassword(t.Context(), token, "new password")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResetPassword = %v, want %v", err, tt.wantErr)
+			}
+
+			hash := db.user("ada").PasswordHash
+			if tt.wantErr != nil {
+				if hash != hashBefore {
+					t.Error("a rejected token changed the password")
+				}
+				return
+			}
+			if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte("new password")); err != nil {
+				t.Errorf("password was not changed: %v", err)
+			}
+			if db.sessions["s"].RevokedAt == nil {
+				t.Error("the user's sessions were not revoked")
+			}
+		})
+	}
+}
@@ -2,6 +2,12 @@
 package users
 
 import (
+	"context"
+	"errors"
+	"eventBookingSystem/internal/apperr"
+	"eventBookingSystem/internal/auth/roles"
+	"strings"
+
 	"gorm.io/gorm"
 )
 
@@ -10,51 +16,94 @@ type UserRepositoryImpl struct {
 }
 
 type UserRepository interface {
-	Create(user *User) error
-	GetByID(id string) (*User, error)
-	GetByUsername(username string) (*User, error)
-	GetByEmail(email string) (*User, error)
-	Update(user *User) error
-	Delete(id string) error
-	GetAll() ([]User, error)
+	Create(ctx context.Context, user *User) error
+	CreateWithRole(ctx context.Context, user *User, roleName string) error
+	GetByID(ctx context.Context, id string) (*User, error)
+	GetByUsername(ctx context.Context, username string) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	Update(ctx context.Context, user *User) error
+	Delete(ctx context.Context, id string) error
+	GetAll(ctx context.Context) ([]User, error)
 }
 
 func NewUserRepository(db *gorm.DB) UserRepository {
 	return &UserRepositoryImpl{DB: db}
 }
 
-func (r *UserRepositoryImpl) Create(user *User) error {
-	return r.DB.Create(user).Error
+func (r *UserRepositoryImpl) Create(ctx context.Context, user *User) error {
+	return createUser(r.DB.WithContext(ctx), user)
+}
+
+// CreateWithRole creates the user together with their first role, so a user
+// never exists without one.
+func (r *UserRepositoryImpl) CreateWithRole(ctx context.Context, user *User, roleName string) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createUserWithRole(tx, user, roleName)
+	})
+}
+
+func createUserWithRole(tx *gorm.DB, user *User, roleName string) error {
+	var role roles.Role
+	err := tx.First(&role, "name = ?", roleName).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return roles.ErrRoleNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := createUser(tx, user); err != nil {
+		return err
+	}
+	return tx.Create(&roles.UserRole{UserID: user.ID, RoleName: roleName}).Error
+}
+
+// createUser reports a taken username or email as ErrUsernameTaken or
+// ErrEmailTaken.
+func createUser(db *gorm.DB, user *User) error {
+	err := db.Create(user).Error
+	if constraint, ok := apperr.UniqueViolation(err); ok {
+		switch {
+		case strings.Contains(constraint, "username"):
+			return ErrUsernameTaken
+		case strings.Contains(constraint, "email"):
+			return ErrEmailTaken
+		}
+	}
+	return err
 }
 
-func (r *UserRepositoryImpl) GetByID(id string) (*User, error) {
+func (r *UserRepositoryImpl) GetByID(ctx context.Context, id string) (*User, error) {
 	var user User
-	err := r.DB.First(&user, "id = ?", id).Error
+	err := r.DB.WithContext(ctx).First(&user, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &user, ErrUserNotFound
+	}
 	return &user, err
 }
 
-func (r *UserRepositoryImpl) GetByUsername(username string) (*User, error) {
+func (r *UserRepositoryImpl) GetByUsername(ctx context.Context, username string) (*User, error) {
 	var user User
-	err := r.DB.First(&user, "username = ?", username).Error
+	err := r.DB.WithContext(ctx).First(&user, "username = ?", username).Error
 	return &user, err
 }
 
-func (r *UserRepositoryImpl) GetByEmail(email string) (*User, error) {
+func (r *UserRepositoryImpl) GetByEmail(ctx context.Context, email string) (*User, error) {
 	var user User
-	err := r.DB.First(&user, "email = ?", email).Error
+	err := r.DB.WithContext(ctx).First(&user, "email = ?", email).Error
 	return &user, err
 }
 
-func (r *UserRepositoryImpl) Update(user *User) error {
-	return r.DB.Save(user).Error
+func (r *UserRepositoryImpl) Update(ctx context.Context, user *User) error {
+	return r.DB.WithContext(ctx).Save(user).Error
 }
 
-func (r *UserRepositoryImpl) Delete(id string) error {
-	return r.DB.Delete(&User{}, "id = ?", id).Error
+func (r *UserRepositoryImpl) Delete(ctx context.Context, id string) error {
+	return r.DB.WithContext(ctx).Delete(&User{}, "id = ?", id).Error
 }
 
-func (r *UserRepositoryImpl) GetAll() ([]User, error) {
+func (r *UserRepositoryImpl) GetAll(ctx context.Context) ([]User, error) {
 	var users []User
-	err := r.DB.Find(&users).Error
+	err := r.DB.WithContext(ctx).Find(&users).Error
 	return users, err
 }
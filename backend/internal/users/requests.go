@@ -0,0 +1,156 @@
+package users
+
+import (
+	"eventBookingSystem/internal/request"
+	"strings"
+)
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 50
+	maxEmailLength    = 254
+	minPasswordLength = 8
+	// maxPasswordBytes is the most bcrypt can hash.
+	maxPasswordBytes = 72
+)
+
+// CreateUserRequest is the body of registration, admin creation and setup.
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (r *CreateUserRequest) Normalize() {
+	r.Username = strings.TrimSpace(r.Username)
+	r.Email = strings.TrimSpace(r.Email)
+}
+
+func (r *CreateUserRequest) Validate(v *request.Validator) {
+	v.Required("username", r.Username)
+	v.Length("username", r.Username, minUsernameLength, maxUsernameLength)
+	validateEmail(v, "email", r.Email)
+	validatePassword(v, "password", r.Password)
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (r *LoginRequest) Normalize() {
+	r.Email = strings.TrimSpace(r.Email)
+}
+
+func (r *LoginRequest) Validate(v *request.Validator) {
+	v.Required("email", r.Email)
+	v.Required("password", r.Password)
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfaToken"`
+	Code     string `json:"code"`
+}
+
+func (r *MFALoginRequest) Normalize() {
+	r.MFAToken = strings.TrimSpace(r.MFAToken)
+	r.Code = strings.TrimSpace(r.Code)
+}
+
+func (r *MFALoginRequest) Validate(v *request.Validator) {
+	v.Required("mfaToken", r.MFAToken)
+	v.Required("code", r.Code)
+}
+
+// MFACodeRequest carries a TOTP or recovery code confirming an MFA change.
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+func (r *MFACodeRequest) Normalize() {
+	r.Code = strings.TrimSpace(r.Code)
+}
+
+func (r *MFACodeRequest) Validate(v *request.Validator) {
+	v.Required("code", r.Code)
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (r *RefreshRequest) Normalize() {
+	r.RefreshToken = strings.TrimSpace(r.RefreshToken)
+}
+
+func (r *RefreshRequest) Validate(v *request.Validator) {
+	v.Required("refreshToken", r.RefreshToken)
+}
+
+// OIDCCallbackRequest carries what the provider redirected back with.
+type OIDCCallbackRequest struct {
+	State string `json:"state"`
+	Code  string `json:"code"`
+}
+
+func (r *OIDCCallbackRequest) Normalize() {
+	r.State = strings.TrimSpace(r.State)
+	r.Code = strings.TrimSpace(r.Code)
+}
+
+func (r *OIDCCallbackRequest) Validate(v *request.Validator) {
+	v.Required("state", r.State)
+	v.Required("code", r.Code)
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+func (r *PasswordResetRequest) Normalize() {
+	r.Email = strings.TrimSpace(r.Email)
+}
+
+func (r *PasswordResetRequest) Validate(v *request.Validator) {
+	validateEmail(v, "email", r.Email)
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (r *ResetPasswordRequest) Normalize() {
+	r.Token = strings.TrimSpace(r.Token)
+}
+
+func (r *ResetPasswordRequest) Validate(v *request.Validator) {
+	v.Required("token", r.Token)
+	validatePassword(v, "password", r.Password)
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+func (r *VerifyEmailRequest) Normalize() {
+	r.Token = strings.TrimSpace(r.Token)
+}
+
+func (r *VerifyEmailRequest) Validate(v *request.Validator) {
+	v.Required("token", r.Token)
+}
+
+func validateEmail(v *request.Validator, field, email string) {
+	v.Required(field, email)
+	v.MaxLength(field, email, maxEmailLength)
+	v.Email(field, email)
+}
+
+// validatePassword applies the password policy. Passwords are not trimmed,
+// whitespace is part of the password.
+func validatePassword(v *request.Validator, field, password string) {
+	v.Required(field, password)
+	v.Length(field, password, minPasswordLength, maxPasswordBytes)
+	v.Check(len(password) <= maxPasswordBytes, field, "Must be at most 72 bytes")
+}
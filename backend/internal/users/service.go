@@ -1,34 +1,56 @@
 package users
 
 import (
+	"context"
+	"errors"
+	"eventBookingSystem/internal/metrics"
+	"strings"
+
 	"github.com/google/uuid"
 
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+// RoleChecker reports whether a user has been given a role. Roles are
+// managed in the roles package; the legacy users.role column only records
+// the role a user was created with.
+type RoleChecker interface {
+	UserHasRole(ctx context.Context, userID, roleName string) (bool, error)
+}
+
+// LoginLockout locks an account out after repeated failed logins. Check
+// returns an error while the key is locked.
+type LoginLockout interface {
+	Check(ctx context.Context, key string) error
+	Fail(ctx context.Context, key string)
+	Reset(ctx context.Context, key string)
+}
+
 type UserServiceImpl struct {
 	UserRepository UserRepository
+	LoginLockout   LoginLockout
 }
 type UserService interface {
-	CreateUser(username, email, password, role string) (*User, error)
-	GetUserByID(id string) (*User, error)
-	GetUserByUsername(username string) (*User, error)
-	UpdateUser(user *User) error
-	DeleteUser(id string) error
-	Login(email, password string) (*User, error)
-	GetAllUsers() ([]User, error)
+	CreateUser(ctx context.Context, username, email, password, role string) (*User, error)
+	GetUserByID(ctx context.Context, id string) (*User, error)
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	UpdateUser(ctx context.Context, user *User) error
+	DeleteUser(ctx context.Context, id string) error
+	Login(ctx context.Context, email, password string) (*User, error)
+	GetAllUsers(ctx context.Context) ([]User, error)
 }
 
-func NewUserService(userRepository UserRepository) UserService {
-	return &UserServiceImpl{UserRepository: userRepository}
+func NewUserService(userRepository UserRepository, loginLockout LoginLockout) UserService {
+	return &UserServiceImpl{UserRepository: userRepository, LoginLockout: loginLockout}
 }
 
-func (s *UserServiceImpl) GetAllUsers() ([]User, error) {
-	return s.UserRepository.GetAll()
+func (s *UserServiceImpl) GetAllUsers(ctx context.Context) ([]User, error) {
+	return s.UserRepository.GetAll(ctx)
 }
 
-func (s *UserServiceImpl) CreateUser(username, email, password, role string) (*User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func (s *UserServiceImpl) CreateUser(ctx context.Context, username, email, password, role string) (*User, error) {
+	hashedPassword, err := hashPassword(password)
 	if err != nil {
 		return nil, err
 	}
@@ -37,45 +59,87 @@ func (s *UserServiceImpl) CreateUser(username, email, password, role string) (*U
 		ID:           uuid.New().String(),
 		Username:     username,
 		Email:        email,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
 		Role:         role,
 	}
 
-	err = s.UserRepository.Create(user)
-	if err != nil {
+	if err := s.UserRepository.CreateWithRole(ctx, user, role); err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
 
-func (s *UserServiceImpl) GetUserByID(id string) (*User, error) {
-	return s.UserRepository.GetByID(id)
+func (s *UserServiceImpl) GetUserByID(ctx context.Context, id string) (*User, error) {
+	return s.UserRepository.GetByID(ctx, id)
 }
 
-func (s *UserServiceImpl) GetUserByUsername(username string) (*User, error) {
-	return s.UserRepository.GetByUsername(username)
+func (s *UserServiceImpl) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	return s.UserRepository.GetByUsername(ctx, username)
 }
 
-func (s *UserServiceImpl) UpdateUser(user *User) error {
-	return s.UserRepository.Update(user)
+func (s *UserServiceImpl) UpdateUser(ctx context.Context, user *User) error {
+	return s.UserRepository.Update(ctx, user)
 }
 
-func (s *UserServiceImpl) DeleteUser(id string) error {
-	return s.UserRepository.Delete(id)
+func (s *UserServiceImpl) DeleteUser(ctx context.Context, id string) error {
+	return s.UserRepository.Delete(ctx, id)
 }
 
-func (s *UserServiceImpl) Login(email, password string) (*User, error) {
+// Login checks the password of the account with the given email. Repeated
+// failures for the same email lock it out, whether or not the account exists.
+// The email is normalized for the lockout like ratelimit.ByJSONField does for
+// the login rate limit.
+func (s *UserServiceImpl) Login(ctx context.Context, email, password string) (*User, error) {
+	lockoutKey := "login:" + strings.ToLower(strings.TrimSpace(email))
+	if err := s.LoginLockout.Check(ctx, lockoutKey); err != nil {
+		return nil, err
+	}
 
-	user, err := s.UserRepository.GetByEmail(email)
+	user, err := s.checkPassword(ctx, email, password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		metrics.LoginFailed("password")
+		s.LoginLockout.Fail(ctx, lockoutKey)
+		return nil, err
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	s.LoginLockout.Reset(ctx, lockoutKey)
+	return user, nil
+}
+
+func (s *UserServiceImpl) checkPassword(ctx context.Context, email, password string) (*User, error) {
+	user, err := s.UserRepository.GetByEmail(ctx, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Users provisioned by single sign-on have no password until they set
+	// one with a password reset.
+	if user.PasswordHash == "" {
+		return nil, ErrInvalidCredentials
+	}
+
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return nil, ErrInvalidCredentials
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
+
+func hashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
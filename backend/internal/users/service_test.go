@@ -0,0 +1,83 @@
+package users
+
+import (
+	"errors"
+	"eventBookingSystem/internal/auth/roles"
+	"testing"
+)
+
+func TestLogin(t *testing.T) {
+	hash, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// failures are the wrong passwords tried before the login.
+		failures []string
+		email    string
+		password string
+		wantErr  error
+	}{
+		{name: "correct password", email: "ada@example.com", password: "correct horse"},
+		{name: "wrong password", email: "ada@example.com", password: "battery staple", wantErr: ErrInvalidCredentials},
+		{name: "unknown email", email: "grace@example.com", password: "correct horse", wantErr: ErrInvalidCredentials},
+		{
+			name:     "success resets failures",
+			failures: []string{"one", "two"},
+			email:    "ada@example.com",
+			password: "correct horse",
+		},
+		{
+			name:     "locked out",
+			failures: []string{"one", "two", "three"},
+			email:    "ada@example.com",
+			password: "correct horse",
+			wantErr:  errLockedOut,
+		},
+		{
+			name:     "locked out whatever the case of the email",
+			failures: []string{"one", "two", "three"},
+			email:    "Ada@Example.com",
+			password: "correct horse",
+			wantErr:  errLockedOut,
+		},
+		{
+			name:     "locked out with spaces around the email",
+			failures: []string{"one", "two", "three"},
+			email:    " ADA@example.com ",
+			password: "correct horse",
+			wantErr:  errLockedOut,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+			db := newFakeDB()
+			db.addUser(User{ID: "ada", Username: "ada", Email: "ada@example.com", PasswordHash: hash}, roles.RoleUser)
+			lockout := newFakeLockout(3)
+			service := NewUserService(fakeUserRepository{db}, lockout)
+
+			for _, password := range tt.failures {
+				if _, err := service.Login(ctx, "ada@example.com", password); !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("Login with %q = %v, want ErrInvalidCredentials", password, err)
+				}
+			}
+
+			user, err := service.Login(ctx, tt.email, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if user.ID != "ada" {
+				t.Errorf("Login = %s, want ada", user.ID)
+			}
+			if len(lockout.failures) != 0 {
+				t.Errorf("failures = %v after a successful login, want none", lockout.failures)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"eventBookingSystem/internal/auth/token"
+	"eventBookingSystem/internal/logging"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SessionService interface {
+	StartSession(ctx context.Context, user *User) (*TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
+	Revoke(ctx context.Context, sessionID string) error
+	RevokeAll(ctx context.Context, userID string) error
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+// TokenIssuer signs and verifies the JWTs handed to clients. Access tokens
+// get the issuer's default lifetime.
+type TokenIssuer interface {
+	Issue(claims token.Claims, ttl time.Duration) (string, time.Time, error)
+	Verify(tokenString, purpose string) (*token.Claims, error)
+}
+
+type SessionServiceImpl struct {
+	SessionRepository SessionRepository
+	UserRepository    UserRepository
+	Tokens            TokenIssuer
+	Roles             RoleChecker
+	RefreshTokenTTL   time.Duration
+	RequireAdminMFA   bool
+}
+
+func NewSessionService(sessionRepository SessionRepository, userRepository UserRepository, tokens TokenIssuer, roles RoleChecker, refreshTokenTTL time.Duration, requireAdminMFA bool) SessionService {
+	return &SessionServiceImpl{
+		SessionRepository: sessionRepository,
+		UserRepository:    userRepository,
+		Tokens:            tokens,
+		Roles:             roles,
+		RefreshTokenTTL:   refreshTokenTTL,
+		RequireAdminMFA:   requireAdminMFA,
+	}
+}
+
+// StartSession opens a new session for the user and issues its first token
+// pair.
+func (s *SessionServiceImpl) StartSession(ctx context.Context, user *User) (*TokenPair, error) {
+	session := &Session{
+		ID:     uuid.New().String(),
+		UserID: user.ID,
+	}
+
+	refreshToken, token, err := s.newRefreshToken(session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.SessionRepository.Create(ctx, session, token); err != nil {
+		return nil, err
+	}
+
+	return s.tokenPair(ctx, user, session.ID, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new token pair. Each refresh token
+// can be used once; presenting one that was already exchanged means it has
+// leaked, so the whole session is revoked.
+func (s *SessionServiceImpl) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	used, err := s.SessionRepository.GetRefreshToken(ctx, hashToken(refreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if used.Session.RevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if used.UsedAt != nil {
+		return nil, s.revokeReused(ctx, used)
+	}
+	if !used.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.UserRepository.GetByID(ctx, used.Session.UserID)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nextToken, next, err := s.newRefreshToken(used.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.SessionRepository.Rotate(ctx, used, next); err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			return nil, s.revokeReused(ctx, used)
+		}
+		return nil, err
+	}
+
+	return s.tokenPair(ctx, user, used.SessionID, nextToken)
+}
+
+func (s *SessionServiceImpl) Revoke(ctx context.Context, sessionID string) error {
+	return s.SessionRepository.Revoke(ctx, sessionID)
+}
+
+// RevokeAll logs the user out of every device.
+func (s *SessionServiceImpl) RevokeAll(ctx context.Context, userID string) error {
+	return s.SessionRepository.RevokeByUserID(ctx, userID)
+}
+
+// IsSessionActive satisfies middleware.SessionValidator.
+func (s *SessionServiceImpl) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return s.SessionRepository.IsActive(ctx, sessionID)
+}
+
+// revokeReused kills the session of a refresh token that was presented after
+// it had already been exchanged.
+func (s *SessionServiceImpl) revokeReused(ctx context.Context, token *RefreshToken) error {
+	logging.FromContext(ctx).WarnContext(ctx, "Refresh token reuse detected, revoking session", "session_id", token.SessionID)
+	if err := s.SessionRepository.Revoke(ctx, token.SessionID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func (s *SessionServiceImpl) newRefreshToken(sessionID string) (string, *RefreshToken, error) {
+	refreshToken, err := newToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return refreshToken, &RefreshToken{
+		ID:        uuid.New().String(),
+		SessionID: sessionID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.RefreshTokenTTL),
+	}, nil
+}
+
+// tokenPair issues the access token for the session. Admins that are forced
+// to use two-factor authentication but have not enrolled yet get a token
+// scoped to enrollment; refreshing after enrolling yields a full one.
+func (s *SessionServiceImpl) tokenPair(ctx context.Context, user *User, sessionID, refreshToken string) (*TokenPair, error) {
+	mfaRequired, err := requiresMFA(ctx, s.Roles, s.RequireAdminMFA, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	enrollmentOnly := mfaRequired && user.TOTPEnabledAt == nil
+
+	claims := token.Claims{
+		Purpose:          token.PurposeAccess,
+		SessionID:        sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: user.ID},
+	}
+	if enrollmentOnly {
+		claims.Scope = token.ScopeMFAEnrollment
+	}
+
+	accessToken, expiresAt, err := s.Tokens.Issue(claims, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:           accessToken,
+		RefreshToken:          refreshToken,
+		ExpiresIn:             int64(time.Until(expiresAt).Round(time.Second).Seconds()),
+		MFAEnrollmentRequired: enrollmentOnly,
+	}, nil
+}
+
+// newToken returns a random, URL-safe token. Tokens are only ever stored
+// hashed, see hashToken.
+func newToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
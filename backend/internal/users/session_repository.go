@@ -0,0 +1,86 @@
+package users
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *Session, token *RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	Rotate(ctx context.Context, used *RefreshToken, next *RefreshToken) error
+	IsActive(ctx context.Context, sessionID string) (bool, error)
+	Revoke(ctx context.Context, sessionID string) error
+	RevokeByUserID(ctx context.Context, userID string) error
+}
+
+type SessionRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &SessionRepositoryImpl{DB: db}
+}
+
+// Create stores a new session together with its first refresh token.
+func (r *SessionRepositoryImpl) Create(ctx context.Context, session *Session, token *RefreshToken) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Session").Create(token).Error
+	})
+}
+
+func (r *SessionRepositoryImpl) GetRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	var token RefreshToken
+	err := r.DB.WithContext(ctx).Joins("Session").First(&token, "token_hash = ?", tokenHash).Error
+	return &token, err
+}
+
+// Rotate marks used as consumed and stores next in its place. Marking the
+// token is conditional on it still being unused, so only one of two
+// concurrent refreshes with the same token succeeds; the other gets
+// ErrRefreshTokenReused.
+func (r *SessionRepositoryImpl) Rotate(ctx context.Context, used *RefreshToken, next *RefreshToken) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", used.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+		used.UsedAt = &now
+
+		return tx.Omit("Session").Create(next).Error
+	})
+}
+
+// IsActive reports whether the session exists, has not been revoked and
+// belongs to a user that has not been deleted.
+func (r *SessionRepositoryImpl) IsActive(ctx context.Context, sessionID string) (bool, error) {
+	var count int64
+	err := r.DB.WithContext(ctx).Model(&Session{}).
+		Joins("JOIN users ON users.id = sessions.user_id AND users.deleted_at IS NULL").
+		Where("sessions.id = ? AND sessions.revoked_at IS NULL", sessionID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *SessionRepositoryImpl) Revoke(ctx context.Context, sessionID string) error {
+	return r.DB.WithContext(ctx).Model(&Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *SessionRepositoryImpl) RevokeByUserID(ctx context.Context, userID string) error {
+	return r.DB.WithContext(ctx).Model(&Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
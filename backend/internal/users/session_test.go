@@ -0,0 +1,162 @@
+package users
+
+import (
+	"errors"
+	"eventBookingSystem/internal/auth/roles"
+	"eventBookingSystem/internal/auth/token"
+	"testing"
+	"time"
+)
+
+func newTestSessionService(t *testing.T, db *fakeDB) (*SessionServiceImpl, *token.Manager) {
+	t.Helper()
+	tokens := newTestTokens(t)
+	service := NewSessionService(fakeSessionRepository{db}, fakeUserRepository{db}, tokens, fakeRoles{db}, time.Hour, true)
+	return service.(*SessionServiceImpl), tokens
+}
+
+func TestSessionRefresh(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare changes the stored state after the session was started and
+		// returns the refresh token to present.
+		prepare      func(t *testing.T, service *SessionServiceImpl, db *fakeDB, refreshToken string) string
+		wantErr      error
+		wantInactive bool
+		wantRotation bool
+	}{
+		{
+			name: "rotates the refresh token",
+			prepare: func(t *testing.T, service *SessionServiceImpl, db *fakeDB, refreshToken string) string {
+				return refreshToken
+			},
+			wantRotation: true,
+		},
+		{
+			name: "reused token revokes the session",
+			prepare: func(t *testing.T, service *SessionServiceImpl, db *fakeDB, refreshToken string) string {
+				if _, err := service.Refresh(t.Context(), refreshToken); err != nil {
+					t.Fatalf("first Refresh: %v", err)
+				}
+				return refreshToken
+			},
+			wantErr:      ErrRefreshTokenReused,
+			wantInactive: true,
+		},
+		{
+			name: "expired token",
+			prepare: func(t *testing.T, service *SessionServiceImpl, db *fakeDB, refreshToken string) string {
+				db.refreshTokens[hashToken(refreshToken)].ExpiresAt = time.Now().Add(-time.Second)
+				return refreshToken
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "revoked session",
+			prepare: func(t *testing.T, service *SessionServiceImpl, db *fakeDB, refreshToken string) string {
+				if err := service.RevokeAll(t.Context(), "ada"); err != nil {
+					t.Fatalf("RevokeAll: %v", err)
+				}
+				return refreshToken
+			},
+			wantErr:      ErrInvalidRefreshToken,
+			wantInactive: true,
+		},
+		{
+			name: "deleted user",
+			prepare: func(t *testing.T, service *SessionServiceImpl, db *fakeDB, refreshToken string) string {
+				delete(db.users, "ada")
+				return refreshToken
+			},
+			wantErr:      ErrInvalidRefreshToken,
+			wantInactive: true,
+		},
+		{
+			name: "unknown token",
+			prepare: func(t *testing.T, service *SessionServiceImpl, db *fakeDB, refreshToken string) string {
+				return refreshToken + "x"
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			db.addUser(User{ID: "ada", Username: "ada", Email: "ada@example.com"}, roles.RoleUser)
+			service, tokens := newTestSessionService(t, db)
+
+			user := db.user("ada")
+			started, err := service.StartSession(t.Context(), &user)
+			if err != nil {
+				t.Fatalf("StartSession: %v", err)
+			}
+			claims, err := tokens.Verify(started.AccessToken, token.PurposeAccess)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			sessionID := claims.SessionID
+
+			refreshed, err := service.Refresh(t.Context(), tt.prepare(t, service, db, started.RefreshToken))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Refresh = %v, want %v", err, tt.wantErr)
+			}
+
+			if active, _ := service.IsSessionActive(t.Context(), sessionID); active == tt.wantInactive {
+				t.Errorf("session active = %v, want %v", active, !tt.wantInactive)
+			}
+
+			if !tt.wantRotation {
+				return
+			}
+			if refreshed.RefreshToken == started.RefreshToken {
+				t.Error("Refresh returned the same refresh token")
+			}
+			claims, err = tokens.Verify(refreshed.AccessToken, token.PurposeAccess)
+			if err != nil {
+				t.Fatalf("Verify refreshed: %v", err)
+			}
+			if claims.SessionID != sessionID || claims.Subject != "ada" {
+				t.Errorf("refreshed token for session %q of %q, want %q of ada", claims.SessionID, claims.Subject, sessionID)
+			}
+			if _, err := service.Refresh(t.Context(), refreshed.RefreshToken); err != nil {
+				t.Errorf("Refresh with the rotated token: %v", err)
+			}
+		})
+	}
+}
+
+func TestSessionEnrollmentOnly(t *testing.T) {
+	enabledAt := time.Now()
+
+	tests := []struct {
+		name           string
+		role           string
+		totpEnabledAt  *time.Time
+		enrollmentOnly bool
+	}{
+		{"user without two-factor authentication", roles.RoleUser, nil, false},
+		{"admin without two-factor authentication", roles.RoleAdmin, nil, true},
+		{"admin with two-factor authentication", roles.RoleAdmin, &enabledAt, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			db.addUser(User{ID: "ada", Username: "ada", Email: "ada@example.com", TOTPEnabledAt: tt.totpEnabledAt}, tt.role)
+			service, tokens := newTestSessionService(t, db)
+
+			user := db.user("ada")
+			pair, err := service.StartSession(t.Context(), &user)
+			if err != nil {
+				t.Fatalf("StartSession: %v", err)
+			}
+			claims, err := tokens.Verify(pair.AccessToken, token.PurposeAccess)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+
+			if pair.MFAEnrollmentRequired != tt.enrollmentOnly || (claims.Scope == token.ScopeMFAEnrollment) != tt.enrollmentOnly {
+				t.Errorf("enrollment required %v with scope %q, want enrollment only %v", pair.MFAEnrollmentRequired, claims.Scope, tt.enrollmentOnly)
+			}
+		})
+	}
+}
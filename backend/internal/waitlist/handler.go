@@ -0,0 +1,95 @@
+package waitlist
+
+import (
+	"encoding/json"
+	"eventBookingSystem/internal/apperr"
+	"eventBookingSystem/internal/middleware"
+	"eventBookingSystem/internal/request"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type WaitlistHandler struct {
+	WaitlistService WaitlistService
+}
+
+func NewWaitlistHandler(waitlistService WaitlistService) *WaitlistHandler {
+	return &WaitlistHandler{WaitlistService: waitlistService}
+}
+
+func (h *WaitlistHandler) Join(w http.ResponseWriter, r *http.Request) {
+	eventID := r.PathValue("eventID")
+	if _, err := uuid.Parse(eventID); err != nil {
+		apperr.Write(w, r, apperr.BadRequest("Invalid event ID"))
+		return
+	}
+
+	var req JoinRequest
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	position, err := h.WaitlistService.Join(r.Context(), userID, eventID, req.Seats)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(position)
+}
+
+func (h *WaitlistHandler) GetPosition(w http.ResponseWriter, r *http.Request) {
+	eventID := r.PathValue("eventID")
+	if _, err := uuid.Parse(eventID); err != nil {
+		apperr.Write(w, r, apperr.BadRequest("Invalid event ID"))
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	position, err := h.WaitlistService.GetPosition(r.Context(), userID, eventID)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(position)
+}
+
+func (h *WaitlistHandler) Leave(w http.ResponseWriter, r *http.Request) {
+	eventID := r.PathValue("eventID")
+	if _, err := uuid.Parse(eventID); err != nil {
+		apperr.Write(w, r, apperr.BadRequest("Invalid event ID"))
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	err := h.WaitlistService.Leave(r.Context(), userID, eventID)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WaitlistHandler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	entries, err := h.WaitlistService.GetEntriesByUserID(r.Context(), userID)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
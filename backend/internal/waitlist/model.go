@@ -0,0 +1,37 @@
+package waitlist
+
+import (
+	"eventBookingSystem/internal/apperr"
+	"time"
+)
+
+const (
+	StatusWaiting  = "waiting"
+	StatusPromoted = "promoted"
+	StatusLeft     = "left"
+)
+
+var (
+	ErrNotWaiting      = apperr.NotFound("user is not on the waitlist for this event")
+	ErrAlreadyWaiting  = apperr.Conflict("user is already on the waitlist for this event")
+	ErrSeatsAvailable  = apperr.Conflict("event has enough seats available, book directly")
+	ErrExceedsCapacity = apperr.BadRequest("requested seats exceed the event capacity")
+)
+
+type WaitlistEntry struct {
+	ID        string  `gorm:"type:uuid;primaryKey"`
+	UserID    string  `gorm:"type:uuid;not null;index"`
+	EventID   string  `gorm:"type:uuid;not null;index"`
+	Seats     int     `gorm:"not null"`
+	Status    string  `gorm:"type:varchar(10);not null;default:'waiting'"`
+	BookingID *string `gorm:"type:uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Position describes where a user's entry sits in an event's waitlist.
+type Position struct {
+	Entry    *WaitlistEntry
+	Position int
+	Ahead    int
+}
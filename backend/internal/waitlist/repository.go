@@ -0,0 +1,183 @@
+package waitlist
+
+import (
+	"context"
+	"errors"
+	"eventBookingSystem/internal/bookings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WaitlistRepository interface {
+	Join(ctx context.Context, entry *WaitlistEntry) error
+	GetWaiting(ctx context.Context, userID, eventID string) (*WaitlistEntry, error)
+	GetByUserID(ctx context.Context, userID string) ([]WaitlistEntry, error)
+	Position(ctx context.Context, entry *WaitlistEntry) (int, error)
+	Leave(ctx context.Context, entry *WaitlistEntry) error
+	PromoteWaiting(ctx context.Context, eventID string) ([]bookings.Booking, error)
+}
+
+type WaitlistRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewWaitlistRepository(db *gorm.DB) WaitlistRepository {
+	return &WaitlistRepositoryImpl{DB: db}
+}
+
+// Join adds the entry to the event's waitlist. Joining is only allowed while
+// the event cannot satisfy the request directly.
+func (r *WaitlistRepositoryImpl) Join(ctx context.Context, entry *WaitlistEntry) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		event, err := bookings.LockEvent(tx, entry.EventID)
+		if err != nil {
+			return err
+		}
+
+		if entry.Seats > event.Capacity {
+			return ErrExceedsCapacity
+		}
+
+		available, err := bookings.AvailableSeats(tx, event)
+		if err != nil {
+			return err
+		}
+
+		queued, err := waitingCount(tx, entry.EventID)
+		if err != nil {
+			return err
+		}
+
+		if queued == 0 && entry.Seats <= available {
+			return ErrSeatsAvailable
+		}
+
+		var existing int64
+		err = tx.Model(&WaitlistEntry{}).
+			Where("user_id = ? AND event_id = ? AND status = ?", entry.UserID, entry.EventID, StatusWaiting).
+			Count(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrAlreadyWaiting
+		}
+
+		return tx.Create(entry).Error
+	})
+}
+
+func (r *WaitlistRepositoryImpl) GetWaiting(ctx context.Context, userID, eventID string) (*WaitlistEntry, error) {
+	var entry WaitlistEntry
+	err := r.DB.WithContext(ctx).First(&entry, "user_id = ? AND event_id = ? AND status = ?", userID, eventID, StatusWaiting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotWaiting
+	}
+	return &entry, err
+}
+
+func (r *WaitlistRepositoryImpl) GetByUserID(ctx context.Context, userID string) ([]WaitlistEntry, error) {
+	var entries []WaitlistEntry
+	err := r.DB.WithContext(ctx).Where("user_id = ? AND status = ?", userID, StatusWaiting).
+		Order("created_at, id").
+		Find(&entries).Error
+	return entries, err
+}
+
+// Position returns the 1-based position of a waiting entry in its event's
+// queue.
+func (r *WaitlistRepositoryImpl) Position(ctx context.Context, entry *WaitlistEntry) (int, error) {
+	var ahead int64
+	err := r.DB.WithContext(ctx).Model(&WaitlistEntry{}).
+		Where("event_id = ? AND status = ?", entry.EventID, StatusWaiting).
+		Where("created_at < ? OR (created_at = ? AND id < ?)", entry.CreatedAt, entry.CreatedAt, entry.ID).
+		Count(&ahead).Error
+	return int(ahead) + 1, err
+}
+
+// Leave marks the entry as left only if it is still waiting, so an entry
+// promoted in the meantime keeps its booking. It returns ErrNotWaiting if the
+// entry is no longer waiting.
+func (r *WaitlistRepositoryImpl) Leave(ctx context.Context, entry *WaitlistEntry) error {
+	result := r.DB.WithContext(ctx).Model(&WaitlistEntry{}).
+		Where("id = ? AND status = ?", entry.ID, StatusWaiting).
+		Update("status", StatusLeft)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotWaiting
+	}
+	entry.Status = StatusLeft
+	return nil
+}
+
+// PromoteWaiting turns waiting entries into bookings in FIFO order for as long
+// as the event has enough free seats for the entry at the head of the queue.
+// The head is never skipped in favour of a smaller request further back.
+func (r *WaitlistRepositoryImpl) PromoteWaiting(ctx context.Context, eventID string) ([]bookings.Booking, error) {
+	var promoted []bookings.Booking
+
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		event, err := bookings.LockEvent(tx, eventID)
+		if err != nil {
+			return err
+		}
+
+		available, err := bookings.AvailableSeats(tx, event)
+		if err != nil {
+			return err
+		}
+
+		var queue []WaitlistEntry
+		err = tx.Where("event_id = ? AND status = ?", eventID, StatusWaiting).
+			Order("created_at, id").
+			Find(&queue).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range queue {
+			entry := &queue[i]
+			if entry.Seats > available {
+				break
+			}
+
+			booking := bookings.Booking{
+				ID:      uuid.New().String(),
+				UserID:  entry.UserID,
+				EventID: entry.EventID,
+				Seats:   entry.Seats,
+				Status:  bookings.StatusBooked,
+			}
+			if err := tx.Create(&booking).Error; err != nil {
+				return err
+			}
+
+			entry.Status = StatusPromoted
+			entry.BookingID = &booking.ID
+			if err := tx.Save(entry).Error; err != nil {
+				return err
+			}
+
+			available -= entry.Seats
+			promoted = append(promoted, booking)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return promoted, nil
+}
+
+func waitingCount(tx *gorm.DB, eventID string) (int64, error) {
+	var count int64
+	err := tx.Model(&WaitlistEntry{}).
+		Where("event_id = ? AND status = ?", eventID, StatusWaiting).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,11 @@
+package waitlist
+
+import "eventBookingSystem/internal/request"
+
+type JoinRequest struct {
+	Seats int `json:"seats"`
+}
+
+func (r *JoinRequest) Validate(v *request.Validator) {
+	v.Positive("seats", r.Seats)
+}
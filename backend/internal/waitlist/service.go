@@ -0,0 +1,97 @@
+package waitlist
+
+import (
+	"context"
+	"eventBookingSystem/internal/logging"
+	"eventBookingSystem/internal/metrics"
+
+	"github.com/google/uuid"
+)
+
+type WaitlistService interface {
+	Join(ctx context.Context, userID, eventID string, seats int) (*Position, error)
+	Leave(ctx context.Context, userID, eventID string) error
+	GetPosition(ctx context.Context, userID, eventID string) (*Position, error)
+	GetEntriesByUserID(ctx context.Context, userID string) ([]WaitlistEntry, error)
+	SeatsReleased(ctx context.Context, eventID string) error
+}
+
+type WaitlistServiceImpl struct {
+	WaitlistRepository WaitlistRepository
+}
+
+func NewWaitlistService(waitlistRepository WaitlistRepository) WaitlistService {
+	return &WaitlistServiceImpl{WaitlistRepository: waitlistRepository}
+}
+
+func (s *WaitlistServiceImpl) Join(ctx context.Context, userID, eventID string, seats int) (*Position, error) {
+	entry := &WaitlistEntry{
+		ID:      uuid.New().String(),
+		UserID:  userID,
+		EventID: eventID,
+		Seats:   seats,
+		Status:  StatusWaiting,
+	}
+
+	if err := s.WaitlistRepository.Join(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return s.position(ctx, entry)
+}
+
+func (s *WaitlistServiceImpl) Leave(ctx context.Context, userID, eventID string) error {
+	entry, err := s.WaitlistRepository.GetWaiting(ctx, userID, eventID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.WaitlistRepository.Leave(ctx, entry); err != nil {
+		return err
+	}
+
+	// The user may have been blocking smaller requests behind them.
+	return s.SeatsReleased(ctx, eventID)
+}
+
+func (s *WaitlistServiceImpl) GetPosition(ctx context.Context, userID, eventID string) (*Position, error) {
+	entry, err := s.WaitlistRepository.GetWaiting(ctx, userID, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.position(ctx, entry)
+}
+
+func (s *WaitlistServiceImpl) GetEntriesByUserID(ctx context.Context, userID string) ([]WaitlistEntry, error) {
+	return s.WaitlistRepository.GetByUserID(ctx, userID)
+}
+
+// SeatsReleased promotes waiting users into bookings. It satisfies
+// bookings.SeatReleaseListener.
+func (s *WaitlistServiceImpl) SeatsReleased(ctx context.Context, eventID string) error {
+	promoted, err := s.WaitlistRepository.PromoteWaiting(ctx, eventID)
+	if err != nil {
+		return err
+	}
+
+	for _, booking := range promoted {
+		metrics.BookingCreated(string(booking.Status))
+		metrics.SeatsSold(booking.EventID, booking.Seats)
+		logging.FromContext(ctx).InfoContext(ctx, "Promoted waitlisted user", "user_id", booking.UserID, "booking_id", booking.ID, "event_id", eventID)
+	}
+	return nil
+}
+
+func (s *WaitlistServiceImpl) position(ctx context.Context, entry *WaitlistEntry) (*Position, error) {
+	position, err := s.WaitlistRepository.Position(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Position{
+		Entry:    entry,
+		Position: position,
+		Ahead:    position - 1,
+	}, nil
+}
@@ -0,0 +1,206 @@
+package waitlist
+
+import (
+	"context"
+	"errors"
+	"eventBookingSystem/internal/bookings"
+	"slices"
+	"testing"
+)
+
+// fakeWaitlistRepository keeps one queue per event in memory. Promotion
+// follows the repository's rule: FIFO while the head of the queue fits.
+type fakeWaitlistRepository struct {
+	entries   []*WaitlistEntry
+	available map[string]int
+	// promotions records the events promotion was attempted for.
+	promotions []string
+	// beforeLeave runs between looking up and leaving an entry, to change
+	// the queue concurrently.
+	beforeLeave func()
+}
+
+func (r *fakeWaitlistRepository) Join(ctx context.Context, entry *WaitlistEntry) error {
+	if _, err := r.GetWaiting(ctx, entry.UserID, entry.EventID); err == nil {
+		return ErrAlreadyWaiting
+	}
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *fakeWaitlistRepository) GetWaiting(ctx context.Context, userID, eventID string) (*WaitlistEntry, error) {
+	for _, entry := range r.entries {
+		if entry.UserID == userID && entry.EventID == eventID && entry.Status == StatusWaiting {
+			return entry, nil
+		}
+	}
+	return nil, ErrNotWaiting
+}
+
+func (r *fakeWaitlistRepository) GetByUserID(ctx context.Context, userID string) ([]WaitlistEntry, error) {
+	var entries []WaitlistEntry
+	for _, entry := range r.entries {
+		if entry.UserID == userID && entry.Status == StatusWaiting {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries, nil
+}
+
+func (r *fakeWaitlistRepository) Position(ctx context.Context, entry *WaitlistEntry) (int, error) {
+	position := 1
+	for _, other := range r.entries {
+		if other == entry {
+			break
+		}
+		if other.EventID == entry.EventID && other.Status == StatusWaiting {
+			position++
+		}
+	}
+	return position, nil
+}
+
+func (r *fakeWaitlistRepository) Leave(ctx context.Context, entry *WaitlistEntry) error {
+	if r.beforeLeave != nil {
+		r.beforeLeave()
+	}
+	for _, stored := range r.entries {
+		if stored.ID == entry.ID && stored.Status == StatusWaiting {
+			stored.Status = StatusLeft
+			entry.Status = StatusLeft
+			return nil
+		}
+	}
+	return ErrNotWaiting
+}
+
+func (r *fakeWaitlistRepository) PromoteWaiting(ctx context.Context, eventID string) ([]bookings.Booking, error) {
+	r.promotions = append(r.promotions, eventID)
+
+	var promoted []bookings.Booking
+	for _, entry := range r.entries {
+		if entry.EventID != eventID || entry.Status != StatusWaiting {
+			continue
+		}
+		if entry.Seats > r.available[eventID] {
+			break
+		}
+		r.available[eventID] -= entry.Seats
+		entry.Status = StatusPromoted
+		promoted = append(promoted, bookings.Booking{ID: "booking-" + entry.UserID, UserID: entry.UserID, EventID: eventID, Seats: entry.Seats, Status: bookings.StatusBooked})
+	}
+	return promoted, nil
+}
+
+func TestJoin(t *testing.T) {
+	ctx := t.Context()
+	service := NewWaitlistService(&fakeWaitlistRepository{available: map[string]int{}})
+
+	for i, userID := range []string{"ada", "grace", "linus"} {
+		position, err := service.Join(ctx, userID, "e", 1)
+		if err != nil {
+			t.Fatalf("Join %s: %v", userID, err)
+		}
+		if position.Position != i+1 || position.Ahead != i || position.Entry.Status != StatusWaiting {
+			t.Errorf("Join %s = %+v, want position %d", userID, position, i+1)
+		}
+	}
+
+	if _, err := service.Join(ctx, "ada", "e", 1); !errors.Is(err, ErrAlreadyWaiting) {
+		t.Errorf("Join twice = %v, want ErrAlreadyWaiting", err)
+	}
+	if position, err := service.Join(ctx, "ada", "other", 1); err != nil || position.Position != 1 {
+		t.Errorf("Join of another event = %+v, %v, want position 1", position, err)
+	}
+}
+
+func TestLeave(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID string
+		// available is the number of free seats of the event.
+		available    int
+		wantErr      error
+		wantWaiting  []string
+		wantPromoted bool
+	}{
+		{
+			name:        "head leaves",
+			userID:      "ada",
+			wantWaiting: []string{"grace", "linus"},
+		},
+		{
+			// ada needs more seats than are free and blocked the others.
+			name:         "blocking head leaves",
+			userID:       "ada",
+			available:    2,
+			wantWaiting:  []string{},
+			wantPromoted: true,
+		},
+		{
+			name:        "not waiting",
+			userID:      "alan",
+			wantErr:     ErrNotWaiting,
+			wantWaiting: []string{"ada", "grace", "linus"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+			repository := &fakeWaitlistRepository{available: map[string]int{"e": tt.available}}
+			service := NewWaitlistService(repository)
+			for _, join := range []struct {
+				userID string
+				seats  int
+			}{{"ada", 3}, {"grace", 1}, {"linus", 1}} {
+				if _, err := service.Join(ctx, join.userID, "e", join.seats); err != nil {
+					t.Fatalf("Join %s: %v", join.userID, err)
+				}
+			}
+
+			if err := service.Leave(ctx, tt.userID, "e"); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Leave = %v, want %v", err, tt.wantErr)
+			}
+
+			waiting := []string{}
+			for _, entry := range repository.entries {
+				if entry.Status == StatusWaiting {
+					waiting = append(waiting, entry.UserID)
+				}
+			}
+			if !slices.Equal(waiting, tt.wantWaiting) {
+				t.Errorf("waiting = %v, want %v", waiting, tt.wantWaiting)
+			}
+			if promoted := repository.available["e"] < tt.available; promoted != tt.wantPromoted {
+				t.Errorf("promoted = %v, want %v", promoted, tt.wantPromoted)
+			}
+			if attempted := len(repository.promotions) > 0; attempted != (tt.wantErr == nil) {
+				t.Errorf("promotion attempted = %v after Leave = %v", attempted, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLeaveAfterPromotion(t *testing.T) {
+	ctx := t.Context()
+	repository := &fakeWaitlistRepository{available: map[string]int{}}
+	service := NewWaitlistService(repository)
+	if _, err := service.Join(ctx, "ada", "e", 2); err != nil {
+		t.Fatalf("Join: %v", err)
+	}
+
+	// Seats are released and ada is promoted after Leave looked her up.
+	repository.beforeLeave = func() {
+		repository.available["e"] = 2
+		if _, err := repository.PromoteWaiting(ctx, "e"); err != nil {
+			t.Fatalf("PromoteWaiting: %v", err)
+		}
+	}
+
+	if err := service.Leave(ctx, "ada", "e"); !errors.Is(err, ErrNotWaiting) {
+		t.Fatalf("Leave = %v, want ErrNotWaiting", err)
+	}
+	if status := repository.entries[0].Status; status != StatusPromoted {
+		t.Errorf("status = %s, want %s", status, StatusPromoted)
+	}
+}
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS waitlist_entries;
+DROP TABLE IF EXISTS bookings;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS email_verification_tokens;
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,141 @@
+-- Baseline schema. Every statement is guarded so databases created by the
+-- old AutoMigrate startup adopt it. Those databases already have the users,
+-- events and bookings tables, which CREATE TABLE IF NOT EXISTS skips, so the
+-- columns added since are added separately before they are indexed.
+
+CREATE TABLE IF NOT EXISTS users (
+    id uuid PRIMARY KEY,
+    username text NOT NULL,
+    email text NOT NULL,
+    password_hash text NOT NULL,
+    role varchar(10) DEFAULT 'user',
+    email_verified_at timestamptz,
+    totp_secret varchar(64),
+    totp_enabled_at timestamptz,
+    totp_last_step bigint,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at timestamptz;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret varchar(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at timestamptz;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step bigint;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL,
+    revoked_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_revoked_at ON sessions (revoked_at);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id uuid PRIMARY KEY,
+    session_id uuid NOT NULL,
+    token_hash char(64) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz,
+    CONSTRAINT fk_refresh_tokens_session FOREIGN KEY (session_id) REFERENCES sessions (id)
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens (session_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL,
+    token_hash char(64) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_reset_tokens_token_hash ON password_reset_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL,
+    token_hash char(64) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user_id ON email_verification_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_verification_tokens_token_hash ON email_verification_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL,
+    code_hash char(64) NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS roles (
+    name varchar(50) PRIMARY KEY,
+    description text,
+    permissions jsonb NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id uuid,
+    role_name varchar(50),
+    created_at timestamptz,
+    PRIMARY KEY (user_id, role_name)
+);
+CREATE INDEX IF NOT EXISTS idx_user_roles_role_name ON user_roles (role_name);
+
+CREATE TABLE IF NOT EXISTS events (
+    id uuid PRIMARY KEY,
+    title text NOT NULL,
+    description text,
+    date timestamptz NOT NULL,
+    location text NOT NULL,
+    capacity bigint NOT NULL,
+    organizer_id uuid,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+ALTER TABLE events ADD COLUMN IF NOT EXISTS organizer_id uuid;
+CREATE INDEX IF NOT EXISTS idx_events_organizer_id ON events (organizer_id);
+CREATE INDEX IF NOT EXISTS idx_events_deleted_at ON events (deleted_at);
+
+CREATE TABLE IF NOT EXISTS bookings (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL,
+    event_id uuid NOT NULL,
+    seats bigint NOT NULL,
+    status varchar(10) DEFAULT 'booked',
+    expires_at timestamptz,
+    role varchar(10) DEFAULT 'user',
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS expires_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_bookings_status ON bookings (status);
+CREATE INDEX IF NOT EXISTS idx_bookings_expires_at ON bookings (expires_at);
+CREATE INDEX IF NOT EXISTS idx_bookings_deleted_at ON bookings (deleted_at);
+
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL,
+    event_id uuid NOT NULL,
+    seats bigint NOT NULL,
+    status varchar(10) NOT NULL DEFAULT 'waiting',
+    booking_id uuid,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_user_id ON waitlist_entries (user_id);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_event_id ON waitlist_entries (event_id);
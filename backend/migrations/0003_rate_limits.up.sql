@@ -0,0 +1,17 @@
+-- Shared state of the rate limiter when RATE_LIMIT_STORE=postgres. Rows are
+-- deleted once they expire.
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key text PRIMARY KEY,
+    tokens double precision NOT NULL,
+    updated_at timestamptz NOT NULL,
+    expires_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_expires_at ON rate_limit_buckets (expires_at);
+
+CREATE TABLE IF NOT EXISTS rate_limit_failures (
+    key text PRIMARY KEY,
+    count integer NOT NULL,
+    last_failure_at timestamptz NOT NULL,
+    expires_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_rate_limit_failures_expires_at ON rate_limit_failures (expires_at);
@@ -0,0 +1,22 @@
+-- Single sign-on with an OpenID Connect provider. Users provisioned by it
+-- have an empty password_hash until they set a password.
+CREATE TABLE IF NOT EXISTS user_identities (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL,
+    issuer text NOT NULL,
+    subject text NOT NULL,
+    email text,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_issuer_subject ON user_identities (issuer, subject);
+
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    id uuid PRIMARY KEY,
+    state_hash char(64) NOT NULL,
+    nonce text NOT NULL,
+    code_verifier text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oidc_login_states_state_hash ON oidc_login_states (state_hash);
@@ -0,0 +1,3 @@
+-- The backfilled rows cannot be told apart from roles assigned later, so
+-- they are kept.
+SELECT 1;
@@ -0,0 +1,7 @@
+-- Give users from before role management the role stored in their legacy
+-- users.role column. Later role changes are only made in user_roles.
+INSERT INTO user_roles (user_id, role_name, created_at)
+SELECT u.id, u.role, NOW() FROM users u
+WHERE u.deleted_at IS NULL
+AND u.role IN ('user', 'admin')
+AND NOT EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id);
@@ -0,0 +1,3 @@
+-- Addresses marked verified here cannot be told apart from ones verified
+-- later, so they are kept.
+SELECT 1;
@@ -0,0 +1,6 @@
+-- Accounts from before email verification never got a verification link,
+-- so their addresses are accepted as they are. Accounts that were sent a
+-- link still have to use it.
+UPDATE users SET email_verified_at = COALESCE(created_at, NOW())
+WHERE email_verified_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM email_verification_tokens t WHERE t.user_id = users.id);
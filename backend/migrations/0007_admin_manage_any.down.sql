@@ -0,0 +1,3 @@
+-- Permissions granted here cannot be told apart from ones granted later
+-- through the API, so they are kept.
+SELECT 1;
@@ -0,0 +1,8 @@
+-- Seed only creates missing roles, so admin roles seeded before these
+-- permissions existed never got them. Later removals through the API are
+-- made after this runs and are kept.
+UPDATE roles SET permissions = permissions || '["bookings:manage_any"]'::jsonb, updated_at = NOW()
+WHERE name = 'admin' AND NOT permissions ? 'bookings:manage_any';
+
+UPDATE roles SET permissions = permissions || '["events:manage_any"]'::jsonb, updated_at = NOW()
+WHERE name = 'admin' AND NOT permissions ? 'events:manage_any';
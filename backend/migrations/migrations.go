@@ -0,0 +1,11 @@
+// Package migrations embeds the SQL migrations of the database schema.
+//
+// Each migration is a pair of files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql. Versions are applied in ascending order and
+// must never be renumbered once released.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS